@@ -0,0 +1,240 @@
+// Package ast binds concrete syntax trees to go types with reflection
+package ast
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/patrickhuber/go-earley/cst"
+	"github.com/patrickhuber/go-earley/grammar"
+)
+
+// Tag is the struct tag key that names the symbol a field binds to
+// `earley:"-"` skips the field
+const Tag = "earley"
+
+var nodeType = reflect.TypeOf((*cst.Node)(nil))
+
+// Builder creates values of registered go types from concrete syntax tree nodes
+//
+// A struct is filled from the children of its node. Each child binds to the field named by the struct tag or, without a tag,
+// to the field whose name matches the child symbol ignoring case and underscores. Tokens match by token type.
+// Fields with the same symbol bind to the children in order and slice fields collect every matching child.
+// Children of unregistered nonterminals that match no field, like repetitions and groupings, are bound as if they were children of the node.
+//
+// A child binds to a string field with its text, to number and bool fields with its parsed text,
+// to a *cst.Node field as it is and to struct, pointer and interface fields with the value built for its node.
+type Builder struct {
+	productions map[*grammar.Production]reflect.Type
+	symbols     map[string]reflect.Type
+}
+
+func New() *Builder {
+	return &Builder{
+		productions: map[*grammar.Production]reflect.Type{},
+		symbols:     map[string]reflect.Type{},
+	}
+}
+
+// Register builds nodes of every production of the nonterminal as the type of the prototype
+func (b *Builder) Register(nonTerminal string, prototype any) {
+	b.symbols[nonTerminal] = reflect.TypeOf(prototype)
+}
+
+// RegisterProduction builds nodes of the production as the type of the prototype
+// it takes precedence over the type registered for the nonterminal
+func (b *Builder) RegisterProduction(production *grammar.Production, prototype any) {
+	b.productions[production] = reflect.TypeOf(prototype)
+}
+
+// Build returns the value of the type registered for the node
+func (b *Builder) Build(node *cst.Node) (any, error) {
+	t, ok := b.registered(node)
+	if !ok {
+		return nil, fmt.Errorf("no type registered for %s", describe(node))
+	}
+	v, err := b.build(node, t)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+func (b *Builder) registered(node *cst.Node) (reflect.Type, bool) {
+	if node.Production == nil {
+		return nil, false
+	}
+	if t, ok := b.productions[node.Production]; ok {
+		return t, true
+	}
+	t, ok := b.symbols[node.Production.LeftHandSide.Name()]
+	return t, ok
+}
+
+// build creates a value of the type from the node
+func (b *Builder) build(node *cst.Node, t reflect.Type) (reflect.Value, error) {
+	if t == nodeType {
+		return reflect.ValueOf(node), nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(text(node)).Convert(t), nil
+	case reflect.Bool:
+		value, err := strconv.ParseBool(text(node))
+		if err != nil {
+			return reflect.Value{}, b.error(node, err)
+		}
+		return reflect.ValueOf(value).Convert(t), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err := strconv.ParseInt(text(node), 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, b.error(node, err)
+		}
+		return reflect.ValueOf(value).Convert(t), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value, err := strconv.ParseUint(text(node), 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, b.error(node, err)
+		}
+		return reflect.ValueOf(value).Convert(t), nil
+	case reflect.Float32, reflect.Float64:
+		value, err := strconv.ParseFloat(text(node), t.Bits())
+		if err != nil {
+			return reflect.Value{}, b.error(node, err)
+		}
+		return reflect.ValueOf(value).Convert(t), nil
+	case reflect.Pointer:
+		if t.Elem().Kind() != reflect.Struct {
+			break
+		}
+		v, err := b.build(node, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		pointer := reflect.New(t.Elem())
+		pointer.Elem().Set(v)
+		return pointer, nil
+	case reflect.Struct:
+		return b.fill(node, t)
+	case reflect.Interface:
+		return b.implementation(node, t)
+	}
+	return reflect.Value{}, fmt.Errorf("unable to bind %s to %s", describe(node), t)
+}
+
+// implementation builds the registered type of the node for an interface
+// unregistered nodes with a single child, like factor = literal, pass through to the child
+func (b *Builder) implementation(node *cst.Node, t reflect.Type) (reflect.Value, error) {
+	for {
+		registered, ok := b.registered(node)
+		if ok {
+			if !registered.AssignableTo(t) {
+				return reflect.Value{}, fmt.Errorf("type %s registered for %s does not implement %s", registered, describe(node), t)
+			}
+			return b.build(node, registered)
+		}
+		if len(node.Children) != 1 {
+			return reflect.Value{}, fmt.Errorf("no type registered for %s to implement %s", describe(node), t)
+		}
+		node = node.Children[0]
+	}
+}
+
+// fill creates a struct of the type and binds the children of the node to its fields
+func (b *Builder) fill(node *cst.Node, t reflect.Type) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	fields := map[string][]int{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Tag.Get(Tag)
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		key := normalize(name)
+		fields[key] = append(fields[key], i)
+	}
+
+	for _, child := range b.children(node, fields) {
+		indexes := fields[normalize(name(child))]
+		if len(indexes) == 0 {
+			continue
+		}
+		field := v.Field(indexes[0])
+		if field.Kind() == reflect.Slice && field.Type() != reflect.TypeOf([]byte(nil)) {
+			element, err := b.build(child, field.Type().Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			field.Set(reflect.Append(field, element))
+			continue
+		}
+		value, err := b.build(child, field.Type())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		field.Set(value)
+		// the next child with the same symbol binds to the next field
+		if len(indexes) > 1 {
+			fields[normalize(name(child))] = indexes[1:]
+		}
+	}
+	return v, nil
+}
+
+// children returns the children of the node with unregistered nonterminals that match no field replaced by their children
+func (b *Builder) children(node *cst.Node, fields map[string][]int) []*cst.Node {
+	var children []*cst.Node
+	for _, child := range node.Children {
+		_, matched := fields[normalize(name(child))]
+		_, registered := b.registered(child)
+		if matched || registered || child.Production == nil {
+			children = append(children, child)
+			continue
+		}
+		children = append(children, b.children(child, fields)...)
+	}
+	return children
+}
+
+func (b *Builder) error(node *cst.Node, err error) error {
+	return fmt.Errorf("unable to bind %s at %d:%d: %w", describe(node), node.Start.Line, node.Start.Column, err)
+}
+
+// name returns the nonterminal name or the token type of the node
+func name(node *cst.Node) string {
+	if node.Production != nil {
+		return node.Production.LeftHandSide.Name()
+	}
+	return node.Token.TokenType()
+}
+
+func describe(node *cst.Node) string {
+	if node.Production != nil {
+		return node.Production.String()
+	}
+	return node.Token.TokenType()
+}
+
+func normalize(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// text returns the text of the tokens below the node
+func text(node *cst.Node) string {
+	if node.Token != nil {
+		return node.Text()
+	}
+	var sb strings.Builder
+	for _, child := range node.Children {
+		sb.WriteString(text(child))
+	}
+	return sb.String()
+}
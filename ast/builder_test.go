@@ -0,0 +1,142 @@
+package ast_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/patrickhuber/go-earley/ast"
+	"github.com/patrickhuber/go-earley/cst"
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/parser"
+	"github.com/patrickhuber/go-earley/pdl"
+	"github.com/patrickhuber/go-earley/scanner"
+	"github.com/stretchr/testify/require"
+)
+
+type Program struct {
+	Statements []*Statement `earley:"Statement"`
+}
+
+type Statement struct {
+	Name  string     `earley:"Identifier"`
+	Value Expression `earley:"Expression"`
+	Node  *cst.Node  `earley:"-"`
+}
+
+type Expression interface {
+	expression()
+}
+
+type Add struct {
+	Left  Expression `earley:"Expression"`
+	Right Expression `earley:"Term"`
+}
+
+func (Add) expression() {}
+
+type Number struct {
+	Value int `earley:"Number"`
+}
+
+func (Number) expression() {}
+
+type Variable struct {
+	Name string `earley:"Identifier"`
+}
+
+func (Variable) expression() {}
+
+const definition = `
+	Program = Statement { Statement } ;
+	Statement = Identifier '=' Expression ';' ;
+	Expression = Expression '+' Term | Term ;
+	Term = Number | Identifier ;
+	Number ~ /[0-9]+/ ;
+	Identifier ~ /[a-z]+/ ;
+	Whitespace ~ /[\s]+/ ;
+	:ignore = Whitespace;`
+
+func TestBuilder(t *testing.T) {
+	g := Compile(t, definition)
+	b := ast.New()
+	b.Register("Program", Program{})
+	b.Register("Statement", &Statement{})
+	b.RegisterProduction(Production(t, g, "Expression -> Expression + Term"), Add{})
+	b.RegisterProduction(Production(t, g, "Term -> Number"), Number{})
+	b.RegisterProduction(Production(t, g, "Term -> Identifier"), Variable{})
+
+	t.Run("binds structs", func(t *testing.T) {
+		value, err := b.Build(Parse(t, g, "a = 1; b = a + 2 + c;"))
+		require.NoError(t, err)
+		require.Equal(t, Program{
+			Statements: []*Statement{
+				{Name: "a", Value: Number{Value: 1}},
+				{Name: "b", Value: Add{
+					Left:  Add{Left: Variable{Name: "a"}, Right: Number{Value: 2}},
+					Right: Variable{Name: "c"},
+				}},
+			},
+		}, value)
+	})
+	t.Run("requires a registered root", func(t *testing.T) {
+		b := ast.New()
+		_, err := b.Build(Parse(t, g, "a = 1;"))
+		require.Error(t, err)
+	})
+	t.Run("binds nodes and repeated symbols", func(t *testing.T) {
+		type Pair struct {
+			First  string `earley:"Identifier"`
+			Second string `earley:"Identifier"`
+			Node   *cst.Node
+		}
+		g := Compile(t, `Pair = Identifier ',' Node; Node = Identifier; Identifier ~ /[a-z]+/ ;`)
+		b := ast.New()
+		b.Register("Pair", Pair{})
+		value, err := b.Build(Parse(t, g, "ab,cd"))
+		require.NoError(t, err)
+		pair := value.(Pair)
+		require.Equal(t, "ab", pair.First)
+		require.Empty(t, pair.Second)
+		require.Equal(t, "Node", pair.Node.Symbol().Name())
+	})
+	t.Run("reports conversion errors", func(t *testing.T) {
+		type Small struct {
+			Value int8 `earley:"Number"`
+		}
+		g := Compile(t, `S = Number; Number ~ /[0-9]+/ ;`)
+		b := ast.New()
+		b.Register("S", Small{})
+		_, err := b.Build(Parse(t, g, "1000"))
+		require.ErrorContains(t, err, "at 1:1")
+	})
+}
+
+func Compile(t *testing.T, input string) *grammar.Grammar {
+	d, err := pdl.Parse(strings.NewReader(input))
+	require.NoError(t, err)
+	g, err := pdl.Compile(d)
+	require.NoError(t, err)
+	return g
+}
+
+func Parse(t *testing.T, g *grammar.Grammar, input string) *cst.Node {
+	p := parser.New(g)
+	accepted, err := scanner.RunToEnd(scanner.New(p, input))
+	require.NoError(t, err)
+	require.True(t, accepted)
+	root, ok := p.GetForestRoot()
+	require.True(t, ok)
+	node, err := cst.New(root)
+	require.NoError(t, err)
+	return node
+}
+
+func Production(t *testing.T, g *grammar.Grammar, text string) *grammar.Production {
+	for _, production := range g.Productions {
+		if production.String() == text {
+			return production
+		}
+	}
+	require.FailNow(t, "production not found", text)
+	return nil
+}
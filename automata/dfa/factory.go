@@ -14,6 +14,11 @@ type Factory struct {
 
 // Create implements token.Factory.
 func (f *Factory) Create(lexerRule grammar.LexerRule, str string, offset int) (token.Lexeme, error) {
+	return f.CreateAt(lexerRule, offset)
+}
+
+// CreateAt implements token.OffsetFactory.
+func (f *Factory) CreateAt(lexerRule grammar.LexerRule, offset int) (token.Lexeme, error) {
 	rule, ok := lexerRule.(*Dfa)
 	if !ok || lexerRule.LexerRuleType() != LexerRuleType {
 		return nil, fmt.Errorf("dfa factory expected lexer rule of type %s but found %s", LexerRuleType, lexerRule.LexerRuleType())
@@ -21,19 +26,29 @@ func (f *Factory) Create(lexerRule grammar.LexerRule, str string, offset int) (t
 	if f.queue.Length() == 0 {
 		return NewLexeme(rule, offset), nil
 	}
-	panic("unimplemented")
+	reused := f.queue.Dequeue()
+	reused.dfa = rule
+	reused.Reset(offset)
+	return reused, nil
 }
 
 // Free implements token.Factory.
 func (f *Factory) Free(lexeme token.Lexeme) error {
-	panic("unimplemented")
+	l, ok := lexeme.(*Lexeme)
+	if !ok {
+		return fmt.Errorf("Free expected *dfa.Lexeme but found %T", lexeme)
+	}
+	f.queue.Enqueue(l)
+	return nil
 }
 
 // Type implements token.Factory.
 func (f *Factory) Type() string {
-	panic("unimplemented")
+	return LexerRuleType
 }
 
 func NewFactory() token.Factory {
-	return &Factory{}
+	return &Factory{
+		queue: queue.New[*Lexeme](),
+	}
 }
@@ -0,0 +1,47 @@
+package dfa_test
+
+import (
+	"testing"
+
+	"github.com/patrickhuber/go-earley/automata/dfa"
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/terminal"
+	"github.com/patrickhuber/go-earley/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFactory(t *testing.T) {
+	digits := func(tokenType string) *dfa.Dfa {
+		start := &dfa.State{}
+		end := &dfa.State{Final: true}
+		start.Transitions = []dfa.Transition{{Terminal: terminal.NewRange('0', '9'), Target: end}}
+		end.Transitions = []dfa.Transition{{Terminal: terminal.NewRange('0', '9'), Target: end}}
+		return dfa.NewDfa(start, tokenType)
+	}
+	t.Run("type", func(t *testing.T) {
+		require.Equal(t, dfa.LexerRuleType, dfa.NewFactory().Type())
+	})
+	t.Run("reuses freed lexemes", func(t *testing.T) {
+		factory := dfa.NewFactory()
+		first, err := factory.Create(digits("first"), "1", 0)
+		require.NoError(t, err)
+		require.True(t, first.Scan('1'))
+		require.True(t, first.Accepted())
+		require.NoError(t, factory.Free(first))
+
+		second, err := factory.(token.OffsetFactory).CreateAt(digits("second"), 3)
+		require.NoError(t, err)
+		require.Same(t, first, second)
+		require.Equal(t, 3, second.Position())
+		require.Equal(t, "second", second.TokenType())
+		require.False(t, second.Accepted())
+		require.False(t, second.Scan('a'))
+		require.True(t, second.Scan('2'))
+		require.True(t, second.Accepted())
+	})
+	t.Run("rejects other lexer rules", func(t *testing.T) {
+		factory := dfa.NewFactory()
+		_, err := factory.(token.OffsetFactory).CreateAt(grammar.NewStringLexerRule("a"), 0)
+		require.Error(t, err)
+	})
+}
@@ -17,6 +17,12 @@ func NewLexeme(dfa *Dfa, position int) *Lexeme {
 	}
 }
 
+// Reset moves the lexeme back to the start state at the given offset
+func (l *Lexeme) Reset(offset int) {
+	l.current = l.dfa.Start
+	l.position = offset
+}
+
 func (l *Lexeme) Accepted() bool {
 	return l.current.Final
 }
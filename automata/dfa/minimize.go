@@ -0,0 +1,229 @@
+package dfa
+
+import (
+	"sort"
+
+	"github.com/patrickhuber/go-earley/terminal"
+)
+
+// Minimize returns an equivalent dfa with the fewest states using hopcroft's partition refinement
+// The alphabet is the set of disjoint character intervals formed by all transition terminals.
+// If a transition terminal cannot describe the characters it matches, the dfa is returned unchanged.
+func Minimize(d *Dfa) *Dfa {
+	states := reachable(d.Start)
+	index := map[*State]int{}
+	for i, state := range states {
+		index[state] = i
+	}
+
+	alphabet, ok := classes(states)
+	if !ok {
+		return d
+	}
+
+	// the dead state is the last state and every missing transition goes to it
+	dead := len(states)
+	count := dead + 1
+	delta := make([][]int, count)
+	for i := range delta {
+		delta[i] = make([]int, len(alphabet))
+		for c := range alphabet {
+			delta[i][c] = dead
+		}
+	}
+	for i, state := range states {
+		for c, interval := range alphabet {
+			for _, transition := range state.Transitions {
+				if transition.Terminal.IsMatch(interval.Min) {
+					delta[i][c] = index[transition.Target]
+					break
+				}
+			}
+		}
+	}
+
+	// inverse[c][q] is the set of states that transition to q on c
+	inverse := make([][][]int, len(alphabet))
+	for c := range alphabet {
+		inverse[c] = make([][]int, count)
+		for q := 0; q < count; q++ {
+			target := delta[q][c]
+			inverse[c][target] = append(inverse[c][target], q)
+		}
+	}
+
+	// initial partition of final and non final states
+	block := make([]int, count)
+	var blocks [][]int
+	var final, nonFinal []int
+	for q := 0; q < count; q++ {
+		if q < dead && states[q].Final {
+			final = append(final, q)
+		} else {
+			nonFinal = append(nonFinal, q)
+		}
+	}
+	work := map[int]struct{}{}
+	for _, members := range [][]int{final, nonFinal} {
+		if len(members) == 0 {
+			continue
+		}
+		id := len(blocks)
+		blocks = append(blocks, members)
+		for _, q := range members {
+			block[q] = id
+		}
+		work[id] = struct{}{}
+	}
+
+	for len(work) > 0 {
+		splitter := pop(work)
+		members := append([]int{}, blocks[splitter]...)
+		for c := range alphabet {
+			// the states that transition into the splitter on c, grouped by block
+			predecessors := map[int][]int{}
+			var touched []int
+			for _, q := range members {
+				for _, p := range inverse[c][q] {
+					b := block[p]
+					if _, ok := predecessors[b]; !ok {
+						touched = append(touched, b)
+					}
+					predecessors[b] = append(predecessors[b], p)
+				}
+			}
+			sort.Ints(touched)
+			for _, b := range touched {
+				in := predecessors[b]
+				if len(in) == len(blocks[b]) {
+					continue
+				}
+				inSet := map[int]struct{}{}
+				for _, p := range in {
+					inSet[p] = struct{}{}
+				}
+				var out []int
+				for _, q := range blocks[b] {
+					if _, ok := inSet[q]; !ok {
+						out = append(out, q)
+					}
+				}
+				id := len(blocks)
+				blocks[b] = in
+				blocks = append(blocks, out)
+				for _, q := range out {
+					block[q] = id
+				}
+				if _, ok := work[b]; ok || len(out) <= len(in) {
+					work[id] = struct{}{}
+				} else {
+					work[b] = struct{}{}
+				}
+			}
+		}
+	}
+
+	// build the minimized dfa in breadth first order from the start block
+	// blocks equivalent to the dead state are dropped
+	deadBlock := block[dead]
+	created := map[int]*State{}
+	create := func(b int) *State {
+		state := &State{}
+		for _, q := range blocks[b] {
+			if q < dead && states[q].Final {
+				state.Final = true
+			}
+		}
+		created[b] = state
+		return state
+	}
+	startBlock := block[index[d.Start]]
+	start := create(startBlock)
+	queue := []int{startBlock}
+	for len(queue) > 0 && startBlock != deadBlock {
+		b := queue[0]
+		queue = queue[1:]
+		state := created[b]
+		representative := blocks[b][0]
+
+		var order []int
+		targets := map[int][]terminal.Interval{}
+		for c, interval := range alphabet {
+			target := block[delta[representative][c]]
+			if target == deadBlock {
+				continue
+			}
+			if _, ok := targets[target]; !ok {
+				order = append(order, target)
+			}
+			targets[target] = append(targets[target], interval)
+		}
+		for _, target := range order {
+			next, ok := created[target]
+			if !ok {
+				next = create(target)
+				queue = append(queue, target)
+			}
+			state.Transitions = append(state.Transitions, Transition{
+				Terminal: terminal.FromIntervals(terminal.Normalize(targets[target])),
+				Target:   next,
+			})
+		}
+	}
+	return NewDfa(start, d.TokenType())
+}
+
+// reachable returns the states reachable from start in breadth first order
+func reachable(start *State) []*State {
+	visited := map[*State]struct{}{start: {}}
+	states := []*State{start}
+	for i := 0; i < len(states); i++ {
+		for _, transition := range states[i].Transitions {
+			if _, ok := visited[transition.Target]; ok {
+				continue
+			}
+			visited[transition.Target] = struct{}{}
+			states = append(states, transition.Target)
+		}
+	}
+	return states
+}
+
+// classes splits the characters matched by all transitions into disjoint intervals
+func classes(states []*State) ([]terminal.Interval, bool) {
+	var bounds []rune
+	for _, state := range states {
+		for _, transition := range state.Transitions {
+			intervals, err := terminal.Intervals(transition.Terminal)
+			if err != nil {
+				return nil, false
+			}
+			for _, interval := range intervals {
+				bounds = append(bounds, interval.Min, interval.Max+1)
+			}
+		}
+	}
+	sort.Slice(bounds, func(i, j int) bool {
+		return bounds[i] < bounds[j]
+	})
+	var result []terminal.Interval
+	for i := 0; i+1 < len(bounds); i++ {
+		if bounds[i] == bounds[i+1] {
+			continue
+		}
+		result = append(result, terminal.Interval{Min: bounds[i], Max: bounds[i+1] - 1})
+	}
+	return result, true
+}
+
+func pop(work map[int]struct{}) int {
+	// pop the smallest block id so the refinement is deterministic
+	smallest := -1
+	for id := range work {
+		if smallest < 0 || id < smallest {
+			smallest = id
+		}
+	}
+	delete(work, smallest)
+	return smallest
+}
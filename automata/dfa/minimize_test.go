@@ -0,0 +1,133 @@
+package dfa_test
+
+import (
+	"testing"
+
+	"github.com/patrickhuber/go-earley/automata/dfa"
+	"github.com/patrickhuber/go-earley/automata/nfa"
+	"github.com/patrickhuber/go-earley/automata/transform"
+	"github.com/patrickhuber/go-earley/re"
+	"github.com/patrickhuber/go-earley/terminal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinimize(t *testing.T) {
+	t.Run("merges equivalent states", func(t *testing.T) {
+		// a(b|c) where b and c lead to different final states
+		b := &dfa.State{Final: true}
+		c := &dfa.State{Final: true}
+		a := &dfa.State{
+			Transitions: []dfa.Transition{
+				{Terminal: terminal.NewCharacter('b'), Target: b},
+				{Terminal: terminal.NewCharacter('c'), Target: c},
+			},
+		}
+		start := &dfa.State{
+			Transitions: []dfa.Transition{
+				{Terminal: terminal.NewCharacter('a'), Target: a},
+			},
+		}
+		d := dfa.Minimize(dfa.NewDfa(start, "abc"))
+		require.Equal(t, "abc", d.TokenType())
+		require.Equal(t, 3, Count(d))
+		require.True(t, Accepts(d, "ab"))
+		require.True(t, Accepts(d, "ac"))
+		require.False(t, Accepts(d, "a"))
+		require.False(t, Accepts(d, "ad"))
+	})
+	t.Run("removes dead states", func(t *testing.T) {
+		dead := &dfa.State{}
+		dead.Transitions = []dfa.Transition{
+			{Terminal: terminal.NewAny(), Target: dead},
+		}
+		end := &dfa.State{Final: true}
+		start := &dfa.State{
+			Transitions: []dfa.Transition{
+				{Terminal: terminal.NewCharacter('a'), Target: end},
+				{Terminal: terminal.NewCharacter('b'), Target: dead},
+			},
+		}
+		d := dfa.Minimize(dfa.NewDfa(start, "a"))
+		require.Equal(t, 2, Count(d))
+		require.True(t, Accepts(d, "a"))
+		require.False(t, Accepts(d, "b"))
+	})
+	t.Run("regex", func(t *testing.T) {
+		tests := []struct {
+			pattern string
+			states  int
+		}{
+			{"a", 2},
+			{"ab|ac", 3},
+			{"a|b", 2},
+			{"(a|b)*abb", 4},
+			{"a*b", 2},
+			{"(a|ab)(c|bcd)", 7},
+			{`\d+(\.\d+)?`, 4},
+			{"[a-c]+[^a]?", 3},
+		}
+		alphabet := []rune{'a', 'b', 'c', 'd', '1', '.', ' '}
+		inputs := generate(alphabet, 4)
+		for _, test := range tests {
+			t.Run(test.pattern, func(t *testing.T) {
+				definition, err := re.Parse(test.pattern)
+				require.NoError(t, err)
+				n, err := nfa.FromRegex(definition)
+				require.NoError(t, err)
+				d, err := transform.Nfa2Dfa(n, test.pattern)
+				require.NoError(t, err)
+				m := dfa.Minimize(d)
+				require.Equal(t, test.pattern, m.TokenType())
+				require.Equal(t, test.states, Count(m))
+				for _, input := range inputs {
+					require.Equal(t, Accepts(d, input), Accepts(m, input), "pattern %s input %q", test.pattern, input)
+				}
+			})
+		}
+	})
+}
+
+// Count returns the number of states reachable from the start state
+func Count(d *dfa.Dfa) int {
+	visited := map[*dfa.State]struct{}{d.Start: {}}
+	work := []*dfa.State{d.Start}
+	for len(work) > 0 {
+		state := work[0]
+		work = work[1:]
+		for _, transition := range state.Transitions {
+			if _, ok := visited[transition.Target]; ok {
+				continue
+			}
+			visited[transition.Target] = struct{}{}
+			work = append(work, transition.Target)
+		}
+	}
+	return len(visited)
+}
+
+func Accepts(d *dfa.Dfa, input string) bool {
+	lexeme := dfa.NewLexeme(d, 0)
+	for _, ch := range input {
+		if !lexeme.Scan(ch) {
+			return false
+		}
+	}
+	return lexeme.Accepted()
+}
+
+// generate returns every string over the alphabet up to the given length
+func generate(alphabet []rune, length int) []string {
+	result := []string{""}
+	previous := []string{""}
+	for i := 0; i < length; i++ {
+		var next []string
+		for _, prefix := range previous {
+			for _, ch := range alphabet {
+				next = append(next, prefix+string(ch))
+			}
+		}
+		result = append(result, next...)
+		previous = next
+	}
+	return result
+}
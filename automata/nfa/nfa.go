@@ -2,5 +2,55 @@ package nfa
 
 type Nfa struct {
 	Start *State
-	Final bool
+	End   *State
+}
+
+// Accepts simulates the nfa over the input and returns true if the end state is reached
+func (n *Nfa) Accepts(input string) bool {
+	current := Closure(n.Start)
+	for _, ch := range input {
+		var next []*State
+		for _, state := range current {
+			for _, transition := range state.Transitions {
+				terminal, ok := transition.(*Terminal)
+				if !ok || !terminal.Terminal().IsMatch(ch) {
+					continue
+				}
+				next = append(next, terminal.Target())
+			}
+		}
+		if len(next) == 0 {
+			return false
+		}
+		current = Closure(next...)
+	}
+	for _, state := range current {
+		if state == n.End {
+			return true
+		}
+	}
+	return false
+}
+
+// Closure returns the states reachable from the given states using only null transitions
+// the given states are included in the result
+func Closure(states ...*State) []*State {
+	visited := map[*State]struct{}{}
+	var closure []*State
+	work := append([]*State{}, states...)
+	for len(work) > 0 {
+		state := work[0]
+		work = work[1:]
+		if _, ok := visited[state]; ok {
+			continue
+		}
+		visited[state] = struct{}{}
+		closure = append(closure, state)
+		for _, transition := range state.Transitions {
+			if null, ok := transition.(*Null); ok {
+				work = append(work, null.Target())
+			}
+		}
+	}
+	return closure
 }
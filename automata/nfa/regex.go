@@ -0,0 +1,293 @@
+package nfa
+
+import (
+	"fmt"
+
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/re"
+	"github.com/patrickhuber/go-earley/terminal"
+)
+
+// FromRegex converts the regular expression definition into an nfa using thompson's construction
+// The start (^) and end ($) anchors are ignored because lexer rules always match from the start of a token.
+func FromRegex(definition *re.Definition) (*Nfa, error) {
+	return expression(definition.Expression)
+}
+
+func expression(e re.Expression) (*Nfa, error) {
+	switch e := e.(type) {
+	case re.ExpressionTerm:
+		return term(e.Term)
+	case re.ExpressionTermExpression:
+		first, err := term(e.Term)
+		if err != nil {
+			return nil, err
+		}
+		second, err := expression(e.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return union(first, second), nil
+	}
+	return nil, fmt.Errorf("unrecognized expression %T", e)
+}
+
+func term(t re.Term) (*Nfa, error) {
+	switch t := t.(type) {
+	case re.TermFactor:
+		return factor(t.Factor)
+	case re.TermFactorTerm:
+		first, err := factor(t.Factor)
+		if err != nil {
+			return nil, err
+		}
+		second, err := term(t.Term)
+		if err != nil {
+			return nil, err
+		}
+		return concatenation(first, second), nil
+	}
+	return nil, fmt.Errorf("unrecognized term %T", t)
+}
+
+func factor(f re.Factor) (*Nfa, error) {
+	switch f := f.(type) {
+	case re.FactorAtom:
+		return atom(f.Atom)
+	case re.FactorAtomIterator:
+		n, err := atom(f.Atom)
+		if err != nil {
+			return nil, err
+		}
+		switch f.Iterator {
+		case re.ZeroOrMany:
+			return kleene(n), nil
+		case re.OneOrMany:
+			return oneOrMany(n), nil
+		case re.ZeroOrOne:
+			return optional(n), nil
+		}
+		return nil, fmt.Errorf("unrecognized iterator %s", f.Iterator)
+	}
+	return nil, fmt.Errorf("unrecognized factor %T", f)
+}
+
+func atom(a re.Atom) (*Nfa, error) {
+	switch a := a.(type) {
+	case re.AtomAny:
+		return single(terminal.NewAny()), nil
+	case re.AtomCharacter:
+		t, err := character(a.Character)
+		if err != nil {
+			return nil, err
+		}
+		return single(t), nil
+	case re.AtomSet:
+		t, err := set(a.Set)
+		if err != nil {
+			return nil, err
+		}
+		return single(t), nil
+	case re.AtomExpression:
+		return expression(a.Expression)
+	}
+	return nil, fmt.Errorf("unrecognized atom %T", a)
+}
+
+func set(s re.Set) (grammar.Terminal, error) {
+	switch s := s.(type) {
+	case re.PositiveSet:
+		return characterClass(s.CharacterClass)
+	case re.NegativeSet:
+		t, err := characterClass(s.CharacterClass)
+		if err != nil {
+			return nil, err
+		}
+		return terminal.NewNegate(t), nil
+	}
+	return nil, fmt.Errorf("unrecognized set %T", s)
+}
+
+func characterClass(c re.CharacterClass) (grammar.Terminal, error) {
+	var terminals []grammar.Terminal
+	for c != nil {
+		var r re.CharacterRange
+		switch class := c.(type) {
+		case re.CharacterClassCharacterRange:
+			r = class.CharacterRange
+			c = nil
+		case re.CharacterClassCharacterRangeCharacterClass:
+			r = class.CharacterRange
+			c = class.CharacterClass
+		default:
+			return nil, fmt.Errorf("unrecognized character class %T", c)
+		}
+		t, err := characterRange(r)
+		if err != nil {
+			return nil, err
+		}
+		terminals = append(terminals, t)
+	}
+	if len(terminals) == 1 {
+		return terminals[0], nil
+	}
+	return terminal.NewSet(terminals), nil
+}
+
+func characterRange(r re.CharacterRange) (grammar.Terminal, error) {
+	switch r := r.(type) {
+	case re.CharacterRangeCharacterClassCharacter:
+		return characterClassCharacter(r.Begin)
+	case re.CharacterRangeCharacterClassCharacterRange:
+		min, ok := value(r.Begin)
+		if !ok {
+			return nil, fmt.Errorf("invalid range start %v", r.Begin)
+		}
+		max, ok := value(r.End)
+		if !ok {
+			return nil, fmt.Errorf("invalid range end %v", r.End)
+		}
+		if min > max {
+			return nil, fmt.Errorf("invalid range %c-%c", min, max)
+		}
+		return terminal.NewRange(min, max), nil
+	}
+	return nil, fmt.Errorf("unrecognized character range %T", r)
+}
+
+func character(c re.Character) (grammar.Terminal, error) {
+	switch c := c.(type) {
+	case re.NotMetaCharacter:
+		return terminal.NewCharacter(c.Char), nil
+	case re.EscapeSequence:
+		return escape(c.Char), nil
+	}
+	return nil, fmt.Errorf("unrecognized character %T", c)
+}
+
+func characterClassCharacter(c re.CharacterClassCharacter) (grammar.Terminal, error) {
+	switch c := c.(type) {
+	case re.NotCloseBracketCharacter:
+		return terminal.NewCharacter(c.Char), nil
+	case re.EscapeSequence:
+		return escape(c.Char), nil
+	}
+	return nil, fmt.Errorf("unrecognized character class character %T", c)
+}
+
+// value returns the single rune a character matches
+func value(c re.CharacterClassCharacter) (rune, bool) {
+	switch c := c.(type) {
+	case re.NotCloseBracketCharacter:
+		return c.Char, true
+	case re.EscapeSequence:
+		t, ok := escape(c.Char).(*terminal.Character)
+		if !ok {
+			return 0, false
+		}
+		return t.Value, true
+	}
+	return 0, false
+}
+
+func escape(ch rune) grammar.Terminal {
+	switch ch {
+	case 's':
+		return terminal.NewWhitespace()
+	case 'S':
+		return terminal.NewNegate(terminal.NewWhitespace())
+	case 'd':
+		return terminal.NewNumber()
+	case 'D':
+		return terminal.NewNegate(terminal.NewNumber())
+	case 'w':
+		return word()
+	case 'W':
+		return terminal.NewNegate(word())
+	case 'n':
+		return terminal.NewCharacter('\n')
+	case 'r':
+		return terminal.NewCharacter('\r')
+	case 't':
+		return terminal.NewCharacter('\t')
+	case 'f':
+		return terminal.NewCharacter('\f')
+	}
+	return terminal.NewCharacter(ch)
+}
+
+// word matches the letters, digits and underscore of \w
+func word() grammar.Terminal {
+	return terminal.NewSet([]grammar.Terminal{
+		terminal.NewLetter(),
+		terminal.NewNumber(),
+		terminal.NewCharacter('_'),
+	})
+}
+
+// single creates start -t-> end
+func single(t grammar.Terminal) *Nfa {
+	end := &State{}
+	start := &State{
+		Transitions: []Transition{NewTerminal(t, end)},
+	}
+	return &Nfa{Start: start, End: end}
+}
+
+// concatenation links the end of first to the start of second
+func concatenation(first, second *Nfa) *Nfa {
+	first.End.Transitions = append(first.End.Transitions, NewNull(second.Start))
+	return &Nfa{Start: first.Start, End: second.End}
+}
+
+// union creates a new start and end that branch through first or second
+func union(first, second *Nfa) *Nfa {
+	end := &State{}
+	start := &State{
+		Transitions: []Transition{
+			NewNull(first.Start),
+			NewNull(second.Start),
+		},
+	}
+	first.End.Transitions = append(first.End.Transitions, NewNull(end))
+	second.End.Transitions = append(second.End.Transitions, NewNull(end))
+	return &Nfa{Start: start, End: end}
+}
+
+// kleene matches zero or more repetitions of n
+func kleene(n *Nfa) *Nfa {
+	end := &State{}
+	start := &State{
+		Transitions: []Transition{
+			NewNull(n.Start),
+			NewNull(end),
+		},
+	}
+	n.End.Transitions = append(n.End.Transitions, NewNull(n.Start), NewNull(end))
+	return &Nfa{Start: start, End: end}
+}
+
+// oneOrMany matches one or more repetitions of n
+func oneOrMany(n *Nfa) *Nfa {
+	end := &State{}
+	start := &State{
+		Transitions: []Transition{
+			NewNull(n.Start),
+		},
+	}
+	n.End.Transitions = append(n.End.Transitions, NewNull(n.Start), NewNull(end))
+	return &Nfa{Start: start, End: end}
+}
+
+// optional matches zero or one repetitions of n
+func optional(n *Nfa) *Nfa {
+	end := &State{}
+	start := &State{
+		Transitions: []Transition{
+			NewNull(n.Start),
+			NewNull(end),
+		},
+	}
+	n.End.Transitions = append(n.End.Transitions, NewNull(end))
+	return &Nfa{Start: start, End: end}
+}
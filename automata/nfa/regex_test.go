@@ -0,0 +1,56 @@
+package nfa_test
+
+import (
+	"testing"
+
+	"github.com/patrickhuber/go-earley/automata/nfa"
+	"github.com/patrickhuber/go-earley/re"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromRegex(t *testing.T) {
+	type test struct {
+		pattern string
+		accept  []string
+		reject  []string
+	}
+	tests := []test{
+		{"a", []string{"a"}, []string{"", "b", "aa"}},
+		{"ab", []string{"ab"}, []string{"a", "b", "ba"}},
+		{"a|b", []string{"a", "b"}, []string{"", "ab"}},
+		{"a*", []string{"", "a", "aaa"}, []string{"b", "ab"}},
+		{"a+", []string{"a", "aaa"}, []string{"", "b"}},
+		{"a?", []string{"", "a"}, []string{"aa"}},
+		{"(ab)*c", []string{"c", "abc", "ababc"}, []string{"ac", "abab"}},
+		{".", []string{"a", "."}, []string{"", "ab"}},
+		{"[a-c_]+", []string{"a", "abc_", "cab"}, []string{"d", "a-c"}},
+		{"[^0-9]", []string{"a", "-"}, []string{"0", "9"}},
+		{`\d+`, []string{"0", "123"}, []string{"", "a"}},
+		{`[\s]+`, []string{" ", " \t\n"}, []string{"", "a"}},
+		{`\w+`, []string{"a", "0", "1", "0z", "a_1", "_"}, []string{"", " ", "a-b"}},
+		{`\W`, []string{" ", "-"}, []string{"a", "0", "_"}},
+		{`[\w-]+`, []string{"a-0", "_-_"}, []string{"", "a b"}},
+		{`\.`, []string{"."}, []string{"a"}},
+		{"^a$", []string{"a"}, []string{"", "aa"}},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			definition, err := re.Parse(test.pattern)
+			require.NoError(t, err)
+			n, err := nfa.FromRegex(definition)
+			require.NoError(t, err)
+			for _, input := range test.accept {
+				require.True(t, n.Accepts(input), "expected %s to accept %q", test.pattern, input)
+			}
+			for _, input := range test.reject {
+				require.False(t, n.Accepts(input), "expected %s to reject %q", test.pattern, input)
+			}
+		})
+	}
+	t.Run("invalid range", func(t *testing.T) {
+		definition, err := re.Parse("[z-a]")
+		require.NoError(t, err)
+		_, err = nfa.FromRegex(definition)
+		require.Error(t, err)
+	})
+}
@@ -25,6 +25,10 @@ func (t Terminal) Target() *State {
 	return t.target
 }
 
+func (t Terminal) Terminal() grammar.Terminal {
+	return t.terminal
+}
+
 func NewNull(target *State) *Null {
 	return &Null{
 		target: target,
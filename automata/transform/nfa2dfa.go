@@ -1,10 +1,173 @@
 package transform
 
 import (
+	"sort"
+	"strconv"
+	"strings"
+
 	"github.com/patrickhuber/go-earley/automata/dfa"
 	"github.com/patrickhuber/go-earley/automata/nfa"
+	"github.com/patrickhuber/go-earley/terminal"
 )
 
-func Nfa2Dfa(n *nfa.Nfa) *dfa.Dfa {
-	return &dfa.Dfa{}
+// Nfa2Dfa converts the nfa to a dfa using subset construction
+// The transitions of each dfa state are keyed by disjoint terminals so at most one transition matches any character.
+func Nfa2Dfa(n *nfa.Nfa, tokenType string) (*dfa.Dfa, error) {
+	c := &converter{
+		ids:    map[*nfa.State]int{},
+		states: map[string]*dfa.State{},
+		end:    n.End,
+	}
+	start, _ := c.state(nfa.Closure(n.Start))
+	work := []subset{start}
+	for len(work) > 0 {
+		current := work[0]
+		work = work[1:]
+		created, err := c.transitions(current)
+		if err != nil {
+			return nil, err
+		}
+		work = append(work, created...)
+	}
+	return dfa.NewDfa(start.state, tokenType), nil
+}
+
+// subset is a dfa state and the nfa states it represents
+type subset struct {
+	state  *dfa.State
+	states []*nfa.State
+}
+
+type converter struct {
+	ids    map[*nfa.State]int
+	states map[string]*dfa.State
+	end    *nfa.State
+}
+
+// edge is an nfa terminal transition described by its intervals
+type edge struct {
+	intervals []terminal.Interval
+	target    *nfa.State
+}
+
+// transitions adds a transition for each disjoint class of characters leaving the subset
+// any subsets not seen before are returned
+func (c *converter) transitions(s subset) ([]subset, error) {
+	var edges []edge
+	var bounds []rune
+	for _, state := range s.states {
+		for _, transition := range state.Transitions {
+			t, ok := transition.(*nfa.Terminal)
+			if !ok {
+				continue
+			}
+			intervals, err := terminal.Intervals(t.Terminal())
+			if err != nil {
+				return nil, err
+			}
+			edges = append(edges, edge{intervals: intervals, target: t.Target()})
+			for _, interval := range intervals {
+				bounds = append(bounds, interval.Min, interval.Max+1)
+			}
+		}
+	}
+	bounds = unique(bounds)
+
+	// split the characters into elementary segments between bounds and group the segments by target subset
+	var keys []string
+	groups := map[string][]terminal.Interval{}
+	targets := map[string][]*nfa.State{}
+	for i := 0; i+1 < len(bounds); i++ {
+		segment := terminal.Interval{Min: bounds[i], Max: bounds[i+1] - 1}
+		var next []*nfa.State
+		for _, e := range edges {
+			if contains(e.intervals, segment.Min) {
+				next = append(next, e.target)
+			}
+		}
+		if len(next) == 0 {
+			continue
+		}
+		closure := nfa.Closure(next...)
+		key := c.key(closure)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+			targets[key] = closure
+		}
+		groups[key] = append(groups[key], segment)
+	}
+
+	var created []subset
+	for _, key := range keys {
+		target, ok := c.state(targets[key])
+		if ok {
+			created = append(created, target)
+		}
+		s.state.Transitions = append(s.state.Transitions, dfa.Transition{
+			Terminal: terminal.FromIntervals(terminal.Normalize(groups[key])),
+			Target:   target.state,
+		})
+	}
+	return created, nil
+}
+
+// state returns the dfa state for the nfa states, true is returned if the state was created
+func (c *converter) state(states []*nfa.State) (subset, bool) {
+	key := c.key(states)
+	s := subset{states: states}
+	if existing, ok := c.states[key]; ok {
+		s.state = existing
+		return s, false
+	}
+	s.state = &dfa.State{}
+	for _, state := range states {
+		if state == c.end {
+			s.state.Final = true
+		}
+	}
+	c.states[key] = s.state
+	return s, true
+}
+
+// key returns a unique key for the set of nfa states
+func (c *converter) key(states []*nfa.State) string {
+	ids := make([]int, 0, len(states))
+	for _, state := range states {
+		id, ok := c.ids[state]
+		if !ok {
+			id = len(c.ids)
+			c.ids[state] = id
+		}
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	var builder strings.Builder
+	for i, id := range ids {
+		if i > 0 {
+			builder.WriteRune(',')
+		}
+		builder.WriteString(strconv.Itoa(id))
+	}
+	return builder.String()
+}
+
+func unique(runes []rune) []rune {
+	sort.Slice(runes, func(i, j int) bool {
+		return runes[i] < runes[j]
+	})
+	var result []rune
+	for i, r := range runes {
+		if i > 0 && runes[i-1] == r {
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+func contains(intervals []terminal.Interval, ch rune) bool {
+	i := sort.Search(len(intervals), func(i int) bool {
+		return intervals[i].Max >= ch
+	})
+	return i < len(intervals) && intervals[i].Min <= ch
 }
@@ -0,0 +1,99 @@
+package transform_test
+
+import (
+	"testing"
+
+	"github.com/patrickhuber/go-earley/automata/dfa"
+	"github.com/patrickhuber/go-earley/automata/nfa"
+	"github.com/patrickhuber/go-earley/automata/transform"
+	"github.com/patrickhuber/go-earley/re"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNfa2Dfa(t *testing.T) {
+	patterns := []string{
+		"a",
+		"ab|ac",
+		"a*b",
+		"(a|b)*abb",
+		"[a-c]+[^a]?",
+		`\d+(\.\d+)?`,
+		`[\s]+`,
+		".b*",
+		"(a|ab)(c|bcd)",
+		`\w+`,
+		`\W\w`,
+	}
+	alphabet := []rune{'a', 'b', 'c', 'd', '1', '_', '.', ' '}
+	inputs := generate(alphabet, 4)
+
+	for _, pattern := range patterns {
+		t.Run(pattern, func(t *testing.T) {
+			definition, err := re.Parse(pattern)
+			require.NoError(t, err)
+			n, err := nfa.FromRegex(definition)
+			require.NoError(t, err)
+			d, err := transform.Nfa2Dfa(n, pattern)
+			require.NoError(t, err)
+			require.Equal(t, pattern, d.TokenType())
+
+			RequireDisjoint(t, d, alphabet)
+			for _, input := range inputs {
+				require.Equal(t, n.Accepts(input), Accepts(d, input), "pattern %s input %q", pattern, input)
+			}
+		})
+	}
+}
+
+// generate returns every string over the alphabet up to the given length
+func generate(alphabet []rune, length int) []string {
+	result := []string{""}
+	previous := []string{""}
+	for i := 0; i < length; i++ {
+		var next []string
+		for _, prefix := range previous {
+			for _, ch := range alphabet {
+				next = append(next, prefix+string(ch))
+			}
+		}
+		result = append(result, next...)
+		previous = next
+	}
+	return result
+}
+
+func Accepts(d *dfa.Dfa, input string) bool {
+	lexeme := dfa.NewLexeme(d, 0)
+	for _, ch := range input {
+		if !lexeme.Scan(ch) {
+			return false
+		}
+	}
+	return lexeme.Accepted()
+}
+
+// RequireDisjoint checks that no two transitions from a state match the same character
+func RequireDisjoint(t *testing.T, d *dfa.Dfa, alphabet []rune) {
+	visited := map[*dfa.State]struct{}{}
+	work := []*dfa.State{d.Start}
+	for len(work) > 0 {
+		state := work[0]
+		work = work[1:]
+		if _, ok := visited[state]; ok {
+			continue
+		}
+		visited[state] = struct{}{}
+		for _, ch := range alphabet {
+			count := 0
+			for _, transition := range state.Transitions {
+				if transition.Terminal.IsMatch(ch) {
+					count++
+				}
+			}
+			require.LessOrEqual(t, count, 1, "character %q matches more than one transition", ch)
+		}
+		for _, transition := range state.Transitions {
+			work = append(work, transition.Target)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// pdlfmt formats pdl files
+//
+//	pdlfmt [-w] [file ...]
+//
+// Without files it formats standard input to standard output.
+// With -w the formatted text replaces the contents of each file instead of being written to standard output.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/patrickhuber/go-earley/pdl"
+)
+
+func main() {
+	write := flag.Bool("w", false, "write the result to the file instead of standard output")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		if *write {
+			fail(fmt.Errorf("-w requires a file"))
+		}
+		source, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fail(err)
+		}
+		if err := format("<stdin>", source, false); err != nil {
+			fail(err)
+		}
+		return
+	}
+
+	failed := false
+	for _, path := range flag.Args() {
+		source, err := os.ReadFile(path)
+		if err == nil {
+			err = format(path, source, *write)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func format(path string, source []byte, write bool) error {
+	output, err := pdl.FormatSource(source)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if !write {
+		_, err := os.Stdout.Write(output)
+		return err
+	}
+	if bytes.Equal(source, output) {
+		return nil
+	}
+	return os.WriteFile(path, output, 0o644)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
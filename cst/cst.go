@@ -0,0 +1,105 @@
+// Package cst converts a parse forest into a concrete syntax tree
+// Intermediate nodes are flattened away so each node holds the full right hand side of its production.
+package cst
+
+import (
+	"fmt"
+
+	"github.com/patrickhuber/go-earley/forest"
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/token"
+)
+
+// Node is a nonterminal with the children of its production or a token leaf
+type Node struct {
+	// Production is the production of a nonterminal, it is nil for tokens
+	Production *grammar.Production
+	// Token is the token of a leaf, it is nil for nonterminals
+	Token    token.Token
+	Children []*Node
+	// Span covers the text of the tokens below the node
+	// an empty nonterminal has a zero length span at the end of the preceding token
+	Span  token.Span
+	Start token.Location
+	End   token.Location
+}
+
+// Symbol returns the left hand side of the production or nil for tokens
+func (n *Node) Symbol() grammar.NonTerminal {
+	if n.Production == nil {
+		return nil
+	}
+	return n.Production.LeftHandSide
+}
+
+// Text returns the text of a token leaf or an empty string if the token does not carry its text
+func (n *Node) Text() string {
+	capture, ok := n.Token.(*token.Capture)
+	if !ok {
+		return ""
+	}
+	return capture.Text
+}
+
+// New converts an unambiguous forest into a tree
+// an error is returned if any node of the forest has more than one alternative
+func New(root forest.Node) (*Node, error) {
+	if ambiguities := forest.Ambiguities(root); len(ambiguities) > 0 {
+		return nil, fmt.Errorf("unable to convert an ambiguous forest, %s has %d alternatives",
+			ambiguities[0], len(ambiguities[0].Productions))
+	}
+	trees := forest.Trees(root)
+	if !trees.Next() {
+		return nil, fmt.Errorf("the forest contains no trees")
+	}
+	return FromTree(trees.Tree()), nil
+}
+
+// FromTree converts one tree chosen from a forest
+func FromTree(tree *forest.Tree) *Node {
+	b := &builder{}
+	return b.node(tree)
+}
+
+// builder tracks the end of the last token so empty nonterminals get a position
+type builder struct {
+	offset int
+	end    token.Location
+}
+
+func (b *builder) node(tree *forest.Tree) *Node {
+	if tok, ok := tree.Node.(*forest.Token); ok {
+		n := &Node{
+			Token: tok.Token,
+			Span:  tok.Span(),
+			Start: tok.Start(),
+			End:   tok.End(),
+		}
+		b.offset = n.Span.Offset + n.Span.Length
+		b.end = n.End
+		return n
+	}
+
+	n := &Node{
+		Production: tree.Production,
+		Span:       token.Span{Offset: b.offset},
+		Start:      b.end,
+		End:        b.end,
+	}
+	empty := true
+	for _, child := range tree.Children {
+		c := b.node(child)
+		n.Children = append(n.Children, c)
+		if c.Span.Length == 0 && c.Token == nil {
+			continue
+		}
+		if empty {
+			n.Span.Offset = c.Span.Offset
+			n.Start = c.Start
+			empty = false
+		}
+		n.Span.Length = c.Span.Offset + c.Span.Length - n.Span.Offset
+		n.End = c.End
+	}
+	return n
+}
@@ -0,0 +1,129 @@
+package cst_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/patrickhuber/go-earley/cst"
+	"github.com/patrickhuber/go-earley/forest"
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/parser"
+	"github.com/patrickhuber/go-earley/pdl"
+	"github.com/patrickhuber/go-earley/scanner"
+	"github.com/patrickhuber/go-earley/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("flattens intermediate nodes", func(t *testing.T) {
+		g := Compile(t, `
+			S = A B A 'c';
+			A = 'a';
+			B = 'b' | '';
+			Whitespace ~ /[\s]+/;
+			:ignore = Whitespace;`)
+		tree, err := cst.New(Run(t, g, "a\n a c"))
+		require.NoError(t, err)
+
+		require.Equal(t, "S", tree.Symbol().Name())
+		require.Len(t, tree.Children, 4)
+		require.Equal(t, token.Span{Offset: 0, Length: 6}, tree.Span)
+		require.Equal(t, token.Location{Line: 1, Column: 1}, tree.Start)
+		require.Equal(t, token.Location{Line: 2, Column: 4}, tree.End)
+
+		a := tree.Children[0]
+		require.Equal(t, "A", a.Symbol().Name())
+		require.Len(t, a.Children, 1)
+		require.Equal(t, "a", a.Children[0].Text())
+		require.Nil(t, a.Children[0].Production)
+
+		b := tree.Children[1]
+		require.Equal(t, "B", b.Symbol().Name())
+		require.Empty(t, b.Children)
+		require.Same(t, Production(t, g, "B ->"), b.Production)
+		require.Equal(t, token.Span{Offset: 1, Length: 0}, b.Span)
+
+		require.Equal(t, token.Span{Offset: 3, Length: 1}, tree.Children[2].Span)
+		require.Equal(t, token.Location{Line: 2, Column: 2}, tree.Children[2].Start)
+		require.Equal(t, "c", tree.Children[3].Text())
+	})
+	t.Run("keeps the productions of empty nonterminals", func(t *testing.T) {
+		g := Compile(t, `S = A 'c'; A = B; B = '';`)
+		tree, err := cst.New(Run(t, g, "c"))
+		require.NoError(t, err)
+
+		a := tree.Children[0]
+		require.Same(t, Production(t, g, "A -> B"), a.Production)
+		require.Len(t, a.Children, 1)
+		b := a.Children[0]
+		require.Same(t, Production(t, g, "B ->"), b.Production)
+		require.Empty(t, b.Children)
+	})
+	t.Run("rejects ambiguous forests", func(t *testing.T) {
+		root := Parse(t, `E = E '+' E | 'a';`, "a+a+a")
+		_, err := cst.New(root)
+		require.Error(t, err)
+	})
+}
+
+func TestFromTree(t *testing.T) {
+	root := Parse(t, `E = E '+' E | 'a';`, "a+a+a")
+	trees := forest.Trees(root)
+	var shapes []string
+	for trees.Next() {
+		tree := cst.FromTree(trees.Tree())
+		require.Equal(t, "E -> E + E", tree.Production.String())
+		require.Len(t, tree.Children, 3)
+		require.Equal(t, token.Span{Offset: 0, Length: 5}, tree.Span)
+		shapes = append(shapes, Shape(tree))
+	}
+	require.ElementsMatch(t, []string{"((a+a)+a)", "(a+(a+a))"}, shapes)
+}
+
+func Parse(t *testing.T, definition string, input string) forest.Node {
+	return Run(t, Compile(t, definition), input)
+}
+
+func Compile(t *testing.T, definition string) *grammar.Grammar {
+	d, err := pdl.Parse(strings.NewReader(definition))
+	require.NoError(t, err)
+	g, err := pdl.Compile(d)
+	require.NoError(t, err)
+	return g
+}
+
+// Production returns the production of the grammar with the text
+func Production(t *testing.T, g *grammar.Grammar, text string) *grammar.Production {
+	for _, production := range g.Productions {
+		if production.String() == text {
+			return production
+		}
+	}
+	require.FailNow(t, "production not found", text)
+	return nil
+}
+
+func Run(t *testing.T, g *grammar.Grammar, input string) forest.Node {
+	p := parser.New(g)
+	accepted, err := scanner.RunToEnd(scanner.New(p, input))
+	require.NoError(t, err)
+	require.True(t, accepted)
+	root, ok := p.GetForestRoot()
+	require.True(t, ok)
+	return root
+}
+
+// Shape prints the tokens of the tree with parentheses around nodes with more than one child
+func Shape(node *cst.Node) string {
+	if node.Token != nil {
+		return node.Text()
+	}
+	var sb strings.Builder
+	for _, child := range node.Children {
+		sb.WriteString(Shape(child))
+	}
+	if len(node.Children) > 1 {
+		return "(" + sb.String() + ")"
+	}
+	return sb.String()
+}
@@ -0,0 +1,237 @@
+// Package eval computes values from a parse forest with the actions of the grammar productions
+package eval
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/patrickhuber/go-earley/forest"
+	"github.com/patrickhuber/go-earley/token"
+)
+
+// Strategy resolves the values of the alternatives of an ambiguous node into one value
+type Strategy func(ctx context.Context, node forest.Node, values []any) (any, error)
+
+// Fail returns an error for ambiguous nodes, it is the default strategy
+func Fail(ctx context.Context, node forest.Node, values []any) (any, error) {
+	return nil, fmt.Errorf("%v is ambiguous with %d derivations", node, len(values))
+}
+
+// First keeps the value of the first alternative
+func First(ctx context.Context, node forest.Node, values []any) (any, error) {
+	return values[0], nil
+}
+
+// All keeps the values of every alternative as a []any
+func All(ctx context.Context, node forest.Node, values []any) (any, error) {
+	return values, nil
+}
+
+type Option func(*Evaluator)
+
+// Ambiguity sets the strategy for ambiguous nodes
+func Ambiguity(strategy Strategy) Option {
+	return func(e *Evaluator) {
+		e.strategy = strategy
+	}
+}
+
+// Evaluator folds a forest bottom up with the actions of the productions
+// A token evaluates to its text, or the token itself if the text was not captured.
+// A production without an action evaluates to the value of its only child or to the values of its children as a []any.
+// The value of each node is computed once.
+type Evaluator struct {
+	strategy Strategy
+}
+
+func New(options ...Option) *Evaluator {
+	e := &Evaluator{
+		strategy: Fail,
+	}
+	for _, option := range options {
+		option(e)
+	}
+	return e
+}
+
+// Evaluate returns the value of the root of the forest
+func Evaluate(ctx context.Context, root forest.Node, options ...Option) (any, error) {
+	return New(options...).Evaluate(ctx, root)
+}
+
+// Evaluate returns the value of the root of the forest
+func (e *Evaluator) Evaluate(ctx context.Context, root forest.Node) (any, error) {
+	f := &fold{
+		evaluator:     e,
+		values:        map[forest.Node]any{},
+		intermediates: map[*forest.Intermediate][][]any{},
+		active:        map[forest.Node]struct{}{},
+	}
+	return f.value(ctx, root)
+}
+
+// fold holds the memoized values of one evaluation
+type fold struct {
+	evaluator     *Evaluator
+	values        map[forest.Node]any
+	intermediates map[*forest.Intermediate][][]any
+	active        map[forest.Node]struct{}
+}
+
+func (f *fold) value(ctx context.Context, node forest.Node) (any, error) {
+	if value, ok := f.values[node]; ok {
+		return value, nil
+	}
+	if err := f.enter(node); err != nil {
+		return nil, err
+	}
+	defer delete(f.active, node)
+
+	var value any
+	var err error
+	switch n := node.(type) {
+	case *forest.Token:
+		value = tokenValue(n)
+	case *forest.Symbol:
+		value, err = f.symbol(ctx, n)
+	case *forest.Intermediate:
+		value, err = f.single(ctx, n)
+	default:
+		err = fmt.Errorf("unsupported node %T", node)
+	}
+	if err != nil {
+		return nil, err
+	}
+	f.values[node] = value
+	return value, nil
+}
+
+func (f *fold) symbol(ctx context.Context, s *forest.Symbol) (any, error) {
+	alternatives := s.Alternatives()
+	if len(alternatives) == 0 {
+		return nil, nil
+	}
+	var values []any
+	for _, alternative := range alternatives {
+		sequences, err := f.children(ctx, alternative)
+		if err != nil {
+			return nil, err
+		}
+		production := forest.Production(s, alternative)
+		for _, children := range sequences {
+			if production.Action == nil {
+				values = append(values, passThrough(children))
+				continue
+			}
+			value, err := production.Action(ctx, children)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+	}
+	if len(values) == 1 {
+		return values[0], nil
+	}
+	return f.evaluator.strategy(ctx, s, values)
+}
+
+// single returns the values of the children of an intermediate root, the ambiguity strategy chooses between alternatives
+func (f *fold) single(ctx context.Context, i *forest.Intermediate) (any, error) {
+	sequences, err := f.intermediate(ctx, i)
+	if err != nil {
+		return nil, err
+	}
+	if len(sequences) == 1 {
+		return sequences[0], nil
+	}
+	values := make([]any, len(sequences))
+	for k, sequence := range sequences {
+		values[k] = sequence
+	}
+	return f.evaluator.strategy(ctx, i, values)
+}
+
+// children returns the values of the children of the alternative for each derivation of its intermediate nodes
+func (f *fold) children(ctx context.Context, alternative forest.Group) ([][]any, error) {
+	sequences := [][]any{{}}
+	for _, child := range alternative.Children() {
+		var next [][]any
+		if intermediate, ok := child.(*forest.Intermediate); ok {
+			expanded, err := f.intermediate(ctx, intermediate)
+			if err != nil {
+				return nil, err
+			}
+			for _, sequence := range sequences {
+				for _, tail := range expanded {
+					next = append(next, concat(sequence, tail...))
+				}
+			}
+		} else {
+			value, err := f.value(ctx, child)
+			if err != nil {
+				return nil, err
+			}
+			for _, sequence := range sequences {
+				next = append(next, concat(sequence, value))
+			}
+		}
+		sequences = next
+	}
+	return sequences, nil
+}
+
+// intermediate returns the values of the children the intermediate node covers for each of its derivations
+func (f *fold) intermediate(ctx context.Context, i *forest.Intermediate) ([][]any, error) {
+	if sequences, ok := f.intermediates[i]; ok {
+		return sequences, nil
+	}
+	if err := f.enter(i); err != nil {
+		return nil, err
+	}
+	defer delete(f.active, i)
+
+	var sequences [][]any
+	for _, alternative := range i.Alternatives() {
+		expanded, err := f.children(ctx, alternative)
+		if err != nil {
+			return nil, err
+		}
+		sequences = append(sequences, expanded...)
+	}
+	f.intermediates[i] = sequences
+	return sequences, nil
+}
+
+func (f *fold) enter(node forest.Node) error {
+	if _, ok := f.active[node]; ok {
+		return fmt.Errorf("%v derives itself", node)
+	}
+	f.active[node] = struct{}{}
+	return nil
+}
+
+func tokenValue(t *forest.Token) any {
+	if _, ok := t.Token.(*token.Capture); ok {
+		return t.Text()
+	}
+	return t.Token
+}
+
+// passThrough is the value of a production without an Action, an empty production has no value
+func passThrough(children []any) any {
+	switch len(children) {
+	case 0:
+		return nil
+	case 1:
+		return children[0]
+	}
+	return children
+}
+
+// concat returns a new slice so sequences that share a prefix do not share storage
+func concat(sequence []any, values ...any) []any {
+	result := make([]any, 0, len(sequence)+len(values))
+	result = append(result, sequence...)
+	return append(result, values...)
+}
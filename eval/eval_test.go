@@ -0,0 +1,153 @@
+package eval_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/patrickhuber/go-earley/eval"
+	"github.com/patrickhuber/go-earley/forest"
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/parser"
+	"github.com/patrickhuber/go-earley/pdl"
+	"github.com/patrickhuber/go-earley/scanner"
+	"github.com/stretchr/testify/require"
+)
+
+const calculator = `
+	Expression
+		= Expression '+' Expression @left @priority(1)
+		| Expression '-' Expression @left @priority(1)
+		| Expression '*' Expression @left @priority(2)
+		| '(' Expression ')'
+		| Number;
+	Number ~ /[0-9]+/ ;
+	Whitespace ~ /[\s]+/ ;
+	:ignore = Whitespace;`
+
+func TestEvaluate(t *testing.T) {
+	g := Compile(t, calculator)
+	Act(t, g, "Expression -> Expression + Expression", func(ctx context.Context, children []any) (any, error) {
+		return children[0].(int) + children[2].(int), nil
+	})
+	Act(t, g, "Expression -> Expression - Expression", func(ctx context.Context, children []any) (any, error) {
+		return children[0].(int) - children[2].(int), nil
+	})
+	Act(t, g, "Expression -> Expression * Expression", func(ctx context.Context, children []any) (any, error) {
+		return children[0].(int) * children[2].(int), nil
+	})
+	Act(t, g, "Expression -> ( Expression )", func(ctx context.Context, children []any) (any, error) {
+		return children[1], nil
+	})
+	Act(t, g, "Expression -> Number", func(ctx context.Context, children []any) (any, error) {
+		return strconv.Atoi(children[0].(string))
+	})
+
+	tests := []struct {
+		input string
+		value int
+	}{
+		{"1 + 2 * 3", 7},
+		{"10 - 4 - 3", 3},
+		{"(1 + 2) * 3", 9},
+		{"2 * 3 * 4 - 1", 23},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			value, err := eval.Evaluate(context.Background(), Parse(t, g, test.input))
+			require.NoError(t, err)
+			require.Equal(t, test.value, value)
+		})
+	}
+	t.Run("returns action errors", func(t *testing.T) {
+		_, err := eval.Evaluate(context.Background(), Parse(t, g, "99999999999999999999"))
+		require.Error(t, err)
+	})
+}
+
+func TestAmbiguity(t *testing.T) {
+	g := Compile(t, `E = E '-' E | Number; Number ~ /[0-9]+/ ;`)
+	Act(t, g, "E -> E - E", func(ctx context.Context, children []any) (any, error) {
+		return children[0].(int) - children[2].(int), nil
+	})
+	Act(t, g, "E -> Number", func(ctx context.Context, children []any) (any, error) {
+		return strconv.Atoi(children[0].(string))
+	})
+	root := Parse(t, g, "8-4-2")
+
+	t.Run("fails by default", func(t *testing.T) {
+		_, err := eval.Evaluate(context.Background(), root)
+		require.Error(t, err)
+	})
+	t.Run("first", func(t *testing.T) {
+		value, err := eval.Evaluate(context.Background(), root, eval.Ambiguity(eval.First))
+		require.NoError(t, err)
+		require.Contains(t, []any{2, 6}, value)
+	})
+	t.Run("all", func(t *testing.T) {
+		value, err := eval.Evaluate(context.Background(), root, eval.Ambiguity(eval.All))
+		require.NoError(t, err)
+		require.ElementsMatch(t, []any{2, 6}, value)
+	})
+	t.Run("custom", func(t *testing.T) {
+		largest := func(ctx context.Context, node forest.Node, values []any) (any, error) {
+			max := values[0].(int)
+			for _, value := range values[1:] {
+				if value.(int) > max {
+					max = value.(int)
+				}
+			}
+			return max, nil
+		}
+		value, err := eval.New(eval.Ambiguity(largest)).Evaluate(context.Background(), root)
+		require.NoError(t, err)
+		require.Equal(t, 6, value)
+	})
+}
+
+func TestDefaultAction(t *testing.T) {
+	g := Compile(t, `S = A 'b' 'c'; A = 'a';`)
+	value, err := eval.Evaluate(context.Background(), Parse(t, g, "abc"))
+	require.NoError(t, err)
+	require.Equal(t, []any{"a", "b", "c"}, value)
+}
+
+func TestEmptyAction(t *testing.T) {
+	g := Compile(t, `S = A 'a'; A = B; B = '';`)
+	Act(t, g, "B ->", func(ctx context.Context, children []any) (any, error) {
+		require.Equal(t, []any{}, children)
+		return 42, nil
+	})
+	value, err := eval.Evaluate(context.Background(), Parse(t, g, "a"))
+	require.NoError(t, err)
+	require.Equal(t, []any{42, "a"}, value)
+}
+
+func Compile(t *testing.T, input string) *grammar.Grammar {
+	d, err := pdl.Parse(strings.NewReader(input))
+	require.NoError(t, err)
+	g, err := pdl.Compile(d)
+	require.NoError(t, err)
+	return g
+}
+
+func Act(t *testing.T, g *grammar.Grammar, text string, action grammar.Action) {
+	for _, production := range g.Productions {
+		if production.String() == text {
+			production.Action = action
+			return
+		}
+	}
+	require.FailNow(t, "production not found", text)
+}
+
+func Parse(t *testing.T, g *grammar.Grammar, input string) forest.Node {
+	p := parser.New(g)
+	accepted, err := scanner.RunToEnd(scanner.New(p, input))
+	require.NoError(t, err)
+	require.True(t, accepted)
+	root, ok := p.GetForestRoot()
+	require.True(t, ok)
+	return root
+}
@@ -0,0 +1,122 @@
+package forest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/token"
+)
+
+// Ambiguity is a node of the forest with more than one derivation
+type Ambiguity struct {
+	Node Node
+	// Symbol is the symbol of a Symbol node or the left hand side of the rule of an Intermediate node
+	Symbol   grammar.Symbol
+	Origin   int
+	Location int
+	// Productions holds the production of each alternative of the node in order
+	// the alternatives of an Intermediate node all share the production of its rule
+	Productions []*grammar.Production
+}
+
+func (a Ambiguity) String() string {
+	var productions []string
+	for _, production := range a.Productions {
+		productions = append(productions, production.String())
+	}
+	return fmt.Sprintf("%s(%d, %d): %s", a.Symbol, a.Origin, a.Location, strings.Join(productions, " | "))
+}
+
+// Ambiguities walks every alternative reachable from the root and returns the nodes with more than one alternative
+// The nodes are listed in depth first order, each node is listed once.
+func Ambiguities(root Node) []Ambiguity {
+	var ambiguities []Ambiguity
+	visited := map[Node]struct{}{}
+	var walk func(node Node)
+	walk = func(node Node) {
+		if node == nil {
+			return
+		}
+		if _, ok := visited[node]; ok {
+			return
+		}
+		visited[node] = struct{}{}
+
+		var alternatives []Group
+		switch n := node.(type) {
+		case *Symbol:
+			alternatives = n.Alternatives()
+			if len(alternatives) > 1 {
+				ambiguities = append(ambiguities, ambiguity(n, n.Symbol, alternatives))
+			}
+		case *Intermediate:
+			alternatives = n.Alternatives()
+			if len(alternatives) > 1 {
+				ambiguities = append(ambiguities, ambiguity(n, n.Rule.Production.LeftHandSide, alternatives))
+			}
+		}
+		for _, alternative := range alternatives {
+			for _, child := range alternative.Children() {
+				walk(child)
+			}
+		}
+	}
+	walk(root)
+	return ambiguities
+}
+
+func ambiguity(node Node, symbol grammar.Symbol, alternatives []Group) Ambiguity {
+	a := Ambiguity{
+		Node:     node,
+		Symbol:   symbol,
+		Origin:   node.Origin(),
+		Location: node.Location(),
+	}
+	for _, alternative := range alternatives {
+		a.Productions = append(a.Productions, Production(node, alternative))
+	}
+	return a
+}
+
+// Production returns the production the alternative of a Symbol or Intermediate node derives
+// the production is rebuilt from the children when the parser did not record it, like for alternatives expanded from leo paths
+func Production(node Node, alternative Group) *grammar.Production {
+	if intermediate, ok := node.(*Intermediate); ok {
+		return intermediate.Rule.Production
+	}
+	if g, ok := alternative.(*group); ok && g.production != nil {
+		return g.production
+	}
+	children := alternative.Children()
+	if len(children) > 0 {
+		if intermediate, ok := children[0].(*Intermediate); ok {
+			return intermediate.Rule.Production
+		}
+	}
+	lhs, _ := node.(*Symbol).Symbol.(grammar.NonTerminal)
+	var rhs []grammar.Symbol
+	for _, child := range children {
+		rhs = append(rhs, symbolOf(child))
+	}
+	return grammar.NewProduction(lhs, rhs...)
+}
+
+func symbolOf(node Node) grammar.Symbol {
+	switch n := node.(type) {
+	case *Symbol:
+		return n.Symbol
+	case *Intermediate:
+		return n.Rule.Production.LeftHandSide
+	case *Token:
+		tok := n.Token
+		if capture, ok := tok.(*token.Capture); ok {
+			tok = capture.Token
+		}
+		if lexeme, ok := tok.(interface{ LexerRule() grammar.LexerRule }); ok {
+			return lexeme.LexerRule()
+		}
+		return grammar.NewStringLexerRule(tok.TokenType())
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package forest_test
+
+import (
+	"testing"
+
+	"github.com/patrickhuber/go-earley/forest"
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmbiguities(t *testing.T) {
+	E := grammar.NewNonTerminal("E")
+	plus := grammar.NewStringLexerRule("+")
+	a := grammar.NewStringLexerRule("a")
+
+	// E -> E '+' E | 'a'
+	g := grammar.New(E,
+		grammar.NewProduction(E, E, plus, E),
+		grammar.NewProduction(E, a),
+	)
+
+	t.Run("lists ambiguous nodes", func(t *testing.T) {
+		root := parse(t, parser.New(g), a, plus, a, plus, a)
+
+		ambiguities := forest.Ambiguities(root)
+		require.Len(t, ambiguities, 1)
+		require.Equal(t, root, ambiguities[0].Node)
+		require.Equal(t, E, ambiguities[0].Symbol)
+		require.Equal(t, 0, ambiguities[0].Origin)
+		require.Equal(t, 5, ambiguities[0].Location)
+		require.Equal(t, "E(0, 5): E -> E + E | E -> E + E", ambiguities[0].String())
+	})
+	t.Run("rebuilds short productions", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		A := grammar.NewNonTerminal("A")
+		B := grammar.NewNonTerminal("B")
+		// S -> A | B, A -> 'a', B -> 'a'
+		g := grammar.New(S,
+			grammar.NewProduction(S, A),
+			grammar.NewProduction(S, B),
+			grammar.NewProduction(A, a),
+			grammar.NewProduction(B, a),
+		)
+		root := parse(t, parser.New(g), a)
+
+		ambiguities := forest.Ambiguities(root)
+		require.Len(t, ambiguities, 1)
+		require.ElementsMatch(t, []string{"S -> A", "S -> B"}, []string{
+			ambiguities[0].Productions[0].String(),
+			ambiguities[0].Productions[1].String(),
+		})
+	})
+	t.Run("unambiguous", func(t *testing.T) {
+		root := parse(t, parser.New(g), a, plus, a)
+		require.Empty(t, forest.Ambiguities(root))
+	})
+}
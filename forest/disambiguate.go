@@ -0,0 +1,307 @@
+package forest
+
+import (
+	"github.com/patrickhuber/go-earley/grammar"
+)
+
+// Disambiguate returns the forest without the derivations that the disambiguation metadata of the grammar productions removes
+// Priority and associativity restrict the productions that derive the first and last child of a production.
+// Prefer and Avoid choose between the alternatives of a node, Reject and NotFollowedBy remove nodes.
+// NotFollowedBy uses the lookahead, the token types of the tokens the parser saw at each location.
+// Nodes that change are copied, so the original forest is left as it is.
+// false is returned if every derivation of the root is removed.
+func Disambiguate(g *grammar.Grammar, root Node, lookahead map[int][]string) (Node, bool) {
+	d := &disambiguator{
+		productions:   map[grammar.NonTerminal][]*grammar.Production{},
+		symbols:       map[key]result{},
+		intermediates: map[*Intermediate]result{},
+		active:        map[Node]struct{}{},
+		lookahead:     lookahead,
+	}
+	for _, p := range g.Productions {
+		d.productions[p.LeftHandSide] = append(d.productions[p.LeftHandSide], p)
+	}
+	return d.node(root, context{})
+}
+
+type disambiguator struct {
+	productions   map[grammar.NonTerminal][]*grammar.Production
+	symbols       map[key]result
+	intermediates map[*Intermediate]result
+	// active holds the nodes being filtered so cycles are kept as they are
+	active map[Node]struct{}
+	// lookahead holds the token types of the tokens that start at each location
+	lookahead map[int][]string
+}
+
+// context is the production and the outer positions of the child being filtered
+type context struct {
+	parent *grammar.Production
+	first  bool
+	last   bool
+}
+
+type key struct {
+	symbol  *Symbol
+	context context
+}
+
+type result struct {
+	node Node
+	ok   bool
+}
+
+func (d *disambiguator) node(node Node, ctx context) (Node, bool) {
+	switch n := node.(type) {
+	case *Symbol:
+		return d.symbol(n, ctx)
+	case *Intermediate:
+		return d.intermediate(n)
+	}
+	return node, true
+}
+
+func (d *disambiguator) symbol(s *Symbol, ctx context) (Node, bool) {
+	k := key{symbol: s, context: ctx}
+	if r, ok := d.symbols[k]; ok {
+		return r.node, r.ok
+	}
+	if _, ok := d.active[s]; ok {
+		return s, true
+	}
+	d.active[s] = struct{}{}
+	defer delete(d.active, s)
+
+	alternatives := s.Alternatives()
+	productions := make([]*grammar.Production, len(alternatives))
+	for i, alternative := range alternatives {
+		productions[i] = d.production(s, alternative)
+		if productions[i] != nil && productions[i].Reject {
+			d.symbols[k] = result{}
+			return nil, false
+		}
+	}
+
+	changed := false
+	var groups []Group
+	var preferences []grammar.Preference
+	for i, alternative := range alternatives {
+		production := productions[i]
+		if production != nil && (!allowed(ctx, production) || !d.followed(s, production)) {
+			changed = true
+			continue
+		}
+		size := len(alternative.Children())
+		if production != nil {
+			size = len(production.RightHandSide)
+		}
+		group, ok := d.group(production, alternative, size)
+		if !ok {
+			changed = true
+			continue
+		}
+		changed = changed || group != alternative
+		groups = append(groups, group)
+		preference := grammar.NoPreference
+		if production != nil {
+			preference = production.Preference
+		}
+		preferences = append(preferences, preference)
+	}
+
+	if preferred, ok := prefer(groups, preferences); ok {
+		changed = true
+		groups = preferred
+	}
+
+	r := result{node: s, ok: true}
+	if len(alternatives) > 0 && len(groups) == 0 {
+		r = result{}
+	} else if changed {
+		r.node = NewSymbol(s.Symbol, s.origin, s.location, groups...)
+	}
+	d.symbols[k] = r
+	return r.node, r.ok
+}
+
+func (d *disambiguator) intermediate(i *Intermediate) (Node, bool) {
+	if r, ok := d.intermediates[i]; ok {
+		return r.node, r.ok
+	}
+	if _, ok := d.active[i]; ok {
+		return i, true
+	}
+	d.active[i] = struct{}{}
+	defer delete(d.active, i)
+
+	alternatives := i.Alternatives()
+	changed := false
+	var groups []Group
+	for _, alternative := range alternatives {
+		group, ok := d.group(i.Rule.Production, alternative, i.Rule.Position)
+		if !ok {
+			changed = true
+			continue
+		}
+		changed = changed || group != alternative
+		groups = append(groups, group)
+	}
+
+	r := result{node: i, ok: true}
+	if len(alternatives) > 0 && len(groups) == 0 {
+		r = result{}
+	} else if changed {
+		r.node = NewIntermediate(i.Rule, i.origin, i.location, groups...)
+	}
+	d.intermediates[i] = r
+	return r.node, r.ok
+}
+
+// group filters the children of the alternative, the children derive the first size symbols of the production
+func (d *disambiguator) group(production *grammar.Production, alternative Group, size int) (Group, bool) {
+	children := alternative.Children()
+	filtered := make([]Node, len(children))
+	changed := false
+	for i, child := range children {
+		var node Node
+		var ok bool
+		if intermediate, isIntermediate := child.(*Intermediate); isIntermediate {
+			node, ok = d.intermediate(intermediate)
+		} else {
+			node, ok = d.node(child, childContext(production, size-len(children)+i))
+		}
+		if !ok {
+			return nil, false
+		}
+		changed = changed || node != child
+		filtered[i] = node
+	}
+	if !changed {
+		return alternative, true
+	}
+	filteredGroup := &group{children: filtered}
+	if g, ok := alternative.(*group); ok {
+		filteredGroup.production = g.production
+	}
+	return filteredGroup, true
+}
+
+// production returns the grammar production of the alternative or nil if it can not be found
+func (d *disambiguator) production(s *Symbol, alternative Group) *grammar.Production {
+	if g, ok := alternative.(*group); ok && g.production != nil {
+		return g.production
+	}
+	children := alternative.Children()
+	if len(children) > 0 {
+		if intermediate, ok := children[0].(*Intermediate); ok {
+			return intermediate.Rule.Production
+		}
+	}
+	lhs, ok := s.Symbol.(grammar.NonTerminal)
+	if !ok {
+		return nil
+	}
+	for _, production := range d.productions[lhs] {
+		if derives(production, children) {
+			return production
+		}
+	}
+	return nil
+}
+
+func derives(production *grammar.Production, children []Node) bool {
+	if len(production.RightHandSide) != len(children) {
+		return false
+	}
+	for i, child := range children {
+		symbol := production.RightHandSide[i]
+		switch n := child.(type) {
+		case *Symbol:
+			if n.Symbol != symbol {
+				return false
+			}
+		case *Token:
+			lexerRule, ok := symbol.(grammar.LexerRule)
+			if !ok || lexerRule.TokenType() != n.Token.TokenType() {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// followed returns false if the symbol is followed by a token the production does not allow
+func (d *disambiguator) followed(s *Symbol, production *grammar.Production) bool {
+	for _, lexerRule := range production.NotFollowedBy {
+		for _, tokenType := range d.lookahead[s.location] {
+			if lexerRule.TokenType() == tokenType {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func childContext(production *grammar.Production, position int) context {
+	if production == nil {
+		return context{}
+	}
+	first := position == 0
+	last := position == len(production.RightHandSide)-1
+	if !first && !last {
+		return context{}
+	}
+	return context{
+		parent: production,
+		first:  first,
+		last:   last,
+	}
+}
+
+// allowed returns false if priority or associativity forbids the production in the context
+func allowed(ctx context, production *grammar.Production) bool {
+	parent := ctx.parent
+	if parent == nil {
+		return true
+	}
+	if parent.Priority > 0 && production.Priority > 0 && production.Priority < parent.Priority {
+		return false
+	}
+	same := production == parent || (parent.Priority > 0 && production.Priority == parent.Priority)
+	if !same {
+		return true
+	}
+	switch parent.Associativity {
+	case grammar.LeftAssociative:
+		return !ctx.last
+	case grammar.RightAssociative:
+		return !ctx.first
+	case grammar.NonAssociative:
+		return !ctx.first && !ctx.last
+	}
+	return true
+}
+
+// prefer returns the groups that remain after Prefer and Avoid apply, false is returned if nothing changes
+func prefer(groups []Group, preferences []grammar.Preference) ([]Group, bool) {
+	var preferred, avoided, other []Group
+	for i, group := range groups {
+		switch preferences[i] {
+		case grammar.Prefer:
+			preferred = append(preferred, group)
+		case grammar.Avoid:
+			avoided = append(avoided, group)
+		default:
+			other = append(other, group)
+		}
+	}
+	if len(preferred) > 0 && len(preferred) < len(groups) {
+		return preferred, true
+	}
+	if len(avoided) > 0 && len(avoided) < len(groups) {
+		return append(preferred, other...), true
+	}
+	return nil, false
+}
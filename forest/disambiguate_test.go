@@ -0,0 +1,167 @@
+package forest_test
+
+import (
+	"testing"
+
+	"github.com/patrickhuber/go-earley/forest"
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/parser"
+	"github.com/patrickhuber/go-earley/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisambiguate(t *testing.T) {
+	E := grammar.NewNonTerminal("E")
+	plus := grammar.NewStringLexerRule("+")
+	times := grammar.NewStringLexerRule("*")
+	power := grammar.NewStringLexerRule("^")
+	equal := grammar.NewStringLexerRule("=")
+	a := grammar.NewStringLexerRule("a")
+
+	sum := grammar.NewProduction(E, E, plus, E)
+	sum.Priority = 1
+	sum.Associativity = grammar.LeftAssociative
+	product := grammar.NewProduction(E, E, times, E)
+	product.Priority = 2
+	product.Associativity = grammar.LeftAssociative
+	exponent := grammar.NewProduction(E, E, power, E)
+	exponent.Priority = 3
+	exponent.Associativity = grammar.RightAssociative
+	equality := grammar.NewProduction(E, E, equal, E)
+	equality.Associativity = grammar.NonAssociative
+
+	g := grammar.New(E, sum, product, exponent, equality, grammar.NewProduction(E, a))
+
+	tests := []struct {
+		name  string
+		input []*grammar.StringLexerRule
+		tree  string
+	}{
+		{"priority", []*grammar.StringLexerRule{a, plus, a, times, a}, "(E (E a) + (E (E a) * (E a)))"},
+		{"priority left", []*grammar.StringLexerRule{a, times, a, plus, a}, "(E (E (E a) * (E a)) + (E a))"},
+		{"left associative", []*grammar.StringLexerRule{a, plus, a, plus, a}, "(E (E (E a) + (E a)) + (E a))"},
+		{"right associative", []*grammar.StringLexerRule{a, power, a, power, a}, "(E (E a) ^ (E (E a) ^ (E a)))"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := parse(t, parser.New(g), test.input...)
+			require.Equal(t, []string{test.tree}, trees(root))
+		})
+	}
+	t.Run("non associative", func(t *testing.T) {
+		p := parser.New(g)
+		for i, rule := range []*grammar.StringLexerRule{a, equal, a, equal, a} {
+			ok, err := p.Pulse(token.NewString(rule, i))
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+		require.False(t, p.Accepted())
+		_, ok := p.GetForestRoot()
+		require.False(t, ok)
+	})
+	t.Run("prefer and avoid", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		A := grammar.NewNonTerminal("A")
+		B := grammar.NewNonTerminal("B")
+		C := grammar.NewNonTerminal("C")
+		sa := grammar.NewProduction(S, A)
+		sb := grammar.NewProduction(S, B)
+		sb.Preference = grammar.Avoid
+		sc := grammar.NewProduction(S, C)
+		g := grammar.New(S, sa, sb, sc,
+			grammar.NewProduction(A, a),
+			grammar.NewProduction(B, a),
+			grammar.NewProduction(C, a),
+		)
+
+		root := parse(t, parser.New(g), a)
+		require.Equal(t, int64(2), forest.CountTrees(root).Int64())
+
+		sa.Preference = grammar.Prefer
+		root = parse(t, parser.New(g), a)
+		require.Equal(t, []string{"(S (A a))"}, trees(root))
+	})
+	t.Run("reject", func(t *testing.T) {
+		Id := grammar.NewNonTerminal("Id")
+		word := grammar.NewStringLexerRule("word")
+		keyword := grammar.NewStringLexerRule("if")
+		reject := grammar.NewProduction(Id, keyword)
+		reject.Reject = true
+		g := grammar.New(Id, grammar.NewProduction(Id, word), reject)
+
+		p := parser.New(g)
+		ok, err := p.Pulse(
+			token.NewString(word, 0),
+			token.NewString(keyword, 0))
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.False(t, p.Accepted())
+
+		parse(t, parser.New(g), word)
+	})
+	t.Run("not followed by", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		A := grammar.NewNonTerminal("A")
+		single := grammar.NewProduction(A, a)
+		single.NotFollowedBy = []grammar.LexerRule{a}
+		g := grammar.New(S,
+			grammar.NewProduction(S, A, S),
+			grammar.NewProduction(S, A),
+			single,
+			grammar.NewProduction(A, a, a),
+		)
+
+		root := parse(t, parser.New(g), a, a, a)
+
+		require.Equal(t, []string{"(S (A a a) (S (A a)))"}, trees(root))
+	})
+	t.Run("not followed by token after the root", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		b := grammar.NewStringLexerRule("b")
+		single := grammar.NewProduction(S, a)
+		single.NotFollowedBy = []grammar.LexerRule{b}
+		g := grammar.New(S, single)
+
+		p := parser.New(g)
+		ok, err := p.Pulse(token.NewString(a, 0))
+		require.NoError(t, err)
+		require.True(t, ok)
+		_, ok = p.GetForestRoot()
+		require.True(t, ok)
+
+		// no derivation consumes the token but it follows the root
+		ok, err = p.Pulse(token.NewString(b, 1))
+		require.Error(t, err)
+		require.False(t, ok)
+		_, ok = p.GetForestRoot()
+		require.False(t, ok)
+	})
+	t.Run("not followed by token from pruned derivations", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		A := grammar.NewNonTerminal("A")
+		id := grammar.NewStringLexerRule("id")
+		keyword := grammar.NewStringLexerRule("kw")
+		single := grammar.NewProduction(A, a)
+		single.NotFollowedBy = []grammar.LexerRule{keyword}
+		g := grammar.New(S,
+			grammar.NewProduction(S, A, id),
+			grammar.NewProduction(S, A, keyword, keyword),
+			single,
+		)
+
+		root := parse(t, parser.New(g), a, id)
+		require.Equal(t, []string{"(S (A a) id)"}, trees(root))
+
+		// the keyword scans but its derivation never completes, so the forest has no keyword token
+		p := parser.New(g)
+		ok, err := p.Pulse(token.NewString(a, 0))
+		require.NoError(t, err)
+		require.True(t, ok)
+		ok, err = p.Pulse(token.NewString(id, 1), token.NewString(keyword, 1))
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.False(t, p.Accepted())
+		_, ok = p.GetForestRoot()
+		require.False(t, ok)
+	})
+}
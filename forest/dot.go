@@ -0,0 +1,72 @@
+package forest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// WriteDOT writes the forest in graphviz dot format
+// Symbol nodes are ellipses, intermediate nodes are boxes, tokens are plain text and packed nodes are points.
+// Each alternative of a node is a packed node, so an ambiguous node has more than one packed node.
+// Nodes shared by several parents are written once.
+func WriteDOT(w io.Writer, root Node) error {
+	writer := bufio.NewWriter(w)
+	d := &dot{
+		writer: writer,
+		ids:    map[Node]int{},
+	}
+	fmt.Fprintln(writer, "digraph forest {")
+	d.node(root)
+	fmt.Fprintln(writer, "}")
+	return writer.Flush()
+}
+
+type dot struct {
+	writer *bufio.Writer
+	ids    map[Node]int
+	packed int
+}
+
+// node writes the node and its descendants that are not written yet and returns the node id
+func (d *dot) node(node Node) string {
+	if id, ok := d.ids[node]; ok {
+		return vertex(id)
+	}
+	id := len(d.ids)
+	d.ids[node] = id
+	name := vertex(id)
+
+	switch n := node.(type) {
+	case *Symbol:
+		fmt.Fprintf(d.writer, "\t%s [label=%s shape=ellipse]\n", name, label(n.Symbol.String(), n))
+	case *Intermediate:
+		fmt.Fprintf(d.writer, "\t%s [label=%s shape=box]\n", name, label(n.Rule.String(), n))
+	case *Token:
+		fmt.Fprintf(d.writer, "\t%s [label=%s shape=plaintext]\n", name, label(n.Token.TokenType(), n))
+	}
+
+	internal, ok := node.(Internal)
+	if !ok {
+		return name
+	}
+	for _, alternative := range internal.Alternatives() {
+		packed := fmt.Sprintf("p%d", d.packed)
+		d.packed++
+		fmt.Fprintf(d.writer, "\t%s [label=\"\" shape=point]\n", packed)
+		fmt.Fprintf(d.writer, "\t%s -> %s\n", name, packed)
+		for _, child := range alternative.Children() {
+			fmt.Fprintf(d.writer, "\t%s -> %s\n", packed, d.node(child))
+		}
+	}
+	return name
+}
+
+func vertex(id int) string {
+	return fmt.Sprintf("n%d", id)
+}
+
+func label(text string, node Node) string {
+	return strconv.Quote(fmt.Sprintf("%s, %d, %d", text, node.Origin(), node.Location()))
+}
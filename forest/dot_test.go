@@ -0,0 +1,51 @@
+package forest_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/patrickhuber/go-earley/forest"
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteDOT(t *testing.T) {
+	S := grammar.NewNonTerminal("S")
+	A := grammar.NewNonTerminal("A")
+	B := grammar.NewNonTerminal("B")
+	a := grammar.NewStringLexerRule("a")
+	// S -> A | B, A -> 'a', B -> 'a'
+	g := grammar.New(S,
+		grammar.NewProduction(S, A),
+		grammar.NewProduction(S, B),
+		grammar.NewProduction(A, a),
+		grammar.NewProduction(B, a),
+	)
+	root := parse(t, parser.New(g), a)
+
+	var buf bytes.Buffer
+	require.NoError(t, forest.WriteDOT(&buf, root))
+
+	// the token is shared by A and B so it is written once
+	expected := `digraph forest {
+	n0 [label="S, 0, 1" shape=ellipse]
+	p0 [label="" shape=point]
+	n0 -> p0
+	n1 [label="A, 0, 1" shape=ellipse]
+	p1 [label="" shape=point]
+	n1 -> p1
+	n2 [label="a, 0, 1" shape=plaintext]
+	p1 -> n2
+	p0 -> n1
+	p2 [label="" shape=point]
+	n0 -> p2
+	n3 [label="B, 0, 1" shape=ellipse]
+	p3 [label="" shape=point]
+	n3 -> p3
+	p3 -> n2
+	p2 -> n3
+}
+`
+	require.Equal(t, expected, buf.String())
+}
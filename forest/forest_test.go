@@ -0,0 +1,33 @@
+package forest_test
+
+import (
+	"testing"
+
+	"github.com/patrickhuber/go-earley/forest"
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/parser"
+	"github.com/patrickhuber/go-earley/token"
+	"github.com/stretchr/testify/require"
+)
+
+// parse pulses a token for each lexer rule and returns the forest root of the accepted input
+func parse(t *testing.T, p parser.Parser, input ...*grammar.StringLexerRule) forest.Node {
+	for i, rule := range input {
+		ok, err := p.Pulse(token.NewString(rule, i))
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+	root, ok := p.GetForestRoot()
+	require.True(t, ok)
+	return root
+}
+
+// trees returns the string of each tree of the forest
+func trees(root forest.Node) []string {
+	var trees []string
+	it := forest.Trees(root)
+	for it.Next() {
+		trees = append(trees, it.Tree().String())
+	}
+	return trees
+}
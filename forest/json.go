@@ -0,0 +1,137 @@
+package forest
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/patrickhuber/go-earley/token"
+)
+
+// JSONVersion is the version of the json forest format written by MarshalJSON
+const JSONVersion = 1
+
+// JSONForest is the json format of a forest
+// Nodes are listed in depth first order from the root, so the root is the first node and the ids are stable for the same forest.
+type JSONForest struct {
+	Version int        `json:"version"`
+	Root    int        `json:"root"`
+	Nodes   []JSONNode `json:"nodes"`
+}
+
+// JSONNode is a symbol, intermediate or token node, children refer to nodes by id
+type JSONNode struct {
+	ID       int    `json:"id"`
+	Kind     string `json:"kind"`
+	Origin   int    `json:"origin"`
+	Location int    `json:"location"`
+	// Symbol is the symbol of a symbol node
+	Symbol string `json:"symbol,omitempty"`
+	// Rule is the dotted rule of an intermediate node
+	Rule string `json:"rule,omitempty"`
+	// TokenType, Text and Span describe a token node
+	TokenType    string            `json:"tokenType,omitempty"`
+	Text         string            `json:"text,omitempty"`
+	Span         *JSONSpan         `json:"span,omitempty"`
+	Alternatives []JSONAlternative `json:"alternatives,omitempty"`
+}
+
+// JSONSpan is the offset and length of the text of a token in the input
+type JSONSpan struct {
+	Offset int `json:"offset"`
+	Length int `json:"length"`
+}
+
+// JSONAlternative is a packed node with the production it derives and the ids of its children
+type JSONAlternative struct {
+	Production string `json:"production,omitempty"`
+	Children   []int  `json:"children"`
+}
+
+const (
+	SymbolKind       = "symbol"
+	IntermediateKind = "intermediate"
+	TokenKind        = "token"
+)
+
+// MarshalJSON returns the forest in the JSONForest format
+func MarshalJSON(root Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	// productions contain '->', keep them readable
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(NewJSONForest(root)); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// NewJSONForest converts the forest into the JSONForest format
+func NewJSONForest(root Node) *JSONForest {
+	j := &jsonWriter{
+		ids: map[Node]int{},
+	}
+	j.node(root)
+	return &JSONForest{
+		Version: JSONVersion,
+		Root:    0,
+		Nodes:   j.nodes,
+	}
+}
+
+type jsonWriter struct {
+	ids   map[Node]int
+	nodes []JSONNode
+}
+
+func (j *jsonWriter) node(node Node) int {
+	if id, ok := j.ids[node]; ok {
+		return id
+	}
+	id := len(j.nodes)
+	j.ids[node] = id
+	j.nodes = append(j.nodes, JSONNode{
+		ID:       id,
+		Origin:   node.Origin(),
+		Location: node.Location(),
+	})
+
+	var alternatives []JSONAlternative
+	switch n := node.(type) {
+	case *Symbol:
+		j.nodes[id].Kind = SymbolKind
+		j.nodes[id].Symbol = n.Symbol.String()
+		for _, alternative := range n.Alternatives() {
+			alternatives = append(alternatives, JSONAlternative{
+				Production: Production(n, alternative).String(),
+				Children:   j.children(alternative),
+			})
+		}
+	case *Intermediate:
+		j.nodes[id].Kind = IntermediateKind
+		j.nodes[id].Rule = n.Rule.String()
+		for _, alternative := range n.Alternatives() {
+			alternatives = append(alternatives, JSONAlternative{
+				Children: j.children(alternative),
+			})
+		}
+	case *Token:
+		j.nodes[id].Kind = TokenKind
+		j.nodes[id].TokenType = n.Token.TokenType()
+		if _, ok := n.Token.(*token.Capture); ok {
+			span := n.Span()
+			j.nodes[id].Text = n.Text()
+			j.nodes[id].Span = &JSONSpan{Offset: span.Offset, Length: span.Length}
+		}
+	}
+	// children are appended after the node, so the node is updated by index
+	j.nodes[id].Alternatives = alternatives
+	return id
+}
+
+func (j *jsonWriter) children(alternative Group) []int {
+	var children []int
+	for _, child := range alternative.Children() {
+		children = append(children, j.node(child))
+	}
+	return children
+}
@@ -0,0 +1,69 @@
+package forest_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/patrickhuber/go-earley/forest"
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	S := grammar.NewNonTerminal("S")
+	A := grammar.NewNonTerminal("A")
+	B := grammar.NewNonTerminal("B")
+	a := grammar.NewStringLexerRule("a")
+	// S -> A | B, A -> 'a', B -> 'a'
+	g := grammar.New(S,
+		grammar.NewProduction(S, A),
+		grammar.NewProduction(S, B),
+		grammar.NewProduction(A, a),
+		grammar.NewProduction(B, a),
+	)
+	root := parse(t, parser.New(g), a)
+
+	data, err := forest.MarshalJSON(root)
+	require.NoError(t, err)
+
+	var f forest.JSONForest
+	require.NoError(t, json.Unmarshal(data, &f))
+	require.Equal(t, forest.JSONVersion, f.Version)
+	require.Equal(t, 0, f.Root)
+	require.Equal(t, []forest.JSONNode{
+		{ID: 0, Kind: forest.SymbolKind, Symbol: "S", Origin: 0, Location: 1, Alternatives: []forest.JSONAlternative{
+			{Production: "S -> A", Children: []int{1}},
+			{Production: "S -> B", Children: []int{3}},
+		}},
+		{ID: 1, Kind: forest.SymbolKind, Symbol: "A", Origin: 0, Location: 1, Alternatives: []forest.JSONAlternative{
+			{Production: "A -> a", Children: []int{2}},
+		}},
+		{ID: 2, Kind: forest.TokenKind, TokenType: "a", Origin: 0, Location: 1},
+		{ID: 3, Kind: forest.SymbolKind, Symbol: "B", Origin: 0, Location: 1, Alternatives: []forest.JSONAlternative{
+			{Production: "B -> a", Children: []int{2}},
+		}},
+	}, f.Nodes)
+
+	t.Run("intermediate", func(t *testing.T) {
+		E := grammar.NewNonTerminal("E")
+		plus := grammar.NewStringLexerRule("+")
+		// E -> E '+' E | 'a'
+		g := grammar.New(E,
+			grammar.NewProduction(E, E, plus, E),
+			grammar.NewProduction(E, a),
+		)
+		root := parse(t, parser.New(g), a, plus, a)
+
+		data, err := forest.MarshalJSON(root)
+		require.NoError(t, err)
+		var f forest.JSONForest
+		require.NoError(t, json.Unmarshal(data, &f))
+		require.Equal(t, "E -> E + E", f.Nodes[0].Alternatives[0].Production)
+
+		intermediate := f.Nodes[f.Nodes[0].Alternatives[0].Children[0]]
+		require.Equal(t, forest.IntermediateKind, intermediate.Kind)
+		require.Equal(t, "E -> E +•E", intermediate.Rule)
+		require.Empty(t, intermediate.Alternatives[0].Production)
+	})
+}
@@ -1,5 +1,7 @@
 package forest
 
+import "github.com/patrickhuber/go-earley/grammar"
+
 type Node interface {
 	node()
 	Origin() int
@@ -12,6 +14,8 @@ type Group interface {
 
 type group struct {
 	children []Node
+	// production is the production that derives the children of a symbol node if it is known
+	production *grammar.Production
 }
 
 func NewGroup(children ...Node) Group {
@@ -34,7 +38,15 @@ type Internal interface {
 }
 
 func (i *internal) AddUniqueFamily(w, v Node) {
-	childCount := 1
+	i.addUniqueFamily(nil, w, v)
+}
+
+// addUniqueFamily adds the family of children, a family without children records the derivation of an empty production
+func (i *internal) addUniqueFamily(production *grammar.Production, w, v Node) {
+	childCount := 0
+	if w != nil {
+		childCount += 1
+	}
 	if v != nil {
 		childCount += 1
 	}
@@ -43,19 +55,33 @@ func (i *internal) AddUniqueFamily(w, v Node) {
 		if len(group.Children()) != childCount {
 			continue
 		}
-		if i.isMatchedSubtree(w, v, group) {
+		// the same children derived by different productions are different derivations
+		if productionOf(group) != production {
+			continue
+		}
+		if childCount == 0 || i.isMatchedSubtree(w, v, group) {
 			return
 		}
 	}
 
-	group := &group{}
-	group.children = append(group.children, w)
-	if childCount > 1 {
+	group := &group{production: production}
+	if w != nil {
+		group.children = append(group.children, w)
+	}
+	if v != nil {
 		group.children = append(group.children, v)
 	}
 	i.alternatives = append(i.alternatives, group)
 }
 
+// productionOf returns the production recorded for the group or nil
+func productionOf(alternative Group) *grammar.Production {
+	if g, ok := alternative.(*group); ok {
+		return g.production
+	}
+	return nil
+}
+
 func (i *internal) isMatchedSubtree(first, second Node, group Group) bool {
 
 	firstCompare := group.Children()[0]
@@ -11,13 +11,45 @@ type Symbol struct {
 	internal *internal
 	origin   int
 	location int
-	expanded bool
-	paths    map[Path]Node
+	paths    []leoPath
+	// expanded is the number of paths added to the alternatives
+	expanded int
+	// levels holds the symbol nodes created for the transitions of the paths
+	levels map[level]*Symbol
+	// intermediates holds the intermediate nodes created for the nulling suffixes of the transitions
+	intermediates map[step]*Intermediate
 }
 
+// Path is a leo transition item, the parent is the transition of the right recursive rule that contains this one
 type Path interface {
-	Next() Path
+	Parent() Path
+	// Node is the node of the children before the right recursive symbol, it is nil if there are none
 	Node() Node
+	// Production is the right recursive production
+	Production() *grammar.Production
+	// Rules are the dotted rules from the one after the right recursive symbol to the complete rule
+	// There is more than one if the production ends in nulling symbols.
+	Rules() []*grammar.DottedRule
+}
+
+// level is the symbol and origin of a symbol node created for a transition
+type level struct {
+	symbol grammar.Symbol
+	origin int
+}
+
+// step is the dotted rule and origin of an intermediate node created for a transition
+type step struct {
+	rule   *grammar.DottedRule
+	origin int
+}
+
+// leoPath is a completed node and the transition that leads from it to the top most item
+// nulls holds the node of each nulling symbol at the location of the completed node
+type leoPath struct {
+	path  Path
+	node  Node
+	nulls map[grammar.Symbol]Node
 }
 
 func NewSymbol(sym grammar.Symbol, origin int, location int, alternatives ...Group) *Symbol {
@@ -35,48 +67,104 @@ func (Symbol) node()           {}
 func (s Symbol) Origin() int   { return s.origin }
 func (s Symbol) Location() int { return s.location }
 func (s *Symbol) Alternatives() []Group {
-	if !s.expanded {
+	if s.expanded < len(s.paths) {
 		s.expand()
-		s.expanded = true
 	}
 	return s.internal.alternatives
 }
 
+// expand adds the derivations the leo paths skipped, the paths are expanded in the order they were added
+// Each transition of a path is a symbol node from the origin of its rule to the location of this node.
+// Symbol and intermediate nodes shared by paths are created once.
 func (s *Symbol) expand() {
-	for path, node := range s.paths {
-		leftTree := path.Node()
-		rightSubTree := node
-		next := path.Next()
-
-		if next == nil || next.Node() == nil || next.Node().Location() == rightSubTree.Location() {
-			s.internal.alternatives = append(s.internal.alternatives, group{
-				children: []Node{
-					leftTree,
-					rightSubTree,
-				},
-			})
-			return
+	if s.levels == nil {
+		s.levels = map[level]*Symbol{}
+		s.intermediates = map[step]*Intermediate{}
+	}
+	for _, p := range s.paths[s.expanded:] {
+		var transitions []Path
+		for path := p.path; path != nil; path = path.Parent() {
+			transitions = append(transitions, path)
+		}
+
+		right := p.node
+		for i, transition := range transitions {
+			production := transition.Production()
+			left := transition.Node()
+			origin := right.Origin()
+			if left != nil {
+				origin = left.Origin()
+			}
+
+			parent := s
+			if i < len(transitions)-1 {
+				l := level{symbol: production.LeftHandSide, origin: origin}
+				var ok bool
+				parent, ok = s.levels[l]
+				if !ok {
+					parent = NewSymbol(production.LeftHandSide, origin, s.location)
+					s.levels[l] = parent
+				}
+			}
+
+			// the nulling suffix adds one intermediate node per symbol, the rule at position one is its only child
+			w, v := left, right
+			if left == nil {
+				w, v = right, nil
+			}
+			rules := transition.Rules()
+			var node Node
+			for r, rule := range rules {
+				if r > 0 {
+					sym, _ := rules[r-1].PostDotSymbol().Deconstruct()
+					w, v = node, p.nulls[sym]
+				}
+				if rule.Complete() {
+					parent.internal.addUniqueFamily(production, w, v)
+					break
+				}
+				if rule.Position == 1 {
+					node = w
+					continue
+				}
+				node = s.intermediate(rule, origin)
+				node.(*Intermediate).AddUniqueFamily(w, v)
+			}
+			right = parent
 		}
-		rightTree := NewSymbol(s.Symbol, next.Node().Origin(), s.location)
-		rightTree.AddPath(next, node)
-		s.internal.alternatives = append(s.internal.alternatives, &group{
-			children: []Node{
-				leftTree,
-				rightTree,
-			},
-		})
 	}
+	s.expanded = len(s.paths)
+}
+
+func (s *Symbol) intermediate(rule *grammar.DottedRule, origin int) *Intermediate {
+	key := step{rule: rule, origin: origin}
+	intermediate, ok := s.intermediates[key]
+	if !ok {
+		intermediate = NewIntermediate(rule, origin, s.location)
+		s.intermediates[key] = intermediate
+	}
+	return intermediate
 }
 
 func (s *Symbol) AddUniqueFamily(w, v Node) {
 	s.internal.AddUniqueFamily(w, v)
 }
 
-func (s *Symbol) AddPath(path Path, node Node) {
-	if s.paths == nil {
-		s.paths = make(map[Path]Node)
+// AddUniqueDerivation adds the family of children derived by the production if the family does not exist
+// w and v are nil for an empty production, the family then has no children.
+func (s *Symbol) AddUniqueDerivation(production *grammar.Production, w, v Node) {
+	s.internal.addUniqueFamily(production, w, v)
+}
+
+// AddPath adds the derivations from the completed node to this node through the leo path
+// nulls holds the node of each nulling symbol that ends a rule of the path.
+func (s *Symbol) AddPath(path Path, node Node, nulls map[grammar.Symbol]Node) {
+	for _, p := range s.paths {
+		if p.path == path && p.node == node {
+			return
+		}
 	}
-	s.paths[path] = node
+	s.paths = append(s.paths, leoPath{path: path, node: node, nulls: nulls})
 }
 
 func (s Symbol) String() string {
@@ -88,7 +176,7 @@ func (s *Symbol) Accept(v Visitor) {
 		// already visited
 		return
 	}
-	for _, alt := range s.internal.alternatives {
+	for _, alt := range s.Alternatives() {
 		for _, child := range alt.Children() {
 			if acceptor, ok := child.(Acceptor); ok {
 				acceptor.Accept(v)
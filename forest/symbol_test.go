@@ -0,0 +1,192 @@
+package forest_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/patrickhuber/go-earley/forest"
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/parser"
+	"github.com/patrickhuber/go-earley/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeoForest(t *testing.T) {
+	a := grammar.NewStringLexerRule("a")
+	b := grammar.NewStringLexerRule("b")
+
+	// forests parses the input without and with leo items
+	forests := func(t *testing.T, g *grammar.Grammar, input ...*grammar.StringLexerRule) (forest.Node, forest.Node) {
+		earley := parse(t, parser.New(g, parser.OptimizeRightRecursion(false)), input...)
+		leo := parse(t, parser.New(g, parser.OptimizeRightRecursion(true)), input...)
+		return earley, leo
+	}
+
+	// identical compares the nodes, the productions of every alternative and the shared nodes of the forests
+	identical := func(t *testing.T, expected, actual forest.Node) {
+		expectedJSON, err := forest.MarshalJSON(expected)
+		require.NoError(t, err)
+		actualJSON, err := forest.MarshalJSON(actual)
+		require.NoError(t, err)
+		require.JSONEq(t, string(expectedJSON), string(actualJSON))
+	}
+
+	t.Run("right recursion", func(t *testing.T) {
+		A := grammar.NewNonTerminal("A")
+		// A -> 'a' A | 'b'
+		g := grammar.New(A,
+			grammar.NewProduction(A, a, A),
+			grammar.NewProduction(A, b),
+		)
+		expected, actual := forests(t, g, a, a, a, a, b)
+		identical(t, expected, actual)
+	})
+	t.Run("nullable", func(t *testing.T) {
+		A := grammar.NewNonTerminal("A")
+		// A -> 'a' A | <null>
+		g := grammar.New(A,
+			grammar.NewProduction(A, a, A),
+			grammar.NewProduction(A),
+		)
+		expected, actual := forests(t, g, a, a, a, a)
+		identical(t, expected, actual)
+	})
+	t.Run("intermediate", func(t *testing.T) {
+		A := grammar.NewNonTerminal("A")
+		// A -> 'a' 'b' A | 'b'
+		g := grammar.New(A,
+			grammar.NewProduction(A, a, b, A),
+			grammar.NewProduction(A, b),
+		)
+		expected, actual := forests(t, g, a, b, a, b, a, b, b)
+		identical(t, expected, actual)
+	})
+	t.Run("nullable suffix", func(t *testing.T) {
+		P := grammar.NewNonTerminal("P")
+		L := grammar.NewNonTerminal("L")
+		S := grammar.NewNonTerminal("S")
+		// P -> L, L -> S L | <null>, S -> 'a' 'b'
+		g := grammar.New(P,
+			grammar.NewProduction(P, L),
+			grammar.NewProduction(L, S, L),
+			grammar.NewProduction(L),
+			grammar.NewProduction(S, a, b),
+		)
+		expected, actual := forests(t, g, a, b, a, b, a, b)
+		identical(t, expected, actual)
+	})
+	t.Run("nulling suffix", func(t *testing.T) {
+		A := grammar.NewNonTerminal("A")
+		N := grammar.NewNonTerminal("N")
+		// A -> 'a' A N | 'b', N -> <null>
+		g := grammar.New(A,
+			grammar.NewProduction(A, a, A, N),
+			grammar.NewProduction(A, b),
+			grammar.NewProduction(N),
+		)
+		expected, actual := forests(t, g, a, a, a, b)
+		identical(t, expected, actual)
+	})
+	t.Run("nulling suffix intermediate", func(t *testing.T) {
+		A := grammar.NewNonTerminal("A")
+		N := grammar.NewNonTerminal("N")
+		M := grammar.NewNonTerminal("M")
+		// A -> 'a' A N N | A N | 'b', N -> M M, M -> <null>
+		g := grammar.New(A,
+			grammar.NewProduction(A, a, A, N, N),
+			grammar.NewProduction(A, b),
+			grammar.NewProduction(N, M, M),
+			grammar.NewProduction(M),
+		)
+		expected, actual := forests(t, g, a, a, a, b)
+		identical(t, expected, actual)
+	})
+	t.Run("nulling suffix mutual recursion", func(t *testing.T) {
+		A := grammar.NewNonTerminal("A")
+		B := grammar.NewNonTerminal("B")
+		N := grammar.NewNonTerminal("N")
+		M := grammar.NewNonTerminal("M")
+		// A -> 'a' B N | 'b', B -> 'b' A M, N -> <null>, M -> N N
+		g := grammar.New(A,
+			grammar.NewProduction(A, a, B, N),
+			grammar.NewProduction(A, b),
+			grammar.NewProduction(B, b, A, M),
+			grammar.NewProduction(N),
+			grammar.NewProduction(M, N, N),
+		)
+		expected, actual := forests(t, g, a, b, a, b, b)
+		identical(t, expected, actual)
+	})
+	t.Run("nullable suffix is not memoized", func(t *testing.T) {
+		A := grammar.NewNonTerminal("A")
+		N := grammar.NewNonTerminal("N")
+		c := grammar.NewStringLexerRule("c")
+		// A -> 'a' A N | 'b', N -> 'c' | <null>
+		// N can derive a token so the items before it are completed without leo
+		g := grammar.New(A,
+			grammar.NewProduction(A, a, A, N),
+			grammar.NewProduction(A, b),
+			grammar.NewProduction(N, c),
+			grammar.NewProduction(N),
+		)
+		buf := &bytes.Buffer{}
+		p := parser.New(g, parser.Trace(parser.NewWriterTracer(buf)))
+		parse(t, p, a, a, b, c)
+		require.NotContains(t, buf.String(), "A : A -> a A N•")
+
+		expected, actual := forests(t, g, a, a, b, c)
+		identical(t, expected, actual)
+	})
+	t.Run("is deterministic", func(t *testing.T) {
+		T := grammar.NewNonTerminal("T")
+		F := grammar.NewNonTerminal("F")
+		// T -> F T | F, F -> 'a'
+		g := grammar.New(T,
+			grammar.NewProduction(T, F, T),
+			grammar.NewProduction(T, F),
+			grammar.NewProduction(F, a),
+		)
+		expected, _ := forests(t, g, a, a, a, a)
+		for i := 0; i < 10; i++ {
+			_, actual := forests(t, g, a, a, a, a)
+			identical(t, expected, actual)
+		}
+	})
+	t.Run("ambiguous", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		A := grammar.NewNonTerminal("A")
+		// S -> A S | A, A -> 'a' | 'a' 'a'
+		g := grammar.New(S,
+			grammar.NewProduction(S, A, S),
+			grammar.NewProduction(S, A),
+			grammar.NewProduction(A, a),
+			grammar.NewProduction(A, a, a),
+		)
+		expected, actual := forests(t, g, a, a, a, a)
+		require.Equal(t, int64(5), forest.CountTrees(expected).Int64())
+		identical(t, expected, actual)
+	})
+}
+
+func TestAddUniqueDerivation(t *testing.T) {
+	S := grammar.NewNonTerminal("S")
+	a := grammar.NewStringLexerRule("a")
+	first := grammar.NewProduction(S, a)
+	second := grammar.NewProduction(S, a)
+
+	w := forest.NewToken(token.NewString(a, 0), 0, 1)
+	node := forest.NewSymbol(S, 0, 1)
+	node.AddUniqueDerivation(first, w, nil)
+	node.AddUniqueDerivation(second, w, nil)
+	node.AddUniqueDerivation(first, w, nil)
+
+	// the families have the same children but different productions
+	alternatives := node.Alternatives()
+	require.Len(t, alternatives, 2)
+	require.Same(t, first, forest.Production(node, alternatives[0]))
+	require.Same(t, second, forest.Production(node, alternatives[1]))
+
+	ambiguities := forest.Ambiguities(node)
+	require.Len(t, ambiguities, 1)
+	require.Equal(t, []*grammar.Production{first, second}, ambiguities[0].Productions)
+}
@@ -33,3 +33,39 @@ func (t Token) String() string {
 func (t *Token) Accept(v Visitor) {
 	v.VisitToken(t)
 }
+
+// Text returns the text matched by the token or an empty string if the token does not carry its text
+func (t Token) Text() string {
+	capture, ok := t.Token.(*token.Capture)
+	if !ok {
+		return ""
+	}
+	return capture.Text
+}
+
+// Span returns the byte offset and length of the token in the input
+func (t Token) Span() token.Span {
+	capture, ok := t.Token.(*token.Capture)
+	if !ok {
+		return token.Span{}
+	}
+	return capture.Span
+}
+
+// Start returns the line and column of the first character of the token
+func (t Token) Start() token.Location {
+	capture, ok := t.Token.(*token.Capture)
+	if !ok {
+		return token.Location{}
+	}
+	return capture.Start
+}
+
+// End returns the line and column of the last character of the token
+func (t Token) End() token.Location {
+	capture, ok := t.Token.(*token.Capture)
+	if !ok {
+		return token.Location{}
+	}
+	return capture.End
+}
@@ -0,0 +1,259 @@
+package forest
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/patrickhuber/go-earley/grammar"
+)
+
+// CountTrees returns the number of parse trees the forest encodes
+// A forest with a cycle encodes infinitely many trees, the count is then -1 and IsInfinite reports it.
+func CountTrees(root Node) *big.Int {
+	c := &counter{
+		counts:   map[Node]*big.Int{},
+		visiting: map[Node]struct{}{},
+	}
+	count, ok := c.count(root)
+	if !ok {
+		return big.NewInt(-1)
+	}
+	return count
+}
+
+// IsInfinite returns true if the count of CountTrees stands for infinitely many trees
+func IsInfinite(count *big.Int) bool {
+	return count.Sign() < 0
+}
+
+type counter struct {
+	counts   map[Node]*big.Int
+	visiting map[Node]struct{}
+}
+
+// count returns false if the node reaches itself
+func (c *counter) count(node Node) (*big.Int, bool) {
+	if count, ok := c.counts[node]; ok {
+		return count, true
+	}
+	if _, ok := c.visiting[node]; ok {
+		return nil, false
+	}
+	internal, ok := node.(Internal)
+	if !ok || len(internal.Alternatives()) == 0 {
+		return big.NewInt(1), true
+	}
+
+	c.visiting[node] = struct{}{}
+	defer delete(c.visiting, node)
+
+	total := new(big.Int)
+	for _, alternative := range internal.Alternatives() {
+		product := big.NewInt(1)
+		for _, child := range alternative.Children() {
+			count, ok := c.count(child)
+			if !ok {
+				return nil, false
+			}
+			product.Mul(product, count)
+		}
+		total.Add(total, product)
+	}
+	c.counts[node] = total
+	return total, true
+}
+
+// Tree is a single derivation from the forest
+// Node is a Symbol with the full right hand side of Production as Children or a Token leaf.
+// Intermediate nodes never appear in a Tree.
+// Production is nil for tokens and for symbols without derivations.
+type Tree struct {
+	Node       Node
+	Production *grammar.Production
+	Children   []*Tree
+}
+
+func (t *Tree) String() string {
+	sb := &strings.Builder{}
+	t.write(sb)
+	return sb.String()
+}
+
+func (t *Tree) write(sb *strings.Builder) {
+	switch n := t.Node.(type) {
+	case *Token:
+		sb.WriteString(n.Token.TokenType())
+		return
+	case *Symbol:
+		sb.WriteString("(")
+		sb.WriteString(n.Symbol.String())
+	}
+	for _, child := range t.Children {
+		sb.WriteString(" ")
+		child.write(sb)
+	}
+	sb.WriteString(")")
+}
+
+// TreeIterator yields the trees of a forest one at a time
+// Each tree is built on demand from a list of alternative choices, so only the current tree is held in memory.
+// Derivations that contain a node inside itself are skipped, so a cyclic forest yields a finite number of trees.
+type TreeIterator struct {
+	root    Node
+	choices []int
+	options []int
+	tree    *Tree
+	started bool
+	done    bool
+}
+
+// Trees returns an iterator over the trees of the forest
+func Trees(root Node) *TreeIterator {
+	return &TreeIterator{
+		root: root,
+	}
+}
+
+// Next moves to the next tree and returns false when no trees remain
+func (it *TreeIterator) Next() bool {
+	for !it.done {
+		if it.started && !it.advance() {
+			break
+		}
+		it.started = true
+		b := &builder{
+			choices: it.choices,
+			options: it.options,
+			path:    map[Node]struct{}{},
+		}
+		tree, ok := b.node(it.root)
+		it.choices = b.choices[:b.index]
+		it.options = b.options[:b.index]
+		if ok {
+			it.tree = tree
+			return true
+		}
+	}
+	it.done = true
+	it.tree = nil
+	return false
+}
+
+// Tree returns the current tree
+func (it *TreeIterator) Tree() *Tree {
+	return it.tree
+}
+
+// advance moves to the next choice like an odometer, the choices after the advanced choice start over
+func (it *TreeIterator) advance() bool {
+	for i := len(it.choices) - 1; i >= 0; i-- {
+		if it.choices[i]+1 < it.options[i] {
+			it.choices[i]++
+			it.choices = it.choices[:i+1]
+			it.options = it.options[:i+1]
+			return true
+		}
+	}
+	return false
+}
+
+// builder builds a tree by following the choices in depth first order
+// choices past the end of the list start at the first alternative
+type builder struct {
+	choices []int
+	options []int
+	index   int
+	path    map[Node]struct{}
+}
+
+func (b *builder) node(node Node) (*Tree, bool) {
+	symbol, ok := node.(*Symbol)
+	if !ok {
+		return &Tree{Node: node}, true
+	}
+	b.path[symbol] = struct{}{}
+	defer delete(b.path, symbol)
+
+	tree := &Tree{Node: symbol}
+	alternative, ok := b.choose(symbol)
+	if !ok {
+		return nil, false
+	}
+	if alternative == nil {
+		// the parser records empty productions, only forests built by hand have symbols without derivations
+		return tree, true
+	}
+	tree.Production = Production(symbol, alternative)
+	tree.Children, ok = b.children(alternative)
+	return tree, ok
+}
+
+// children returns the trees of the children of the alternative with intermediate nodes expanded in place
+func (b *builder) children(alternative Group) ([]*Tree, bool) {
+	var trees []*Tree
+	for _, child := range alternative.Children() {
+		intermediate, ok := child.(*Intermediate)
+		if !ok {
+			tree, ok := b.node(child)
+			if !ok {
+				return nil, false
+			}
+			trees = append(trees, tree)
+			continue
+		}
+		b.path[intermediate] = struct{}{}
+		expanded, ok := b.intermediate(intermediate)
+		delete(b.path, intermediate)
+		if !ok {
+			return nil, false
+		}
+		trees = append(trees, expanded...)
+	}
+	return trees, true
+}
+
+func (b *builder) intermediate(intermediate *Intermediate) ([]*Tree, bool) {
+	alternative, ok := b.choose(intermediate)
+	if !ok || alternative == nil {
+		return nil, ok
+	}
+	return b.children(alternative)
+}
+
+// choose returns the alternative of the node for the current choice
+// nil is returned for nodes without alternatives and false if every alternative leads back to a node on the path
+func (b *builder) choose(internal Internal) (Group, bool) {
+	alternatives := internal.Alternatives()
+	if len(alternatives) == 0 {
+		return nil, true
+	}
+	var acyclic []Group
+	for _, alternative := range alternatives {
+		if !b.cyclic(alternative) {
+			acyclic = append(acyclic, alternative)
+		}
+	}
+	if len(acyclic) == 0 {
+		return nil, false
+	}
+	if len(acyclic) == 1 {
+		return acyclic[0], true
+	}
+	if b.index == len(b.choices) {
+		b.choices = append(b.choices, 0)
+		b.options = append(b.options, 0)
+	}
+	choice := b.choices[b.index]
+	b.options[b.index] = len(acyclic)
+	b.index++
+	return acyclic[choice], true
+}
+
+func (b *builder) cyclic(alternative Group) bool {
+	for _, child := range alternative.Children() {
+		if _, ok := b.path[child]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package forest_test
+
+import (
+	"testing"
+
+	"github.com/patrickhuber/go-earley/forest"
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountTrees(t *testing.T) {
+	E := grammar.NewNonTerminal("E")
+	plus := grammar.NewStringLexerRule("+")
+	a := grammar.NewStringLexerRule("a")
+
+	// E -> E '+' E | 'a'
+	g := grammar.New(E,
+		grammar.NewProduction(E, E, plus, E),
+		grammar.NewProduction(E, a),
+	)
+
+	// the number of trees is the catalan number of the number of operators
+	tests := []struct {
+		operators int
+		count     int64
+	}{
+		{0, 1}, {1, 1}, {2, 2}, {3, 5}, {4, 14},
+	}
+	for _, test := range tests {
+		input := []*grammar.StringLexerRule{a}
+		for i := 0; i < test.operators; i++ {
+			input = append(input, plus, a)
+		}
+		root := parse(t, parser.New(g), input...)
+		require.Equal(t, test.count, forest.CountTrees(root).Int64(), "operators %d", test.operators)
+	}
+
+	t.Run("cycle is infinite", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		// S -> S | 'a'
+		g := grammar.New(S,
+			grammar.NewProduction(S, S),
+			grammar.NewProduction(S, a),
+		)
+		root := parse(t, parser.New(g), a)
+		require.True(t, forest.IsInfinite(forest.CountTrees(root)))
+	})
+}
+
+func TestTrees(t *testing.T) {
+	E := grammar.NewNonTerminal("E")
+	plus := grammar.NewStringLexerRule("+")
+	a := grammar.NewStringLexerRule("a")
+
+	// E -> E '+' E | 'a'
+	g := grammar.New(E,
+		grammar.NewProduction(E, E, plus, E),
+		grammar.NewProduction(E, a),
+	)
+
+	t.Run("enumerates each tree", func(t *testing.T) {
+		root := parse(t, parser.New(g), a, plus, a, plus, a)
+
+		var trees []string
+		it := forest.Trees(root)
+		for it.Next() {
+			tree := it.Tree()
+			require.Equal(t, "E -> E + E", tree.Production.String())
+			require.Len(t, tree.Children, 3)
+			trees = append(trees, tree.String())
+		}
+		require.ElementsMatch(t, []string{
+			"(E (E (E a) + (E a)) + (E a))",
+			"(E (E a) + (E (E a) + (E a)))",
+		}, trees)
+		require.False(t, it.Next())
+	})
+	t.Run("expands intermediate nodes", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		b := grammar.NewStringLexerRule("b")
+		c := grammar.NewStringLexerRule("c")
+		// S -> 'a' 'b' 'c' 'a'
+		g := grammar.New(S, grammar.NewProduction(S, a, b, c, a))
+		root := parse(t, parser.New(g), a, b, c, a)
+
+		require.Equal(t, []string{"(S a b c a)"}, trees(root))
+	})
+	t.Run("skips cycles", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		// S -> S | 'a'
+		g := grammar.New(S,
+			grammar.NewProduction(S, S),
+			grammar.NewProduction(S, a),
+		)
+		root := parse(t, parser.New(g), a)
+
+		require.Equal(t, []string{"(S a)"}, trees(root))
+	})
+}
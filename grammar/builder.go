@@ -0,0 +1,279 @@
+package grammar
+
+import "fmt"
+
+// Builder creates a grammar from rules written with EBNF operators
+//
+//	b := grammar.NewBuilder()
+//	b.Rule("List").Seq(grammar.Literal("["), grammar.SepBy(grammar.Ref("Item"), grammar.Literal(",")), grammar.Literal("]"))
+//	b.Rule("Item").Seq(grammar.Literal("a")).Or(grammar.Ref("List"))
+//	g, err := b.Build("List")
+//
+// Many, Many1, Optional and SepBy desugar into productions of synthetic nonterminals named after the rule that uses them.
+type Builder struct {
+	rules     map[string]*RuleBuilder
+	order     []*RuleBuilder
+	literals  map[string]LexerRule
+	regexes   map[string]LexerRule
+	compile   func(pattern string) (LexerRule, error)
+	generated int
+	// productions holds the productions of the build in progress
+	productions []*Production
+}
+
+type BuilderOption func(*Builder)
+
+// RegexCompiler sets the function that compiles the patterns of Regex elements into lexer rules, like pdl.Regex
+func RegexCompiler(compile func(pattern string) (LexerRule, error)) BuilderOption {
+	return func(b *Builder) {
+		b.compile = compile
+	}
+}
+
+func NewBuilder(options ...BuilderOption) *Builder {
+	b := &Builder{
+		rules:    map[string]*RuleBuilder{},
+		literals: map[string]LexerRule{},
+		regexes:  map[string]LexerRule{},
+	}
+	for _, option := range options {
+		option(b)
+	}
+	return b
+}
+
+// RuleBuilder holds the alternatives of a rule
+type RuleBuilder struct {
+	nonTerminal  NonTerminal
+	alternatives [][]Element
+}
+
+// Rule returns the rule with the name, the rule is created the first time it is requested
+func (b *Builder) Rule(name string) *RuleBuilder {
+	if r, ok := b.rules[name]; ok {
+		return r
+	}
+	r := &RuleBuilder{
+		nonTerminal: NewNonTerminal(name),
+	}
+	b.rules[name] = r
+	b.order = append(b.order, r)
+	return r
+}
+
+// Seq adds an alternative that is the sequence of the elements, no elements is the empty alternative
+func (r *RuleBuilder) Seq(elements ...Element) *RuleBuilder {
+	r.alternatives = append(r.alternatives, elements)
+	return r
+}
+
+// Or adds another alternative, it is the same as Seq
+func (r *RuleBuilder) Or(elements ...Element) *RuleBuilder {
+	return r.Seq(elements...)
+}
+
+// Build returns the grammar with the rule of the name as the start symbol
+func (b *Builder) Build(start string) (*Grammar, error) {
+	s, ok := b.rules[start]
+	if !ok {
+		return nil, fmt.Errorf("start rule %s is not defined", start)
+	}
+	b.productions = nil
+	b.generated = 0
+	for _, r := range b.order {
+		for _, alternative := range r.alternatives {
+			rhs, err := b.sequence(r.nonTerminal, alternative)
+			if err != nil {
+				return nil, err
+			}
+			b.productions = append(b.productions, NewProduction(r.nonTerminal, rhs...))
+		}
+	}
+	return New(s.nonTerminal, b.productions...), nil
+}
+
+func (b *Builder) sequence(lhs NonTerminal, elements []Element) ([]Symbol, error) {
+	var symbols []Symbol
+	for _, element := range elements {
+		symbol, err := element.resolve(b, lhs)
+		if err != nil {
+			return nil, err
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols, nil
+}
+
+// generate creates a uniquely named synthetic nonterminal for the rule
+func (b *Builder) generate(lhs NonTerminal, kind string) NonTerminal {
+	for {
+		b.generated++
+		name := fmt.Sprintf("%s_%s_%d", lhs.Name(), kind, b.generated)
+		if _, ok := b.rules[name]; ok {
+			continue
+		}
+		return NewSyntheticNonTerminal(name)
+	}
+}
+
+func (b *Builder) add(lhs NonTerminal, rhs ...Symbol) {
+	b.productions = append(b.productions, NewProduction(lhs, rhs...))
+}
+
+// Element is a part of an alternative, it resolves to a symbol when the grammar is built
+type Element interface {
+	resolve(b *Builder, lhs NonTerminal) (Symbol, error)
+}
+
+type ref string
+
+// Ref refers to the rule with the name
+func Ref(name string) Element {
+	return ref(name)
+}
+
+func (e ref) resolve(b *Builder, lhs NonTerminal) (Symbol, error) {
+	r, ok := b.rules[string(e)]
+	if !ok {
+		return nil, fmt.Errorf("rule %s references undefined rule %s", lhs, string(e))
+	}
+	return r.nonTerminal, nil
+}
+
+type literal string
+
+// Literal matches the text, equal literals share one string lexer rule
+func Literal(value string) Element {
+	return literal(value)
+}
+
+func (e literal) resolve(b *Builder, lhs NonTerminal) (Symbol, error) {
+	if lexerRule, ok := b.literals[string(e)]; ok {
+		return lexerRule, nil
+	}
+	lexerRule := NewStringLexerRule(string(e))
+	b.literals[string(e)] = lexerRule
+	return lexerRule, nil
+}
+
+type regex string
+
+// Regex matches the regular expression, the Builder compiles it with the RegexCompiler option
+func Regex(pattern string) Element {
+	return regex(pattern)
+}
+
+func (e regex) resolve(b *Builder, lhs NonTerminal) (Symbol, error) {
+	if lexerRule, ok := b.regexes[string(e)]; ok {
+		return lexerRule, nil
+	}
+	if b.compile == nil {
+		return nil, fmt.Errorf("rule %s uses regex /%s/ without a RegexCompiler", lhs, string(e))
+	}
+	lexerRule, err := b.compile(string(e))
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: %w", lhs, err)
+	}
+	b.regexes[string(e)] = lexerRule
+	return lexerRule, nil
+}
+
+type use struct {
+	symbol Symbol
+}
+
+// Use adds an existing symbol, like a lexer rule or a nonterminal of another grammar
+func Use(symbol Symbol) Element {
+	return use{symbol: symbol}
+}
+
+func (e use) resolve(b *Builder, lhs NonTerminal) (Symbol, error) {
+	return e.symbol, nil
+}
+
+type many struct {
+	elements []Element
+	min1     bool
+}
+
+// Many matches the sequence of elements zero or more times
+//
+//	R -> e R | <empty>
+func Many(elements ...Element) Element {
+	return many{elements: elements}
+}
+
+// Many1 matches the sequence of elements one or more times
+//
+//	R -> e R | e
+func Many1(elements ...Element) Element {
+	return many{elements: elements, min1: true}
+}
+
+func (e many) resolve(b *Builder, lhs NonTerminal) (Symbol, error) {
+	kind := "many"
+	if e.min1 {
+		kind = "many1"
+	}
+	nt := b.generate(lhs, kind)
+	symbols, err := b.sequence(lhs, e.elements)
+	if err != nil {
+		return nil, err
+	}
+	b.add(nt, append(symbols, nt)...)
+	if e.min1 {
+		b.add(nt, symbols...)
+	} else {
+		b.add(nt)
+	}
+	return nt, nil
+}
+
+type optional struct {
+	elements []Element
+}
+
+// Optional matches the sequence of elements zero or one time
+//
+//	O -> e | <empty>
+func Optional(elements ...Element) Element {
+	return optional{elements: elements}
+}
+
+func (e optional) resolve(b *Builder, lhs NonTerminal) (Symbol, error) {
+	nt := b.generate(lhs, "optional")
+	symbols, err := b.sequence(lhs, e.elements)
+	if err != nil {
+		return nil, err
+	}
+	b.add(nt, symbols...)
+	b.add(nt)
+	return nt, nil
+}
+
+type sepBy struct {
+	element   Element
+	separator Element
+}
+
+// SepBy matches the element zero or more times with the separator between each element
+//
+//	S -> L | <empty>
+//	L -> e | e s L
+func SepBy(element, separator Element) Element {
+	return sepBy{element: element, separator: separator}
+}
+
+func (e sepBy) resolve(b *Builder, lhs NonTerminal) (Symbol, error) {
+	nt := b.generate(lhs, "sepby")
+	list := b.generate(lhs, "sepby")
+	symbols, err := b.sequence(lhs, []Element{e.element, e.separator})
+	if err != nil {
+		return nil, err
+	}
+	b.add(nt, list)
+	b.add(nt)
+	b.add(list, symbols[0])
+	b.add(list, symbols[0], symbols[1], list)
+	return nt, nil
+}
@@ -0,0 +1,98 @@
+package grammar_test
+
+import (
+	"testing"
+
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/parser"
+	"github.com/patrickhuber/go-earley/pdl"
+	"github.com/patrickhuber/go-earley/scanner"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder(t *testing.T) {
+	productions := func(g *grammar.Grammar) []string {
+		var productions []string
+		for _, p := range g.Productions {
+			productions = append(productions, p.String())
+		}
+		return productions
+	}
+
+	t.Run("rules", func(t *testing.T) {
+		b := grammar.NewBuilder()
+		b.Rule("Expr").
+			Seq(grammar.Ref("Expr"), grammar.Literal("+"), grammar.Ref("Term")).
+			Or(grammar.Ref("Term"))
+		b.Rule("Term").Seq(grammar.Literal("a"))
+
+		g, err := b.Build("Expr")
+		require.NoError(t, err)
+		require.Equal(t, "Expr", g.Start.Name())
+		require.Equal(t, []string{
+			"Expr -> Expr + Term",
+			"Expr -> Term",
+			"Term -> a",
+		}, productions(g))
+		require.Empty(t, grammar.Validate(g))
+	})
+	t.Run("desugars operators", func(t *testing.T) {
+		b := grammar.NewBuilder()
+		b.Rule("S").Seq(
+			grammar.Many(grammar.Literal("a")),
+			grammar.Many1(grammar.Literal("b")),
+			grammar.Optional(grammar.Literal("c")),
+			grammar.SepBy(grammar.Literal("d"), grammar.Literal(",")),
+		)
+
+		g, err := b.Build("S")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"S_many_1 -> a S_many_1",
+			"S_many_1 ->",
+			"S_many1_2 -> b S_many1_2",
+			"S_many1_2 -> b",
+			"S_optional_3 -> c",
+			"S_optional_3 ->",
+			"S_sepby_4 -> S_sepby_5",
+			"S_sepby_4 ->",
+			"S_sepby_5 -> d",
+			"S_sepby_5 -> d , S_sepby_5",
+			"S -> S_many_1 S_many1_2 S_optional_3 S_sepby_4",
+		}, productions(g))
+		require.Empty(t, grammar.Validate(g))
+
+		for _, p := range g.Productions {
+			require.Equal(t, p.LeftHandSide.Name() != "S", grammar.IsSynthetic(p.LeftHandSide), p.String())
+		}
+	})
+	t.Run("parses", func(t *testing.T) {
+		b := grammar.NewBuilder(grammar.RegexCompiler(pdl.Regex))
+		b.Rule("List").Seq(grammar.Literal("["), grammar.SepBy(grammar.Ref("Item"), grammar.Literal(",")), grammar.Literal("]"))
+		b.Rule("Item").Seq(grammar.Regex("[0-9]+")).Or(grammar.Ref("List"))
+
+		g, err := b.Build("List")
+		require.NoError(t, err)
+		for _, input := range []string{"[]", "[1]", "[1,[22,[]],3]"} {
+			accepted, err := scanner.RunToEnd(scanner.New(parser.New(g), input))
+			require.NoError(t, err, input)
+			require.True(t, accepted, input)
+		}
+	})
+	t.Run("undefined start", func(t *testing.T) {
+		_, err := grammar.NewBuilder().Build("S")
+		require.Error(t, err)
+	})
+	t.Run("undefined rule", func(t *testing.T) {
+		b := grammar.NewBuilder()
+		b.Rule("S").Seq(grammar.Many(grammar.Ref("A")))
+		_, err := b.Build("S")
+		require.EqualError(t, err, "rule S references undefined rule A")
+	})
+	t.Run("regex without compiler", func(t *testing.T) {
+		b := grammar.NewBuilder()
+		b.Rule("S").Seq(grammar.Regex("[a-z]+"))
+		_, err := b.Build("S")
+		require.Error(t, err)
+	})
+}
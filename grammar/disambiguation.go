@@ -0,0 +1,39 @@
+package grammar
+
+// Associativity restricts how a production nests in itself or in productions of the same priority
+type Associativity int
+
+const (
+	NoAssociativity Associativity = iota
+	// LeftAssociative removes derivations where the production is the last child of itself
+	LeftAssociative
+	// RightAssociative removes derivations where the production is the first child of itself
+	RightAssociative
+	// NonAssociative removes derivations where the production is the first or last child of itself
+	NonAssociative
+)
+
+// Preference selects between the alternatives of an ambiguous node
+type Preference int
+
+const (
+	NoPreference Preference = iota
+	// Prefer keeps only the alternatives of preferred productions when there are any
+	Prefer
+	// Avoid removes the alternatives of avoided productions when there are others
+	Avoid
+)
+
+// Disambiguates returns true if any production has disambiguation metadata
+func (g *Grammar) Disambiguates() bool {
+	for _, p := range g.Productions {
+		if p.Priority != 0 ||
+			p.Associativity != NoAssociativity ||
+			p.Preference != NoPreference ||
+			p.Reject ||
+			len(p.NotFollowedBy) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -8,11 +8,15 @@ import (
 )
 
 type Grammar struct {
-	Start          NonTerminal
-	Productions    []*Production
-	Rules          RuleRegistry
+	Start       NonTerminal
+	Productions []*Production
+	Rules       RuleRegistry
+	// Ignore holds lexer rules, like whitespace and comments, that may appear between any two tokens
+	Ignore         []LexerRule
 	transitiveNull map[Symbol]struct{}
+	nulling        map[Symbol]struct{}
 	rightRecursive map[*Production]struct{}
+	sets           *sets
 }
 
 func New(start NonTerminal, productions ...*Production) *Grammar {
@@ -29,12 +33,16 @@ func New(start NonTerminal, productions ...*Production) *Grammar {
 
 	// compute transitive null
 	g.transitiveNull = identifyNullableSymbols(g)
+	g.nulling = identifyNullingSymbols(g)
 
 	// compute right recursive
 	rightRecursive, err := g.identifyRightRecursiveSymbols().Deconstruct()
 	if err == nil {
 		g.rightRecursive = rightRecursive
 	}
+
+	// compute first, last and follow sets
+	g.sets = identifySets(g)
 	return g
 }
 
@@ -215,6 +223,31 @@ func dequeue[T any](queue []T) ([]T, T) {
 	return queue[1:], queue[0]
 }
 
+// identifyNullingSymbols starts with the nullable symbols and removes the ones with a production that can derive a lexer rule
+func identifyNullingSymbols(g *Grammar) map[Symbol]struct{} {
+	nulling := make(map[Symbol]struct{})
+	for sym := range g.transitiveNull {
+		nulling[sym] = struct{}{}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, p := range g.Productions {
+			if _, ok := nulling[p.LeftHandSide]; !ok {
+				continue
+			}
+			for _, sym := range p.RightHandSide {
+				if _, ok := nulling[sym]; ok {
+					continue
+				}
+				delete(nulling, p.LeftHandSide)
+				changed = true
+				break
+			}
+		}
+	}
+	return nulling
+}
+
 func (g *Grammar) RulesFor(nt NonTerminal) []*Production {
 	// TODO: optimize this if it becomes a memory hog
 	var productions []*Production
@@ -236,6 +269,12 @@ func (g *Grammar) IsTransativeNullable(nt NonTerminal) bool {
 	return ok
 }
 
+// IsNulling returns true if the nonterminal only derives the empty string
+func (g *Grammar) IsNulling(nt NonTerminal) bool {
+	_, ok := g.nulling[nt]
+	return ok
+}
+
 func (g *Grammar) IsRightRecursive(p *Production) bool {
 	_, ok := g.rightRecursive[p]
 	return ok
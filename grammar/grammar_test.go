@@ -20,6 +20,23 @@ func TestGrammar(t *testing.T) {
 		require.True(t, g.IsTransativeNullable(A))
 		require.True(t, g.IsTransativeNullable(E))
 	})
+	t.Run("nulling", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		A := grammar.NewNonTerminal("A")
+		E := grammar.NewNonTerminal("E")
+		a := grammar.NewStringLexerRule("a")
+
+		// S -> A E, A -> 'a' | <null>, E -> E E | <null>
+		g := grammar.New(S,
+			grammar.NewProduction(S, A, E),
+			grammar.NewProduction(A, a),
+			grammar.NewProduction(A),
+			grammar.NewProduction(E, E, E),
+			grammar.NewProduction(E))
+		require.False(t, g.IsNulling(S))
+		require.False(t, g.IsNulling(A))
+		require.True(t, g.IsNulling(E))
+	})
 	
 	t.Run("right recursive", func(t *testing.T) {
 		A := grammar.NewNonTerminal("A")
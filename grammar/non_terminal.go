@@ -6,7 +6,8 @@ type NonTerminal interface {
 }
 
 type nonTerminal struct {
-	name string
+	name      string
+	synthetic bool
 }
 
 func (nt *nonTerminal) Name() string {
@@ -21,6 +22,21 @@ func NewNonTerminal(name string) NonTerminal {
 	}
 }
 
+// NewSyntheticNonTerminal creates a nonterminal generated for a repetition, optional or grouping
+// it is not part of the grammar as written, so forest consumers can hide it with IsSynthetic
+func NewSyntheticNonTerminal(name string) NonTerminal {
+	return &nonTerminal{
+		name:      name,
+		synthetic: true,
+	}
+}
+
+// IsSynthetic returns true if the symbol is a nonterminal created with NewSyntheticNonTerminal
+func IsSynthetic(s Symbol) bool {
+	nt, ok := s.(*nonTerminal)
+	return ok && nt.synthetic
+}
+
 func (nt *nonTerminal) Equal(other Symbol) bool {
 	otherNonTerminal, ok := other.(NonTerminal)
 	if !ok {
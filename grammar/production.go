@@ -1,8 +1,27 @@
 package grammar
 
+import (
+	"context"
+	"strings"
+)
+
+// Action computes the value of a production from the values of its children
+type Action func(ctx context.Context, children []any) (any, error)
+
 type Production struct {
 	LeftHandSide  NonTerminal
 	RightHandSide []Symbol
+	// Priority orders the productions for disambiguation, a higher priority binds tighter
+	// zero means the production has no priority
+	Priority      int
+	Associativity Associativity
+	Preference    Preference
+	// Reject removes every derivation of the left hand side that the production can also derive
+	Reject bool
+	// NotFollowedBy removes derivations of the production that are followed by a token of one of the lexer rules
+	NotFollowedBy []LexerRule
+	// Action computes the value of the production when the forest is evaluated
+	Action Action
 }
 
 func NewProduction(lhs NonTerminal, rhs ...Symbol) *Production {
@@ -10,4 +29,15 @@ func NewProduction(lhs NonTerminal, rhs ...Symbol) *Production {
 		LeftHandSide:  lhs,
 		RightHandSide: rhs,
 	}
-}
\ No newline at end of file
+}
+
+func (p *Production) String() string {
+	sb := &strings.Builder{}
+	sb.WriteString(p.LeftHandSide.Name())
+	sb.WriteString(" ->")
+	for _, s := range p.RightHandSide {
+		sb.WriteString(" ")
+		sb.WriteString(s.String())
+	}
+	return sb.String()
+}
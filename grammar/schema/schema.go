@@ -0,0 +1,715 @@
+// Package schema reads and writes grammars in a versioned json and yaml format
+// Symbols refer to each other by name, so other tools can generate grammars that load without compiling pdl.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+
+	"github.com/patrickhuber/go-earley/automata/dfa"
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/pdl"
+	"github.com/patrickhuber/go-earley/terminal"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the version of the schema written by New
+const Version = 1
+
+// Grammar is the schema of a grammar.Grammar
+// Names are unique across nonterminals and lexer rules, the right hand side of a production lists the names of its symbols.
+type Grammar struct {
+	Version      int           `json:"version" yaml:"version"`
+	Start        string        `json:"start" yaml:"start"`
+	NonTerminals []NonTerminal `json:"nonTerminals" yaml:"nonTerminals"`
+	LexerRules   []LexerRule   `json:"lexerRules,omitempty" yaml:"lexerRules,omitempty"`
+	Productions  []Production  `json:"productions" yaml:"productions"`
+	// Ignore holds the names of the lexer rules that may appear between any two tokens
+	Ignore []string `json:"ignore,omitempty" yaml:"ignore,omitempty"`
+}
+
+type NonTerminal struct {
+	Name      string `json:"name" yaml:"name"`
+	Synthetic bool   `json:"synthetic,omitempty" yaml:"synthetic,omitempty"`
+}
+
+// LexerRule is a string, terminal or dfa lexer rule, the type is the LexerRuleType of the lexer rule
+type LexerRule struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
+	// Value is the text of a string lexer rule
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+	// Terminal is the terminal of a terminal lexer rule
+	Terminal *Terminal `json:"terminal,omitempty" yaml:"terminal,omitempty"`
+	// TokenType and either Pattern or States describe a dfa
+	// The name is the token type when it is empty.
+	TokenType string `json:"tokenType,omitempty" yaml:"tokenType,omitempty"`
+	// Pattern is the regular expression of the dfa without the enclosing slashes, the dfa is rebuilt from it
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	// States lists the states of the dfa, the first state is the start state
+	States []State `json:"states,omitempty" yaml:"states,omitempty"`
+}
+
+// State is a dfa state, transitions refer to their target by its index
+type State struct {
+	Final       bool         `json:"final,omitempty" yaml:"final,omitempty"`
+	Transitions []Transition `json:"transitions,omitempty" yaml:"transitions,omitempty"`
+}
+
+type Transition struct {
+	Terminal Terminal `json:"terminal" yaml:"terminal"`
+	Target   int      `json:"target" yaml:"target"`
+}
+
+const (
+	AnyTerminal        = "any"
+	CharacterTerminal  = "character"
+	RangeTerminal      = "range"
+	RangeSetTerminal   = "rangeSet"
+	SetTerminal        = "set"
+	NegateTerminal     = "negate"
+	LetterTerminal     = "letter"
+	NumberTerminal     = "number"
+	WhitespaceTerminal = "whitespace"
+)
+
+// Terminal is a class of characters, each character is written as a string with one character
+type Terminal struct {
+	Type string `json:"type" yaml:"type"`
+	// Value is the character of a character terminal
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+	// Min and Max are the inclusive bounds of a range terminal
+	Min string `json:"min,omitempty" yaml:"min,omitempty"`
+	Max string `json:"max,omitempty" yaml:"max,omitempty"`
+	// Intervals holds the sorted, non overlapping intervals of a range set terminal
+	Intervals []Interval `json:"intervals,omitempty" yaml:"intervals,omitempty"`
+	// Terminals holds the terminals of a set terminal
+	Terminals []Terminal `json:"terminals,omitempty" yaml:"terminals,omitempty"`
+	// Terminal is the terminal whose characters a negate terminal excludes
+	Terminal *Terminal `json:"terminal,omitempty" yaml:"terminal,omitempty"`
+}
+
+type Interval struct {
+	Min string `json:"min" yaml:"min"`
+	Max string `json:"max" yaml:"max"`
+}
+
+const (
+	LeftAssociativity     = "left"
+	RightAssociativity    = "right"
+	NonAssocAssociativity = "nonassoc"
+	PreferPreference      = "prefer"
+	AvoidPreference       = "avoid"
+)
+
+// Production holds the disambiguation metadata of a production, actions are code and are not part of the schema
+type Production struct {
+	LeftHandSide  string   `json:"lhs" yaml:"lhs"`
+	RightHandSide []string `json:"rhs" yaml:"rhs"`
+	Priority      int      `json:"priority,omitempty" yaml:"priority,omitempty"`
+	// Associativity is left, right or nonassoc
+	Associativity string `json:"associativity,omitempty" yaml:"associativity,omitempty"`
+	// Preference is prefer or avoid
+	Preference    string   `json:"preference,omitempty" yaml:"preference,omitempty"`
+	Reject        bool     `json:"reject,omitempty" yaml:"reject,omitempty"`
+	NotFollowedBy []string `json:"notFollowedBy,omitempty" yaml:"notFollowedBy,omitempty"`
+}
+
+// MarshalJSON returns the grammar in the json schema
+func MarshalJSON(g *grammar.Grammar) ([]byte, error) {
+	s, err := New(g)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	// lexer rules like '<' and '&' are common, keep them readable
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(s); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// UnmarshalJSON reads a grammar written in the json schema
+func UnmarshalJSON(data []byte) (*grammar.Grammar, error) {
+	var s Grammar
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return s.Grammar()
+}
+
+// MarshalYAML returns the grammar in the yaml schema
+func MarshalYAML(g *grammar.Grammar) ([]byte, error) {
+	s, err := New(g)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(s); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalYAML reads a grammar written in the yaml schema
+func UnmarshalYAML(data []byte) (*grammar.Grammar, error) {
+	var s Grammar
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return s.Grammar()
+}
+
+// New converts the grammar into the schema
+// Lexer rules are named by their token type, a suffix like #2 is added when the name is taken.
+// String lexer rules with the same value are written once.
+func New(g *grammar.Grammar) (*Grammar, error) {
+	w := &writer{
+		names:   map[grammar.LexerRule]string{},
+		strings: map[string]string{},
+		taken:   map[string]struct{}{},
+	}
+	s := &Grammar{
+		Version:     Version,
+		Start:       g.Start.Name(),
+		Productions: []Production{},
+	}
+
+	s.NonTerminals = append(s.NonTerminals, w.nonTerminal(g.Start)...)
+	for _, p := range g.Productions {
+		s.NonTerminals = append(s.NonTerminals, w.nonTerminal(p.LeftHandSide)...)
+		for _, symbol := range p.RightHandSide {
+			if nt, ok := symbol.(grammar.NonTerminal); ok {
+				s.NonTerminals = append(s.NonTerminals, w.nonTerminal(nt)...)
+			}
+		}
+	}
+
+	for _, p := range g.Productions {
+		production := Production{
+			LeftHandSide:  p.LeftHandSide.Name(),
+			RightHandSide: []string{},
+			Priority:      p.Priority,
+			Reject:        p.Reject,
+		}
+		for _, symbol := range p.RightHandSide {
+			switch sym := symbol.(type) {
+			case grammar.NonTerminal:
+				production.RightHandSide = append(production.RightHandSide, sym.Name())
+			case grammar.LexerRule:
+				name, err := w.lexerRule(sym)
+				if err != nil {
+					return nil, err
+				}
+				production.RightHandSide = append(production.RightHandSide, name)
+			default:
+				return nil, fmt.Errorf("production %s: unsupported symbol %T", p, symbol)
+			}
+		}
+		switch p.Associativity {
+		case grammar.LeftAssociative:
+			production.Associativity = LeftAssociativity
+		case grammar.RightAssociative:
+			production.Associativity = RightAssociativity
+		case grammar.NonAssociative:
+			production.Associativity = NonAssocAssociativity
+		}
+		switch p.Preference {
+		case grammar.Prefer:
+			production.Preference = PreferPreference
+		case grammar.Avoid:
+			production.Preference = AvoidPreference
+		}
+		for _, lexerRule := range p.NotFollowedBy {
+			name, err := w.lexerRule(lexerRule)
+			if err != nil {
+				return nil, err
+			}
+			production.NotFollowedBy = append(production.NotFollowedBy, name)
+		}
+		s.Productions = append(s.Productions, production)
+	}
+
+	for _, lexerRule := range g.Ignore {
+		name, err := w.lexerRule(lexerRule)
+		if err != nil {
+			return nil, err
+		}
+		s.Ignore = append(s.Ignore, name)
+	}
+	s.LexerRules = w.lexerRules
+	return s, nil
+}
+
+type writer struct {
+	names map[grammar.LexerRule]string
+	// strings holds the names of string lexer rules by value
+	strings    map[string]string
+	taken      map[string]struct{}
+	lexerRules []LexerRule
+}
+
+// nonTerminal returns the schema of the nonterminal the first time its name is seen
+func (w *writer) nonTerminal(nt grammar.NonTerminal) []NonTerminal {
+	if _, ok := w.taken[nt.Name()]; ok {
+		return nil
+	}
+	w.taken[nt.Name()] = struct{}{}
+	return []NonTerminal{{Name: nt.Name(), Synthetic: grammar.IsSynthetic(nt)}}
+}
+
+// lexerRule returns the name of the lexer rule, the schema of the lexer rule is added the first time it is seen
+func (w *writer) lexerRule(lexerRule grammar.LexerRule) (string, error) {
+	if name, ok := w.names[lexerRule]; ok {
+		return name, nil
+	}
+	s, isString := lexerRule.(*grammar.StringLexerRule)
+	if isString {
+		if name, ok := w.strings[s.Value]; ok {
+			return name, nil
+		}
+	}
+
+	l := LexerRule{
+		Type: lexerRule.LexerRuleType(),
+	}
+	switch r := lexerRule.(type) {
+	case *grammar.StringLexerRule:
+		l.Value = r.Value
+	case *grammar.TerminalLexerRule:
+		t, err := newTerminal(r.Terminal)
+		if err != nil {
+			return "", fmt.Errorf("lexer rule %s: %w", r.TokenType(), err)
+		}
+		l.Terminal = &t
+	case *dfa.Dfa:
+		states, err := newStates(r)
+		if err != nil {
+			return "", fmt.Errorf("lexer rule %s: %w", r.TokenType(), err)
+		}
+		l.TokenType = r.TokenType()
+		l.States = states
+		// classes like \w expand to thousands of intervals in the states, the pattern keeps them short
+		if pattern, err := pdl.Pattern(r); err == nil && shorter(pattern, states) {
+			l.Pattern = pattern
+			l.States = nil
+		}
+	default:
+		return "", fmt.Errorf("lexer rule %s has unsupported type %s", lexerRule.TokenType(), lexerRule.LexerRuleType())
+	}
+
+	name := lexerRule.TokenType()
+	for i := 2; ; i++ {
+		if _, ok := w.taken[name]; !ok {
+			break
+		}
+		name = fmt.Sprintf("%s#%d", lexerRule.TokenType(), i)
+	}
+	l.Name = name
+	w.taken[name] = struct{}{}
+	w.names[lexerRule] = name
+	if isString {
+		w.strings[s.Value] = name
+	}
+	w.lexerRules = append(w.lexerRules, l)
+	return name, nil
+}
+
+// shorter returns true if the pattern is shorter than the json of the states
+func shorter(pattern string, states []State) bool {
+	data, err := json.Marshal(states)
+	return err != nil || len(pattern) < len(data)
+}
+
+// newStates lists the dfa states in breadth first order from the start state
+func newStates(d *dfa.Dfa) ([]State, error) {
+	states := []*dfa.State{d.Start}
+	index := map[*dfa.State]int{d.Start: 0}
+	var result []State
+	for i := 0; i < len(states); i++ {
+		state := State{Final: states[i].Final}
+		for _, transition := range states[i].Transitions {
+			target, ok := index[transition.Target]
+			if !ok {
+				target = len(states)
+				index[transition.Target] = target
+				states = append(states, transition.Target)
+			}
+			t, err := newTerminal(transition.Terminal)
+			if err != nil {
+				return nil, err
+			}
+			state.Transitions = append(state.Transitions, Transition{Terminal: t, Target: target})
+		}
+		result = append(result, state)
+	}
+	return result, nil
+}
+
+var (
+	letterType = reflect.TypeOf(terminal.NewLetter())
+	numberType = reflect.TypeOf(terminal.NewNumber())
+)
+
+// newTerminal converts the terminal into the schema, terminals of other types are written as the range set of the characters they match
+func newTerminal(t grammar.Terminal) (Terminal, error) {
+	switch t := t.(type) {
+	case *terminal.Any:
+		return Terminal{Type: AnyTerminal}, nil
+	case *terminal.Character:
+		value, err := character(t.Value)
+		return Terminal{Type: CharacterTerminal, Value: value}, err
+	case *terminal.Range:
+		min, err := character(t.Min)
+		if err != nil {
+			return Terminal{}, err
+		}
+		max, err := character(t.Max)
+		return Terminal{Type: RangeTerminal, Min: min, Max: max}, err
+	case *terminal.RangeSet:
+		return newRangeSet(t.Intervals)
+	case *terminal.Set:
+		s := Terminal{Type: SetTerminal}
+		for _, child := range t.Terminals {
+			c, err := newTerminal(child)
+			if err != nil {
+				return Terminal{}, err
+			}
+			s.Terminals = append(s.Terminals, c)
+		}
+		return s, nil
+	case *terminal.Negate:
+		c, err := newTerminal(t.Terminal())
+		if err != nil {
+			return Terminal{}, err
+		}
+		return Terminal{Type: NegateTerminal, Terminal: &c}, nil
+	case *terminal.Whitespace:
+		return Terminal{Type: WhitespaceTerminal}, nil
+	}
+	switch reflect.TypeOf(t) {
+	case letterType:
+		return Terminal{Type: LetterTerminal}, nil
+	case numberType:
+		return Terminal{Type: NumberTerminal}, nil
+	}
+	intervals, err := terminal.Intervals(t)
+	if err != nil {
+		return Terminal{}, err
+	}
+	return newRangeSet(intervals)
+}
+
+func newRangeSet(intervals []terminal.Interval) (Terminal, error) {
+	s := Terminal{Type: RangeSetTerminal}
+	for _, interval := range intervals {
+		min, err := character(interval.Min)
+		if err != nil {
+			return Terminal{}, err
+		}
+		max, err := character(interval.Max)
+		if err != nil {
+			return Terminal{}, err
+		}
+		s.Intervals = append(s.Intervals, Interval{Min: min, Max: max})
+	}
+	return s, nil
+}
+
+func character(ch rune) (string, error) {
+	if !utf8.ValidRune(ch) {
+		return "", fmt.Errorf("character %U can not be written as a string", ch)
+	}
+	return string(ch), nil
+}
+
+// Grammar converts the schema into a grammar
+func (s *Grammar) Grammar() (*grammar.Grammar, error) {
+	if s.Version != Version {
+		return nil, fmt.Errorf("unsupported grammar schema version %d, expected %d", s.Version, Version)
+	}
+	r := &reader{
+		symbols: map[string]grammar.Symbol{},
+	}
+	for _, nt := range s.NonTerminals {
+		symbol := grammar.NewNonTerminal(nt.Name)
+		if nt.Synthetic {
+			symbol = grammar.NewSyntheticNonTerminal(nt.Name)
+		}
+		if err := r.add(nt.Name, symbol); err != nil {
+			return nil, err
+		}
+	}
+	for _, l := range s.LexerRules {
+		lexerRule, err := l.lexerRule()
+		if err != nil {
+			return nil, err
+		}
+		if err := r.add(l.Name, lexerRule); err != nil {
+			return nil, err
+		}
+	}
+
+	start, err := r.nonTerminal(s.Start)
+	if err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+	var productions []*grammar.Production
+	for i, p := range s.Productions {
+		production, err := r.production(p)
+		if err != nil {
+			return nil, fmt.Errorf("production %d: %w", i, err)
+		}
+		productions = append(productions, production)
+	}
+	var ignore []grammar.LexerRule
+	for _, name := range s.Ignore {
+		lexerRule, err := r.lexerRule(name)
+		if err != nil {
+			return nil, fmt.Errorf("ignore: %w", err)
+		}
+		ignore = append(ignore, lexerRule)
+	}
+
+	g := grammar.New(start, productions...)
+	g.Ignore = ignore
+	return g, nil
+}
+
+type reader struct {
+	symbols map[string]grammar.Symbol
+}
+
+func (r *reader) add(name string, symbol grammar.Symbol) error {
+	if name == "" {
+		return fmt.Errorf("symbol without a name")
+	}
+	if _, ok := r.symbols[name]; ok {
+		return fmt.Errorf("more than one symbol is named %s", name)
+	}
+	r.symbols[name] = symbol
+	return nil
+}
+
+func (r *reader) symbol(name string) (grammar.Symbol, error) {
+	symbol, ok := r.symbols[name]
+	if !ok {
+		return nil, fmt.Errorf("undefined symbol %s", name)
+	}
+	return symbol, nil
+}
+
+func (r *reader) nonTerminal(name string) (grammar.NonTerminal, error) {
+	symbol, err := r.symbol(name)
+	if err != nil {
+		return nil, err
+	}
+	nt, ok := symbol.(grammar.NonTerminal)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a nonterminal", name)
+	}
+	return nt, nil
+}
+
+func (r *reader) lexerRule(name string) (grammar.LexerRule, error) {
+	symbol, err := r.symbol(name)
+	if err != nil {
+		return nil, err
+	}
+	lexerRule, ok := symbol.(grammar.LexerRule)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a lexer rule", name)
+	}
+	return lexerRule, nil
+}
+
+func (r *reader) production(p Production) (*grammar.Production, error) {
+	lhs, err := r.nonTerminal(p.LeftHandSide)
+	if err != nil {
+		return nil, err
+	}
+	var rhs []grammar.Symbol
+	for _, name := range p.RightHandSide {
+		symbol, err := r.symbol(name)
+		if err != nil {
+			return nil, err
+		}
+		rhs = append(rhs, symbol)
+	}
+	production := grammar.NewProduction(lhs, rhs...)
+	production.Priority = p.Priority
+	production.Reject = p.Reject
+	switch p.Associativity {
+	case "":
+	case LeftAssociativity:
+		production.Associativity = grammar.LeftAssociative
+	case RightAssociativity:
+		production.Associativity = grammar.RightAssociative
+	case NonAssocAssociativity:
+		production.Associativity = grammar.NonAssociative
+	default:
+		return nil, fmt.Errorf("unsupported associativity %s", p.Associativity)
+	}
+	switch p.Preference {
+	case "":
+	case PreferPreference:
+		production.Preference = grammar.Prefer
+	case AvoidPreference:
+		production.Preference = grammar.Avoid
+	default:
+		return nil, fmt.Errorf("unsupported preference %s", p.Preference)
+	}
+	for _, name := range p.NotFollowedBy {
+		lexerRule, err := r.lexerRule(name)
+		if err != nil {
+			return nil, err
+		}
+		production.NotFollowedBy = append(production.NotFollowedBy, lexerRule)
+	}
+	return production, nil
+}
+
+func (l LexerRule) lexerRule() (grammar.LexerRule, error) {
+	switch l.Type {
+	case grammar.StringLexerRuleType:
+		return grammar.NewStringLexerRule(l.Value), nil
+	case grammar.TerminalLexerRuleType:
+		if l.Terminal == nil {
+			return nil, fmt.Errorf("lexer rule %s: terminal lexer rule without a terminal", l.Name)
+		}
+		t, err := l.Terminal.terminal()
+		if err != nil {
+			return nil, fmt.Errorf("lexer rule %s: %w", l.Name, err)
+		}
+		return grammar.NewTerminalLexerRule(t), nil
+	case dfa.LexerRuleType:
+		tokenType := l.TokenType
+		if tokenType == "" {
+			tokenType = l.Name
+		}
+		if l.Pattern != "" {
+			if len(l.States) > 0 {
+				return nil, fmt.Errorf("lexer rule %s: dfa with both a pattern and states", l.Name)
+			}
+			lexerRule, err := pdl.Regex(l.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("lexer rule %s: %w", l.Name, err)
+			}
+			return dfa.NewDfa(lexerRule.(*dfa.Dfa).Start, tokenType), nil
+		}
+		if len(l.States) == 0 {
+			return nil, fmt.Errorf("lexer rule %s: dfa without a pattern or states", l.Name)
+		}
+		states := make([]*dfa.State, len(l.States))
+		for i := range states {
+			states[i] = &dfa.State{Final: l.States[i].Final}
+		}
+		for i, state := range l.States {
+			for _, transition := range state.Transitions {
+				if transition.Target < 0 || transition.Target >= len(states) {
+					return nil, fmt.Errorf("lexer rule %s: state %d has a transition to undefined state %d", l.Name, i, transition.Target)
+				}
+				t, err := transition.Terminal.terminal()
+				if err != nil {
+					return nil, fmt.Errorf("lexer rule %s: %w", l.Name, err)
+				}
+				states[i].Transitions = append(states[i].Transitions, dfa.Transition{
+					Terminal: t,
+					Target:   states[transition.Target],
+				})
+			}
+		}
+		return dfa.NewDfa(states[0], tokenType), nil
+	}
+	return nil, fmt.Errorf("lexer rule %s has unsupported type %s", l.Name, l.Type)
+}
+
+func (t Terminal) terminal() (grammar.Terminal, error) {
+	switch t.Type {
+	case AnyTerminal:
+		return terminal.NewAny(), nil
+	case CharacterTerminal:
+		value, err := parseCharacter(t.Value)
+		if err != nil {
+			return nil, err
+		}
+		return terminal.NewCharacter(value), nil
+	case RangeTerminal:
+		interval, err := parseInterval(Interval{Min: t.Min, Max: t.Max})
+		if err != nil {
+			return nil, err
+		}
+		return terminal.NewRange(interval.Min, interval.Max), nil
+	case RangeSetTerminal:
+		var intervals []terminal.Interval
+		for _, i := range t.Intervals {
+			interval, err := parseInterval(i)
+			if err != nil {
+				return nil, err
+			}
+			intervals = append(intervals, interval)
+		}
+		return terminal.NewRangeSet(terminal.Normalize(intervals)), nil
+	case SetTerminal:
+		var terminals []grammar.Terminal
+		for _, child := range t.Terminals {
+			c, err := child.terminal()
+			if err != nil {
+				return nil, err
+			}
+			terminals = append(terminals, c)
+		}
+		return terminal.NewSet(terminals), nil
+	case NegateTerminal:
+		if t.Terminal == nil {
+			return nil, fmt.Errorf("negate terminal without a terminal")
+		}
+		c, err := t.Terminal.terminal()
+		if err != nil {
+			return nil, err
+		}
+		return terminal.NewNegate(c), nil
+	case LetterTerminal:
+		return terminal.NewLetter(), nil
+	case NumberTerminal:
+		return terminal.NewNumber(), nil
+	case WhitespaceTerminal:
+		return terminal.NewWhitespace(), nil
+	}
+	return nil, fmt.Errorf("unsupported terminal type %s", t.Type)
+}
+
+func parseInterval(i Interval) (terminal.Interval, error) {
+	min, err := parseCharacter(i.Min)
+	if err != nil {
+		return terminal.Interval{}, err
+	}
+	max, err := parseCharacter(i.Max)
+	if err != nil {
+		return terminal.Interval{}, err
+	}
+	if min > max {
+		return terminal.Interval{}, fmt.Errorf("invalid range %q-%q", i.Min, i.Max)
+	}
+	return terminal.Interval{Min: min, Max: max}, nil
+}
+
+// parseCharacter returns the character of a string with exactly one character
+func parseCharacter(value string) (rune, error) {
+	ch, size := utf8.DecodeRuneInString(value)
+	if value == "" || size != len(value) || (ch == utf8.RuneError && size == 1) {
+		return 0, fmt.Errorf("%q is not a single character", value)
+	}
+	return ch, nil
+}
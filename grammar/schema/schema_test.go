@@ -0,0 +1,258 @@
+package schema_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/grammar/schema"
+	"github.com/patrickhuber/go-earley/parser"
+	"github.com/patrickhuber/go-earley/pdl"
+	"github.com/patrickhuber/go-earley/scanner"
+	"github.com/patrickhuber/go-earley/terminal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		B := grammar.NewNonTerminal("B")
+		g := grammar.New(S,
+			grammar.NewProduction(S, grammar.NewStringLexerRule("a"), B),
+			grammar.NewProduction(B, grammar.NewTerminalLexerRule(terminal.NewRange('b', 'd'))),
+			grammar.NewProduction(B, grammar.NewStringLexerRule("a")),
+		)
+		g.Productions[1].Associativity = grammar.LeftAssociative
+		data, err := schema.MarshalJSON(g)
+		require.NoError(t, err)
+		require.Equal(t, `{
+  "version": 1,
+  "start": "S",
+  "nonTerminals": [
+    {
+      "name": "S"
+    },
+    {
+      "name": "B"
+    }
+  ],
+  "lexerRules": [
+    {
+      "name": "a",
+      "type": "string",
+      "value": "a"
+    },
+    {
+      "name": "b-d",
+      "type": "terminal",
+      "terminal": {
+        "type": "range",
+        "min": "b",
+        "max": "d"
+      }
+    }
+  ],
+  "productions": [
+    {
+      "lhs": "S",
+      "rhs": [
+        "a",
+        "B"
+      ]
+    },
+    {
+      "lhs": "B",
+      "rhs": [
+        "b-d"
+      ],
+      "associativity": "left"
+    },
+    {
+      "lhs": "B",
+      "rhs": [
+        "a"
+      ]
+    }
+  ]
+}`, string(data))
+	})
+	t.Run("round trip", func(t *testing.T) {
+		g := compile(t, `
+			Calculator = Expression;
+			Expression = Expression '+' Term @left | Term @priority(1);
+			Term = Term '*' Factor @right | Factor @prefer;
+			Factor = Number @notfollowedby('.') | '(' Expression ')' | { '-' } Factor @avoid | 'x' @reject;
+			Number ~ /[0-9]+/;
+			Whitespace ~ /[\s]+/;
+			:ignore = Whitespace;`)
+
+		for _, format := range []struct {
+			name      string
+			marshal   func(*grammar.Grammar) ([]byte, error)
+			unmarshal func([]byte) (*grammar.Grammar, error)
+		}{
+			{"json", schema.MarshalJSON, schema.UnmarshalJSON},
+			{"yaml", schema.MarshalYAML, schema.UnmarshalYAML},
+		} {
+			t.Run(format.name, func(t *testing.T) {
+				data, err := format.marshal(g)
+				require.NoError(t, err)
+				loaded, err := format.unmarshal(data)
+				require.NoError(t, err)
+
+				again, err := format.marshal(loaded)
+				require.NoError(t, err)
+				require.Equal(t, string(data), string(again))
+
+				expected, err := pdl.Format(g)
+				require.NoError(t, err)
+				actual, err := pdl.Format(loaded)
+				require.NoError(t, err)
+				require.Equal(t, string(expected), string(actual))
+
+				accepted, err := scanner.RunToEnd(scanner.New(parser.New(loaded), " 1 + 22\n* (--333) "))
+				require.NoError(t, err)
+				require.True(t, accepted)
+			})
+		}
+	})
+	t.Run("terminals", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		var rhs []grammar.Symbol
+		for _, terminal := range []grammar.Terminal{
+			terminal.NewAny(),
+			terminal.NewCharacter('c'),
+			terminal.NewRange('l', 'n'),
+			terminal.NewRangeSet([]terminal.Interval{{Min: 0, Max: 'a'}, {Min: 'x', Max: '\U0010FFFF'}}),
+			terminal.NewSet([]grammar.Terminal{terminal.NewNumber(), terminal.NewCharacter('x')}),
+			terminal.NewNegate(terminal.NewLetter()),
+			terminal.NewLetter(),
+			terminal.NewNumber(),
+			terminal.NewWhitespace(),
+		} {
+			rhs = append(rhs, grammar.NewTerminalLexerRule(terminal))
+		}
+		g := grammar.New(S, grammar.NewProduction(S, rhs...))
+
+		data, err := schema.MarshalYAML(g)
+		require.NoError(t, err)
+		loaded, err := schema.UnmarshalYAML(data)
+		require.NoError(t, err)
+		again, err := schema.MarshalYAML(loaded)
+		require.NoError(t, err)
+		require.Equal(t, string(data), string(again))
+
+		for _, input := range []string{"écm\x001#Q7 ", "?cn\U0010FFFFx.a0\t"} {
+			accepted, err := scanner.RunToEnd(scanner.New(parser.New(loaded), input))
+			require.NoError(t, err, input)
+			require.True(t, accepted, input)
+		}
+		accepted, _ := scanner.RunToEnd(scanner.New(parser.New(loaded), "écmb1#Q7 "))
+		require.False(t, accepted)
+	})
+	t.Run("names", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		a := grammar.NewNonTerminal("a")
+		g := grammar.New(S,
+			grammar.NewProduction(S, grammar.NewStringLexerRule("a"), a, grammar.NewStringLexerRule("a")),
+			grammar.NewProduction(a, grammar.NewTerminalLexerRule(terminal.NewCharacter('a'))),
+		)
+		s, err := schema.New(g)
+		require.NoError(t, err)
+		require.Equal(t, []string{"a#2", "a", "a#2"}, s.Productions[0].RightHandSide)
+		require.Equal(t, []string{"a#3"}, s.Productions[1].RightHandSide)
+		require.Len(t, s.LexerRules, 2)
+	})
+	t.Run("dfa", func(t *testing.T) {
+		loaded, err := schema.UnmarshalYAML([]byte(`
+version: 1
+start: List
+nonTerminals:
+  - name: List
+lexerRules:
+  - name: Number
+    type: dfa
+    states:
+      - transitions:
+          - terminal: { type: range, min: "0", max: "9" }
+            target: 1
+      - final: true
+        transitions:
+          - terminal: { type: range, min: "0", max: "9" }
+            target: 1
+  - name: ","
+    type: string
+    value: ","
+productions:
+  - lhs: List
+    rhs: [Number]
+  - lhs: List
+    rhs: [List, ",", Number]
+`))
+		require.NoError(t, err)
+		for _, input := range []string{"1", "12,3,456"} {
+			accepted, err := scanner.RunToEnd(scanner.New(parser.New(loaded), input))
+			require.NoError(t, err, input)
+			require.True(t, accepted, input)
+		}
+		require.Equal(t, "Number", loaded.Productions[0].RightHandSide[0].(grammar.LexerRule).TokenType())
+	})
+	t.Run("pattern", func(t *testing.T) {
+		g := compile(t, `
+			List = Word | List ',' Word;
+			Word ~ /\w+/;
+			Whitespace ~ /\s+/;
+			:ignore = Whitespace;`)
+
+		data, err := schema.MarshalJSON(g)
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"pattern": "\\w+"`)
+		require.Less(t, len(data), 2048)
+		loaded, err := schema.UnmarshalJSON(data)
+		require.NoError(t, err)
+
+		yaml, err := schema.MarshalYAML(g)
+		require.NoError(t, err)
+		require.Less(t, len(yaml), 1024)
+
+		for _, input := range []string{"a", "héllo, wörld_42 , x"} {
+			accepted, err := scanner.RunToEnd(scanner.New(parser.New(loaded), input))
+			require.NoError(t, err, input)
+			require.True(t, accepted, input)
+		}
+		accepted, _ := scanner.RunToEnd(scanner.New(parser.New(loaded), "a-b"))
+		require.False(t, accepted)
+		require.Equal(t, "Word", loaded.Productions[0].RightHandSide[0].(grammar.LexerRule).TokenType())
+	})
+	t.Run("errors", func(t *testing.T) {
+		for _, test := range []struct {
+			name  string
+			input string
+			err   string
+		}{
+			{"version", `{"version": 2, "start": "S"}`, "unsupported grammar schema version 2, expected 1"},
+			{"start", `{"version": 1, "start": "S"}`, "start: undefined symbol S"},
+			{"duplicate", `{"version": 1, "start": "S", "nonTerminals": [{"name": "S"}], "lexerRules": [{"name": "S", "type": "string", "value": "s"}]}`, "more than one symbol is named S"},
+			{"undefined", `{"version": 1, "start": "S", "nonTerminals": [{"name": "S"}], "productions": [{"lhs": "S", "rhs": ["A"]}]}`, "production 0: undefined symbol A"},
+			{"lexer rule lhs", `{"version": 1, "start": "S", "nonTerminals": [{"name": "S"}], "lexerRules": [{"name": "a", "type": "string", "value": "a"}], "productions": [{"lhs": "a", "rhs": []}]}`, "production 0: a is not a nonterminal"},
+			{"target", `{"version": 1, "start": "S", "lexerRules": [{"name": "a", "type": "dfa", "states": [{"transitions": [{"terminal": {"type": "any"}, "target": 1}]}]}]}`, "lexer rule a: state 0 has a transition to undefined state 1"},
+			{"pattern and states", `{"version": 1, "start": "S", "lexerRules": [{"name": "a", "type": "dfa", "pattern": "a", "states": [{"final": true}]}]}`, "lexer rule a: dfa with both a pattern and states"},
+			{"pattern", `{"version": 1, "start": "S", "lexerRules": [{"name": "a", "type": "dfa", "pattern": "a("}]}`, `lexer rule a: invalid pattern "a(": unexpected end of input, expected ( or . or [^[^.$()[]+*?\/|]] or \ or [ at 1:3`},
+			{"character", `{"version": 1, "start": "S", "lexerRules": [{"name": "a", "type": "terminal", "terminal": {"type": "character", "value": "ab"}}]}`, `lexer rule a: "ab" is not a single character`},
+			{"terminal", `{"version": 1, "start": "S", "lexerRules": [{"name": "a", "type": "terminal", "terminal": {"type": "digit"}}]}`, "lexer rule a: unsupported terminal type digit"},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				_, err := schema.UnmarshalJSON([]byte(test.input))
+				require.EqualError(t, err, test.err)
+			})
+		}
+	})
+}
+
+func compile(t *testing.T, input string) *grammar.Grammar {
+	definition, err := pdl.Parse(strings.NewReader(input))
+	require.NoError(t, err)
+	g, err := pdl.Compile(definition)
+	require.NoError(t, err)
+	return g
+}
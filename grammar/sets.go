@@ -0,0 +1,232 @@
+package grammar
+
+// CharacterSet is the set of characters a token of one of its lexer rules can start with
+type CharacterSet struct {
+	// runes holds the first character of each string lexer rule
+	runes map[rune]struct{}
+	// lexerRules holds the other lexer rules, they are checked with CanApply
+	lexerRules []LexerRule
+}
+
+func newCharacterSet(lexerRules []LexerRule) *CharacterSet {
+	c := &CharacterSet{
+		runes: map[rune]struct{}{},
+	}
+	for _, lexerRule := range lexerRules {
+		s, ok := lexerRule.(*StringLexerRule)
+		if !ok {
+			c.lexerRules = append(c.lexerRules, lexerRule)
+			continue
+		}
+		for _, r := range s.Value {
+			c.runes[r] = struct{}{}
+			break
+		}
+	}
+	return c
+}
+
+// Contains returns true if a token can start with the character
+func (c *CharacterSet) Contains(ch rune) bool {
+	if _, ok := c.runes[ch]; ok {
+		return true
+	}
+	for _, lexerRule := range c.lexerRules {
+		if lexerRule.CanApply(ch) {
+			return true
+		}
+	}
+	return false
+}
+
+// lexerRuleSet is a set of lexer rules that keeps the order they were added in
+type lexerRuleSet struct {
+	lexerRules []LexerRule
+	index      map[LexerRule]struct{}
+}
+
+func (s *lexerRuleSet) add(lexerRules ...LexerRule) bool {
+	if s.index == nil {
+		s.index = map[LexerRule]struct{}{}
+	}
+	changed := false
+	for _, lexerRule := range lexerRules {
+		if _, ok := s.index[lexerRule]; ok {
+			continue
+		}
+		s.index[lexerRule] = struct{}{}
+		s.lexerRules = append(s.lexerRules, lexerRule)
+		changed = true
+	}
+	return changed
+}
+
+// sets holds the FIRST, LAST and FOLLOW sets of the nonterminals
+type sets struct {
+	first  map[NonTerminal]*lexerRuleSet
+	last   map[NonTerminal]*lexerRuleSet
+	follow map[NonTerminal]*lexerRuleSet
+	// end holds the nonterminals that can end the input
+	end        map[NonTerminal]struct{}
+	characters map[NonTerminal]*CharacterSet
+}
+
+func identifySets(g *Grammar) *sets {
+	s := &sets{
+		first:      map[NonTerminal]*lexerRuleSet{},
+		last:       map[NonTerminal]*lexerRuleSet{},
+		follow:     map[NonTerminal]*lexerRuleSet{},
+		end:        map[NonTerminal]struct{}{},
+		characters: map[NonTerminal]*CharacterSet{},
+	}
+	get := func(m map[NonTerminal]*lexerRuleSet, nt NonTerminal) *lexerRuleSet {
+		set, ok := m[nt]
+		if !ok {
+			set = &lexerRuleSet{}
+			m[nt] = set
+		}
+		return set
+	}
+
+	// FIRST and LAST grow until no production adds a lexer rule
+	for changed := true; changed; {
+		changed = false
+		for _, p := range g.Productions {
+			first := get(s.first, p.LeftHandSide)
+			last := get(s.last, p.LeftHandSide)
+			changed = first.add(g.sequenceFirst(s, p.RightHandSide)...) || changed
+			changed = last.add(g.sequenceLast(s, p.RightHandSide)...) || changed
+		}
+	}
+
+	// FOLLOW of a symbol holds the FIRST of the symbols after it and, if they are nullable, the FOLLOW of the left hand side
+	s.end[g.Start] = struct{}{}
+	for changed := true; changed; {
+		changed = false
+		for _, p := range g.Productions {
+			for i, symbol := range p.RightHandSide {
+				nt, ok := symbol.(NonTerminal)
+				if !ok {
+					continue
+				}
+				follow := get(s.follow, nt)
+				rest := p.RightHandSide[i+1:]
+				changed = follow.add(g.sequenceFirst(s, rest)...) || changed
+				if !g.isNullableSequence(rest) {
+					continue
+				}
+				changed = follow.add(get(s.follow, p.LeftHandSide).lexerRules...) || changed
+				if _, ok := s.end[p.LeftHandSide]; !ok {
+					continue
+				}
+				if _, ok := s.end[nt]; !ok {
+					s.end[nt] = struct{}{}
+					changed = true
+				}
+			}
+		}
+	}
+
+	for nt, first := range s.first {
+		s.characters[nt] = newCharacterSet(first.lexerRules)
+	}
+	return s
+}
+
+// sequenceFirst returns the lexer rules that can start the symbols
+func (g *Grammar) sequenceFirst(s *sets, symbols []Symbol) []LexerRule {
+	var lexerRules []LexerRule
+	for _, symbol := range symbols {
+		switch sym := symbol.(type) {
+		case LexerRule:
+			return append(lexerRules, sym)
+		case NonTerminal:
+			if first, ok := s.first[sym]; ok {
+				lexerRules = append(lexerRules, first.lexerRules...)
+			}
+			if !g.IsTransativeNullable(sym) {
+				return lexerRules
+			}
+		}
+	}
+	return lexerRules
+}
+
+// sequenceLast returns the lexer rules that can end the symbols
+func (g *Grammar) sequenceLast(s *sets, symbols []Symbol) []LexerRule {
+	var lexerRules []LexerRule
+	for i := len(symbols) - 1; i >= 0; i-- {
+		switch sym := symbols[i].(type) {
+		case LexerRule:
+			return append(lexerRules, sym)
+		case NonTerminal:
+			if last, ok := s.last[sym]; ok {
+				lexerRules = append(lexerRules, last.lexerRules...)
+			}
+			if !g.IsTransativeNullable(sym) {
+				return lexerRules
+			}
+		}
+	}
+	return lexerRules
+}
+
+func (g *Grammar) isNullableSequence(symbols []Symbol) bool {
+	for _, symbol := range symbols {
+		nt, ok := symbol.(NonTerminal)
+		if !ok || !g.IsTransativeNullable(nt) {
+			return false
+		}
+	}
+	return true
+}
+
+// First returns the lexer rules that can start the symbol, a lexer rule starts itself
+// The lexer rules are in the order they are found in the productions.
+func (g *Grammar) First(symbol Symbol) []LexerRule {
+	switch sym := symbol.(type) {
+	case LexerRule:
+		return []LexerRule{sym}
+	case NonTerminal:
+		if first, ok := g.sets.first[sym]; ok {
+			return first.lexerRules
+		}
+	}
+	return nil
+}
+
+// Last returns the lexer rules that can end the symbol, a lexer rule ends itself
+func (g *Grammar) Last(symbol Symbol) []LexerRule {
+	switch sym := symbol.(type) {
+	case LexerRule:
+		return []LexerRule{sym}
+	case NonTerminal:
+		if last, ok := g.sets.last[sym]; ok {
+			return last.lexerRules
+		}
+	}
+	return nil
+}
+
+// Follow returns the lexer rules that can follow the nonterminal
+// CanEndInput reports if the end of input can follow it.
+func (g *Grammar) Follow(nt NonTerminal) []LexerRule {
+	if follow, ok := g.sets.follow[nt]; ok {
+		return follow.lexerRules
+	}
+	return nil
+}
+
+// CanEndInput returns true if the end of input can follow the nonterminal
+func (g *Grammar) CanEndInput(nt NonTerminal) bool {
+	_, ok := g.sets.end[nt]
+	return ok
+}
+
+// FirstCharacters returns the characters a token of the FIRST set of the nonterminal can start with
+func (g *Grammar) FirstCharacters(nt NonTerminal) *CharacterSet {
+	if characters, ok := g.sets.characters[nt]; ok {
+		return characters
+	}
+	return newCharacterSet(nil)
+}
@@ -0,0 +1,90 @@
+package grammar_test
+
+import (
+	"testing"
+
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSets(t *testing.T) {
+	E := grammar.NewNonTerminal("E")
+	E_ := grammar.NewNonTerminal("E'")
+	T := grammar.NewNonTerminal("T")
+	T_ := grammar.NewNonTerminal("T'")
+	F := grammar.NewNonTerminal("F")
+	plus := grammar.NewStringLexerRule("+")
+	star := grammar.NewStringLexerRule("*")
+	open := grammar.NewStringLexerRule("(")
+	close := grammar.NewStringLexerRule(")")
+	id := grammar.NewStringLexerRule("id")
+
+	// E -> T E'
+	// E' -> '+' T E' | <null>
+	// T -> F T'
+	// T' -> '*' F T' | <null>
+	// F -> '(' E ')' | 'id'
+	g := grammar.New(E,
+		grammar.NewProduction(E, T, E_),
+		grammar.NewProduction(E_, plus, T, E_),
+		grammar.NewProduction(E_),
+		grammar.NewProduction(T, F, T_),
+		grammar.NewProduction(T_, star, F, T_),
+		grammar.NewProduction(T_),
+		grammar.NewProduction(F, open, E, close),
+		grammar.NewProduction(F, id),
+	)
+
+	tokenTypes := func(lexerRules []grammar.LexerRule) []string {
+		var tokenTypes []string
+		for _, lexerRule := range lexerRules {
+			tokenTypes = append(tokenTypes, lexerRule.TokenType())
+		}
+		return tokenTypes
+	}
+
+	t.Run("first", func(t *testing.T) {
+		require.Equal(t, []string{"(", "id"}, tokenTypes(g.First(E)))
+		require.Equal(t, []string{"(", "id"}, tokenTypes(g.First(T)))
+		require.Equal(t, []string{"+"}, tokenTypes(g.First(E_)))
+		require.Equal(t, []string{"*"}, tokenTypes(g.First(T_)))
+		require.Equal(t, []string{"id"}, tokenTypes(g.First(id)))
+	})
+	t.Run("last", func(t *testing.T) {
+		require.ElementsMatch(t, []string{")", "id"}, tokenTypes(g.Last(E)))
+		require.ElementsMatch(t, []string{")", "id"}, tokenTypes(g.Last(E_)))
+		require.ElementsMatch(t, []string{")", "id"}, tokenTypes(g.Last(T_)))
+	})
+	t.Run("follow", func(t *testing.T) {
+		require.ElementsMatch(t, []string{")"}, tokenTypes(g.Follow(E)))
+		require.ElementsMatch(t, []string{")"}, tokenTypes(g.Follow(E_)))
+		require.ElementsMatch(t, []string{"+", ")"}, tokenTypes(g.Follow(T)))
+		require.ElementsMatch(t, []string{"+", ")"}, tokenTypes(g.Follow(T_)))
+		require.ElementsMatch(t, []string{"*", "+", ")"}, tokenTypes(g.Follow(F)))
+	})
+	t.Run("end of input", func(t *testing.T) {
+		require.True(t, g.CanEndInput(E))
+		require.True(t, g.CanEndInput(T_))
+		require.True(t, g.CanEndInput(F))
+	})
+	t.Run("first characters", func(t *testing.T) {
+		characters := g.FirstCharacters(E)
+		require.True(t, characters.Contains('('))
+		require.True(t, characters.Contains('i'))
+		require.False(t, characters.Contains('+'))
+		require.False(t, g.FirstCharacters(E_).Contains('*'))
+	})
+	t.Run("not at end of input", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		A := grammar.NewNonTerminal("A")
+		a := grammar.NewStringLexerRule("a")
+		// S -> A 'a', A -> 'a'
+		g := grammar.New(S,
+			grammar.NewProduction(S, A, a),
+			grammar.NewProduction(A, a),
+		)
+		require.True(t, g.CanEndInput(S))
+		require.False(t, g.CanEndInput(A))
+		require.Equal(t, []string{"a"}, tokenTypes(g.Follow(A)))
+	})
+}
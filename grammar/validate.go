@@ -0,0 +1,343 @@
+package grammar
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiagnosticKind is the kind of problem a Diagnostic reports
+type DiagnosticKind int
+
+const (
+	// UndefinedNonTerminal is a nonterminal used in a production or as the start symbol without productions of its own
+	UndefinedNonTerminal DiagnosticKind = iota
+	// UnreachableNonTerminal is a nonterminal with productions that no derivation of the start symbol uses
+	UnreachableNonTerminal
+	// NonProductiveNonTerminal is a nonterminal that derives no string of lexer rules
+	NonProductiveNonTerminal
+	// DuplicateProduction is a production with the same left and right hand side as an earlier production
+	DuplicateProduction
+	// UnitCycle is a set of nonterminals that derive each other through unit productions, so a parse can have infinitely many trees
+	UnitCycle
+)
+
+func (k DiagnosticKind) String() string {
+	switch k {
+	case UndefinedNonTerminal:
+		return "undefined nonterminal"
+	case UnreachableNonTerminal:
+		return "unreachable nonterminal"
+	case NonProductiveNonTerminal:
+		return "non-productive nonterminal"
+	case DuplicateProduction:
+		return "duplicate production"
+	case UnitCycle:
+		return "unit production cycle"
+	}
+	return fmt.Sprintf("DiagnosticKind(%d)", int(k))
+}
+
+// Diagnostic is a problem found by Validate
+type Diagnostic struct {
+	Kind DiagnosticKind
+	// NonTerminal is the nonterminal the diagnostic is about, the first nonterminal of the cycle for a UnitCycle
+	NonTerminal NonTerminal
+	// Productions holds the duplicate production or the unit productions of the cycle
+	Productions []*Production
+}
+
+func (d Diagnostic) String() string {
+	switch d.Kind {
+	case DuplicateProduction:
+		return fmt.Sprintf("%s: %s", d.Kind, d.Productions[0])
+	case UnitCycle:
+		var productions []string
+		for _, production := range d.Productions {
+			productions = append(productions, production.String())
+		}
+		return fmt.Sprintf("%s: %s", d.Kind, strings.Join(productions, ", "))
+	}
+	return fmt.Sprintf("%s: %s", d.Kind, d.NonTerminal.Name())
+}
+
+// ValidationError is returned by NewChecked when the grammar has diagnostics
+type ValidationError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *ValidationError) Error() string {
+	var diagnostics []string
+	for _, diagnostic := range e.Diagnostics {
+		diagnostics = append(diagnostics, diagnostic.String())
+	}
+	return fmt.Sprintf("invalid grammar: %s", strings.Join(diagnostics, "; "))
+}
+
+// NewChecked creates the grammar like New and returns a ValidationError if Validate reports any diagnostics
+func NewChecked(start NonTerminal, productions ...*Production) (*Grammar, error) {
+	g := New(start, productions...)
+	if diagnostics := Validate(g); len(diagnostics) > 0 {
+		return nil, &ValidationError{Diagnostics: diagnostics}
+	}
+	return g, nil
+}
+
+// Validate reports undefined, unreachable and non-productive nonterminals, duplicate productions and unit production cycles
+// The diagnostics are ordered by kind and then by the order the nonterminals and productions appear in the grammar.
+func Validate(g *Grammar) []Diagnostic {
+	v := newValidator(g)
+	var diagnostics []Diagnostic
+	diagnostics = append(diagnostics, v.undefined()...)
+	diagnostics = append(diagnostics, v.unreachable()...)
+	diagnostics = append(diagnostics, v.nonProductive()...)
+	diagnostics = append(diagnostics, v.duplicates()...)
+	diagnostics = append(diagnostics, v.cycles()...)
+	return diagnostics
+}
+
+type validator struct {
+	grammar *Grammar
+	// nonTerminals holds every nonterminal of the grammar in the order it first appears
+	nonTerminals []NonTerminal
+	productions  map[NonTerminal][]*Production
+}
+
+func newValidator(g *Grammar) *validator {
+	v := &validator{
+		grammar:     g,
+		productions: map[NonTerminal][]*Production{},
+	}
+	seen := map[NonTerminal]struct{}{}
+	add := func(nt NonTerminal) {
+		if _, ok := seen[nt]; ok {
+			return
+		}
+		seen[nt] = struct{}{}
+		v.nonTerminals = append(v.nonTerminals, nt)
+	}
+	add(g.Start)
+	for _, p := range g.Productions {
+		add(p.LeftHandSide)
+		v.productions[p.LeftHandSide] = append(v.productions[p.LeftHandSide], p)
+		for _, s := range p.RightHandSide {
+			if nt, ok := s.(NonTerminal); ok {
+				add(nt)
+			}
+		}
+	}
+	return v
+}
+
+func (v *validator) defined(nt NonTerminal) bool {
+	return len(v.productions[nt]) > 0
+}
+
+func (v *validator) undefined() []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, nt := range v.nonTerminals {
+		if !v.defined(nt) {
+			diagnostics = append(diagnostics, Diagnostic{Kind: UndefinedNonTerminal, NonTerminal: nt})
+		}
+	}
+	return diagnostics
+}
+
+func (v *validator) unreachable() []Diagnostic {
+	reachable := map[NonTerminal]struct{}{
+		v.grammar.Start: {},
+	}
+	work := []NonTerminal{v.grammar.Start}
+	for len(work) > 0 {
+		var nt NonTerminal
+		work, nt = dequeue(work)
+		for _, p := range v.productions[nt] {
+			for _, s := range p.RightHandSide {
+				next, ok := s.(NonTerminal)
+				if !ok {
+					continue
+				}
+				if _, ok := reachable[next]; ok {
+					continue
+				}
+				reachable[next] = struct{}{}
+				work = enqueue(work, next)
+			}
+		}
+	}
+
+	var diagnostics []Diagnostic
+	for _, nt := range v.nonTerminals {
+		if _, ok := reachable[nt]; ok || !v.defined(nt) {
+			continue
+		}
+		diagnostics = append(diagnostics, Diagnostic{Kind: UnreachableNonTerminal, NonTerminal: nt})
+	}
+	return diagnostics
+}
+
+// nonProductive reports defined nonterminals that derive no string of lexer rules, undefined nonterminals are already reported
+func (v *validator) nonProductive() []Diagnostic {
+	productive := map[NonTerminal]struct{}{}
+	for changed := true; changed; {
+		changed = false
+		for _, p := range v.grammar.Productions {
+			if _, ok := productive[p.LeftHandSide]; ok {
+				continue
+			}
+			if v.derives(p, productive) {
+				productive[p.LeftHandSide] = struct{}{}
+				changed = true
+			}
+		}
+	}
+
+	var diagnostics []Diagnostic
+	for _, nt := range v.nonTerminals {
+		if _, ok := productive[nt]; ok || !v.defined(nt) {
+			continue
+		}
+		diagnostics = append(diagnostics, Diagnostic{Kind: NonProductiveNonTerminal, NonTerminal: nt})
+	}
+	return diagnostics
+}
+
+// derives returns true if every nonterminal on the right hand side of the production is productive
+func (v *validator) derives(p *Production, productive map[NonTerminal]struct{}) bool {
+	for _, s := range p.RightHandSide {
+		nt, ok := s.(NonTerminal)
+		if !ok {
+			continue
+		}
+		if _, ok := productive[nt]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (v *validator) duplicates() []Diagnostic {
+	var diagnostics []Diagnostic
+	for i, p := range v.grammar.Productions {
+		for _, previous := range v.grammar.Productions[:i] {
+			if sameProduction(previous, p) {
+				diagnostics = append(diagnostics, Diagnostic{
+					Kind:        DuplicateProduction,
+					NonTerminal: p.LeftHandSide,
+					Productions: []*Production{p},
+				})
+				break
+			}
+		}
+	}
+	return diagnostics
+}
+
+func sameProduction(p, q *Production) bool {
+	if p.LeftHandSide != q.LeftHandSide || len(p.RightHandSide) != len(q.RightHandSide) {
+		return false
+	}
+	for i := range p.RightHandSide {
+		if !sameSymbol(p.RightHandSide[i], q.RightHandSide[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameSymbol compares nonterminals by identity and lexer rules by type, so two string lexer rules for the same text are the same
+func sameSymbol(s, t Symbol) bool {
+	if s == t {
+		return true
+	}
+	l, ok := s.(LexerRule)
+	if !ok {
+		return false
+	}
+	r, ok := t.(LexerRule)
+	if !ok {
+		return false
+	}
+	return l.LexerRuleType() == r.LexerRuleType() && l.TokenType() == r.TokenType()
+}
+
+// cycles reports each set of nonterminals that derive themselves through unit productions
+// a production is a unit production of a nonterminal on its right hand side if the other symbols are nullable
+func (v *validator) cycles() []Diagnostic {
+	units := map[NonTerminal][]*Production{}
+	for _, p := range v.grammar.Productions {
+		for i, s := range p.RightHandSide {
+			if _, ok := s.(NonTerminal); !ok || !v.nullableExcept(p, i) {
+				continue
+			}
+			units[p.LeftHandSide] = append(units[p.LeftHandSide], p)
+			break
+		}
+	}
+
+	reported := map[NonTerminal]struct{}{}
+	var diagnostics []Diagnostic
+	for _, nt := range v.nonTerminals {
+		if _, ok := reported[nt]; ok {
+			continue
+		}
+		cycle := v.cycle(nt, units)
+		if len(cycle) == 0 {
+			continue
+		}
+		for _, p := range cycle {
+			reported[p.LeftHandSide] = struct{}{}
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Kind:        UnitCycle,
+			NonTerminal: nt,
+			Productions: cycle,
+		})
+	}
+	return diagnostics
+}
+
+// cycle returns the unit productions of the shortest cycle from the nonterminal back to itself
+func (v *validator) cycle(start NonTerminal, units map[NonTerminal][]*Production) []*Production {
+	// breadth first search so the reported cycle is the shortest one
+	previous := map[NonTerminal]*Production{}
+	work := []NonTerminal{start}
+	for len(work) > 0 {
+		var nt NonTerminal
+		work, nt = dequeue(work)
+		for _, p := range units[nt] {
+			for i, s := range p.RightHandSide {
+				next, ok := s.(NonTerminal)
+				if !ok || !v.nullableExcept(p, i) {
+					continue
+				}
+				if next == start {
+					cycle := []*Production{p}
+					for at := nt; at != start; at = previous[at].LeftHandSide {
+						cycle = append([]*Production{previous[at]}, cycle...)
+					}
+					return cycle
+				}
+				if _, ok := previous[next]; ok {
+					continue
+				}
+				previous[next] = p
+				work = enqueue(work, next)
+			}
+		}
+	}
+	return nil
+}
+
+// nullableExcept returns true if every symbol on the right hand side of the production other than the one at the index is nullable
+func (v *validator) nullableExcept(p *Production, index int) bool {
+	for i, s := range p.RightHandSide {
+		if i == index {
+			continue
+		}
+		nt, ok := s.(NonTerminal)
+		if !ok || !v.grammar.IsTransativeNullable(nt) {
+			return false
+		}
+	}
+	return true
+}
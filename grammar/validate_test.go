@@ -0,0 +1,125 @@
+package grammar_test
+
+import (
+	"testing"
+
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	S := grammar.NewNonTerminal("S")
+	A := grammar.NewNonTerminal("A")
+	B := grammar.NewNonTerminal("B")
+	C := grammar.NewNonTerminal("C")
+	a := grammar.NewStringLexerRule("a")
+
+	messages := func(diagnostics []grammar.Diagnostic) []string {
+		var messages []string
+		for _, diagnostic := range diagnostics {
+			messages = append(messages, diagnostic.String())
+		}
+		return messages
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		// S -> A S | <null>, A -> 'a'
+		g := grammar.New(S,
+			grammar.NewProduction(S, A, S),
+			grammar.NewProduction(S),
+			grammar.NewProduction(A, a),
+		)
+		require.Empty(t, grammar.Validate(g))
+	})
+	t.Run("undefined", func(t *testing.T) {
+		// S -> A, A is never defined
+		g := grammar.New(S,
+			grammar.NewProduction(S, A),
+		)
+		diagnostics := grammar.Validate(g)
+		require.Len(t, diagnostics, 2)
+		require.Equal(t, grammar.UndefinedNonTerminal, diagnostics[0].Kind)
+		require.Equal(t, A, diagnostics[0].NonTerminal)
+		require.Equal(t, []string{
+			"undefined nonterminal: A",
+			"non-productive nonterminal: S",
+		}, messages(diagnostics))
+	})
+	t.Run("unreachable", func(t *testing.T) {
+		// S -> 'a', A -> B, B -> 'a'
+		g := grammar.New(S,
+			grammar.NewProduction(S, a),
+			grammar.NewProduction(A, B),
+			grammar.NewProduction(B, a),
+		)
+		require.Equal(t, []string{
+			"unreachable nonterminal: A",
+			"unreachable nonterminal: B",
+		}, messages(grammar.Validate(g)))
+	})
+	t.Run("non-productive", func(t *testing.T) {
+		// S -> A | 'a', A -> 'a' A
+		g := grammar.New(S,
+			grammar.NewProduction(S, A),
+			grammar.NewProduction(S, a),
+			grammar.NewProduction(A, a, A),
+		)
+		require.Equal(t, []string{
+			"non-productive nonterminal: A",
+		}, messages(grammar.Validate(g)))
+	})
+	t.Run("duplicate", func(t *testing.T) {
+		// S -> 'a' | 'a' with distinct lexer rules for the same text
+		duplicate := grammar.NewProduction(S, grammar.NewStringLexerRule("a"))
+		g := grammar.New(S,
+			grammar.NewProduction(S, a),
+			duplicate,
+		)
+		diagnostics := grammar.Validate(g)
+		require.Len(t, diagnostics, 1)
+		require.Equal(t, grammar.DuplicateProduction, diagnostics[0].Kind)
+		require.Equal(t, []*grammar.Production{duplicate}, diagnostics[0].Productions)
+		require.Equal(t, "duplicate production: S -> a", diagnostics[0].String())
+	})
+	t.Run("unit cycle", func(t *testing.T) {
+		// S -> A, A -> B | 'a', B -> C A, C -> <null>
+		g := grammar.New(S,
+			grammar.NewProduction(S, A),
+			grammar.NewProduction(A, B),
+			grammar.NewProduction(A, a),
+			grammar.NewProduction(B, C, A),
+			grammar.NewProduction(C),
+		)
+		diagnostics := grammar.Validate(g)
+		require.Len(t, diagnostics, 1)
+		require.Equal(t, grammar.UnitCycle, diagnostics[0].Kind)
+		require.Equal(t, A, diagnostics[0].NonTerminal)
+		require.Equal(t, "unit production cycle: A -> B, B -> C A", diagnostics[0].String())
+	})
+	t.Run("self cycle", func(t *testing.T) {
+		// S -> S | 'a'
+		g := grammar.New(S,
+			grammar.NewProduction(S, S),
+			grammar.NewProduction(S, a),
+		)
+		require.Equal(t, []string{
+			"unit production cycle: S -> S",
+		}, messages(grammar.Validate(g)))
+	})
+}
+
+func TestNewChecked(t *testing.T) {
+	S := grammar.NewNonTerminal("S")
+	A := grammar.NewNonTerminal("A")
+	a := grammar.NewStringLexerRule("a")
+
+	g, err := grammar.NewChecked(S, grammar.NewProduction(S, a))
+	require.NoError(t, err)
+	require.NotNil(t, g)
+
+	_, err = grammar.NewChecked(S, grammar.NewProduction(S, A))
+	var validationError *grammar.ValidationError
+	require.ErrorAs(t, err, &validationError)
+	require.Len(t, validationError.Diagnostics, 2)
+	require.Equal(t, "invalid grammar: undefined nonterminal: A; non-productive nonterminal: S", err.Error())
+}
@@ -19,11 +19,19 @@ type Transition struct {
 	// Symbol is the transition symbol
 	Symbol grammar.Symbol
 
-	next *Transition
+	// Rule is the dotted rule after the predict item, its production is the right recursive production
+	Rule *grammar.DottedRule
 
-	Predict forest.Node
+	// Suffix holds the dotted rules after Rule up to the complete rule when the production ends in nulling symbols
+	Suffix []*grammar.DottedRule
+
+	// Nulls holds the nulling symbols of the suffixes of this transition and its parents
+	Nulls []grammar.Symbol
 
-	Root int
+	// parent is the transition the item was cloned from
+	parent *Transition
+
+	Predict forest.Node
 }
 
 func (*Transition) Type() Type { return TransitionType }
@@ -33,12 +41,26 @@ func (t *Transition) String() string {
 		t.Symbol, t.DottedRule, t.Origin)
 }
 
-func (t *Transition) Next() forest.Path {
-	return t.next
+// Parent returns the transition the item was cloned from or nil for the top most transition
+func (t *Transition) Parent() forest.Path {
+	// return a nil interface instead of a nil *Transition
+	if t.parent == nil {
+		return nil
+	}
+	return t.parent
+}
+
+func (t *Transition) SetParent(parent *Transition) {
+	t.parent = parent
+}
+
+func (t *Transition) Production() *grammar.Production {
+	return t.Rule.Production
 }
 
-func (t *Transition) SetNext(next *Transition) {
-	t.next = next
+// Rules returns Rule followed by the Suffix rules
+func (t *Transition) Rules() []*grammar.DottedRule {
+	return append([]*grammar.DottedRule{t.Rule}, t.Suffix...)
 }
 
 func (t *Transition) Node() forest.Node {
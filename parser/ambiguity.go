@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/patrickhuber/go-earley/forest"
+)
+
+// AmbiguityMode sets how the parser handles an accepted parse with more than one derivation
+type AmbiguityMode int
+
+const (
+	// AllowAmbiguity keeps every derivation in the forest without analysis, this is the default
+	AllowAmbiguity AmbiguityMode = iota
+	// WarnAmbiguity analyzes the accepted parse and reports the ambiguous nodes through Ambiguities
+	WarnAmbiguity
+	// RejectAmbiguity analyzes the accepted parse and does not accept it if it is ambiguous
+	// scanner.RunToEnd returns an AmbiguityError in that case
+	RejectAmbiguity
+)
+
+// Ambiguity sets the ambiguity mode
+// the default is AllowAmbiguity
+func Ambiguity(mode AmbiguityMode) Option {
+	return func(p *parser) {
+		p.ambiguity = mode
+	}
+}
+
+// AmbiguityError is returned when RejectAmbiguity is set and the parse is ambiguous
+type AmbiguityError struct {
+	Ambiguities []forest.Ambiguity
+}
+
+func (e *AmbiguityError) Error() string {
+	var ambiguities []string
+	for _, ambiguity := range e.Ambiguities {
+		ambiguities = append(ambiguities, ambiguity.String())
+	}
+	return fmt.Sprintf("ambiguous parse: %s", strings.Join(ambiguities, "; "))
+}
+
+// Ambiguities implements Parser.
+// It returns the ambiguous nodes of the parse accepted at the current location.
+// Nothing is returned when the ambiguity mode is AllowAmbiguity.
+func (p *parser) Ambiguities() []forest.Ambiguity {
+	if p.ambiguity == AllowAmbiguity {
+		return nil
+	}
+	if p.analyzed == p.location {
+		return p.ambiguities
+	}
+	p.analyzed = p.location
+	p.ambiguities = nil
+	if root, ok := p.root(); ok {
+		p.ambiguities = forest.Ambiguities(root)
+	}
+	return p.ambiguities
+}
+
+// rejected returns true if the accepted parse is rejected because it is ambiguous
+func (p *parser) rejected() bool {
+	return p.ambiguity == RejectAmbiguity && len(p.Ambiguities()) > 0
+}
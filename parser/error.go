@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/token"
+)
+
+// ParseError is a syntax error with its location in the input and what the parser expected at that location
+type ParseError struct {
+	// Position is the offset of the unexpected character or token
+	Position int
+	Line     int
+	Column   int
+	// Character is the unexpected character when no lexer rule matches it
+	Character rune
+	// Token is the unexpected token when the parser rejects it
+	Token token.Token
+	// EndOfInput is true when the input ends before the parser accepts
+	EndOfInput bool
+	// Expected holds the lexer rules expected at the location
+	Expected []grammar.LexerRule
+	// NonTerminals holds the nonterminals predicted at the location
+	NonTerminals []grammar.NonTerminal
+}
+
+// NewParseError returns a ParseError with the expected lexer rules and nonterminals at the current location of the parser
+func NewParseError(p Parser, position, line, column int) *ParseError {
+	return &ParseError{
+		Position:     position,
+		Line:         line,
+		Column:       column,
+		Expected:     p.Expected(),
+		NonTerminals: p.ExpectedNonTerminals(),
+	}
+}
+
+func (e *ParseError) Error() string {
+	var unexpected string
+	switch {
+	case e.EndOfInput:
+		unexpected = "end of input"
+	case e.Token != nil:
+		unexpected = e.Token.TokenType()
+		if capture, ok := e.Token.(*token.Capture); ok {
+			unexpected = capture.Text
+		}
+		unexpected = fmt.Sprintf("%q", unexpected)
+	default:
+		unexpected = fmt.Sprintf("character %q", e.Character)
+	}
+	return fmt.Sprintf("unexpected %s, expected %s at %d:%d", unexpected, Expectation(e.Expected), e.Line, e.Column)
+}
+
+// Expectation joins the distinct token types of the lexer rules with "or", string lexer rules are quoted
+func Expectation(lexerRules []grammar.LexerRule) string {
+	seen := map[string]struct{}{}
+	var names []string
+	for _, lexerRule := range lexerRules {
+		name := lexerRule.TokenType()
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		if _, ok := lexerRule.(*grammar.StringLexerRule); ok {
+			name = fmt.Sprintf("'%s'", name)
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return "end of input"
+	}
+	return strings.Join(names, " or ")
+}
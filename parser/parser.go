@@ -1,8 +1,6 @@
 package parser
 
 import (
-	"fmt"
-
 	"github.com/patrickhuber/go-earley/forest"
 	"github.com/patrickhuber/go-earley/grammar"
 	"github.com/patrickhuber/go-earley/internal/chart"
@@ -12,6 +10,11 @@ import (
 
 type Parser interface {
 	Expected() []grammar.LexerRule
+	ExpectedNonTerminals() []grammar.NonTerminal
+	Ignored() []grammar.LexerRule
+	Diagnostics() []*ParseError
+	Repair(err *ParseError) bool
+	Ambiguities() []forest.Ambiguity
 	Accepted() bool
 	Location() int
 	Pulse(tok ...token.Token) (bool, error)
@@ -24,6 +27,25 @@ type parser struct {
 	chart                  *chart.Chart
 	nodes                  *forest.Set
 	optimizeRightRecursion bool
+	tracer                 Tracer
+	recover                bool
+	recovery               []grammar.NonTerminal
+	// errors holds the complete rule of the error production of each recovery nonterminal
+	errors      map[grammar.NonTerminal]*grammar.DottedRule
+	diagnostics []*ParseError
+	resync      *resynchronization
+	// pulsed holds the token scanned at each location when recovery is enabled
+	pulsed      []token.Token
+	ambiguity   AmbiguityMode
+	ambiguities []forest.Ambiguity
+	// analyzed is the location of the cached ambiguities
+	analyzed     int
+	disambiguate bool
+	// filtered is the disambiguated root cached for the disambiguated location, nil if every derivation is removed
+	filtered      forest.Node
+	disambiguated int
+	// lookahead holds the token types pulsed at each location when the forest is disambiguated
+	lookahead map[int][]string
 }
 
 type Option func(*parser)
@@ -42,6 +64,11 @@ func New(g *grammar.Grammar, options ...Option) Parser {
 		chart:                  chart.New(),
 		nodes:                  &forest.Set{},
 		optimizeRightRecursion: true,
+		tracer:                 NopTracer(),
+		analyzed:               -1,
+		disambiguate:           g.Disambiguates(),
+		disambiguated:          -1,
+		lookahead:              map[int][]string{},
 	}
 	for _, option := range options {
 		option(p)
@@ -51,9 +78,8 @@ func New(g *grammar.Grammar, options ...Option) Parser {
 }
 
 func (p *parser) initialize() {
-	fmt.Printf("--------- %d ---------", p.location)
-	fmt.Println()
 	p.location = 0
+	p.tracer.Location(p.location)
 	p.chart = chart.New()
 	start := p.grammar.StartProductions()
 
@@ -61,8 +87,7 @@ func (p *parser) initialize() {
 		production := start[s]
 		state := p.newState(production, 0, 0)
 		p.chart.Enqueue(0, state)
-		fmt.Printf("%s : Init", state)
-		fmt.Println()
+		p.tracer.Init(state.DottedRule, state.Origin)
 	}
 	p.reductionPass(p.location)
 }
@@ -76,23 +101,51 @@ func (p *parser) newState(production *grammar.Production, position int, origin i
 }
 
 func (p *parser) Pulse(tok ...token.Token) (bool, error) {
-	fmt.Printf("--------- %d ---------", p.location+1)
-	fmt.Println()
+	p.tracer.Location(p.location + 1)
 	for _, t := range tok {
 		p.scanPass(p.Location(), t)
 	}
 
 	tokenRecognized := len(p.chart.Sets) > p.Location()+1
 	if !tokenRecognized {
-		return false, nil
+		err := p.unexpected(tok...)
+		if !p.recover || len(tok) == 0 {
+			// the tokens still follow the forest at the current location
+			p.look(p.location, tok...)
+			return false, err
+		}
+		p.recoverFrom(err, tok...)
+		return true, nil
 	}
 
+	p.advance(tok...)
+	return true, nil
+}
+
+// advance moves to the next location after the tokens are scanned
+func (p *parser) advance(tok ...token.Token) {
+	if p.recover {
+		p.pulsed = append(p.pulsed, tok[0])
+	}
+	p.look(p.location, tok...)
+
 	p.location++
 
 	p.reductionPass(p.location)
 	p.nodes.Clear()
+}
 
-	return true, nil
+// look records the token types at the location for NotFollowedBy
+func (p *parser) look(location int, tok ...token.Token) {
+	if !p.disambiguate {
+		return
+	}
+	for _, t := range tok {
+		p.lookahead[location] = append(p.lookahead[location], t.TokenType())
+	}
+	if location == p.disambiguated {
+		p.disambiguated = -1
+	}
 }
 
 func (p *parser) scanPass(location int, tok token.Token) {
@@ -139,8 +192,7 @@ func (p *parser) scan(s *state.Normal, j int, tok token.Token) {
 	next := p.newState(rule.Production, rule.Position, s.Origin)
 	next.Node = parseNode
 	p.chart.Enqueue(j+1, next)
-	fmt.Printf("%s : Scan", next)
-	fmt.Println()
+	p.tracer.Scan(next.DottedRule, next.Origin, tok)
 }
 
 func (parser *parser) reductionPass(location int) {
@@ -173,10 +225,13 @@ func (p *parser) complete(completed *state.Normal, location int) {
 	sym := completed.DottedRule.Production.LeftHandSide
 
 	if completed.Node == nil {
-		completed.Node = p.nodes.AddOrGetExistingSymbolNode(
+		// only empty productions complete without a node, the family without children records the production
+		node := p.nodes.AddOrGetExistingSymbolNode(
 			completed.DottedRule.Production.LeftHandSide,
 			completed.Origin,
 			location)
+		node.AddUniqueDerivation(completed.DottedRule.Production, nil, nil)
+		completed.Node = node
 	}
 
 	trans, ok := set.FindTransition(sym)
@@ -192,6 +247,17 @@ func (p *parser) leoComplete(completed *state.Normal, trans *state.Transition, l
 	dottedRule := trans.DottedRule
 	origin := trans.Origin
 
+	// add the path before the existence check so each completed node is a derivation of the top most node
+	node := p.nodes.AddOrGetExistingSymbolNode(dottedRule.Production.LeftHandSide, origin, location)
+	var nulls map[grammar.Symbol]forest.Node
+	if len(trans.Nulls) > 0 {
+		nulls = map[grammar.Symbol]forest.Node{}
+		for _, sym := range trans.Nulls {
+			p.nullNode(sym.(grammar.NonTerminal), location, nulls)
+		}
+	}
+	node.AddPath(trans, completed.Node, nulls)
+
 	// check if the item exists
 	if p.chart.Contains(location, state.NormalType, dottedRule, origin) {
 		return
@@ -199,19 +265,11 @@ func (p *parser) leoComplete(completed *state.Normal, trans *state.Transition, l
 
 	// this is the top most item
 	top := p.newState(dottedRule.Production, dottedRule.Position, origin)
-	node := p.nodes.AddOrGetExistingSymbolNode(dottedRule.Production.LeftHandSide, origin, location)
 	top.Node = node
 
-	root, ok := p.chart.Sets[trans.Root].FindTransition(trans.Symbol)
-	if !ok {
-		root = trans
-	}
-	node.AddPath(root, completed.Node)
-
 	p.chart.Enqueue(location, top)
 
-	fmt.Printf("%s : Leo Complete", top)
-	fmt.Println()
+	p.tracer.LeoComplete(top.DottedRule, top.Origin)
 }
 
 func (par *parser) earleyComplete(completed *state.Normal, location int) {
@@ -244,8 +302,7 @@ func (par *parser) earleyComplete(completed *state.Normal, location int) {
 
 		par.chart.Enqueue(location, state)
 
-		fmt.Printf("%s : Earley Complete", state)
-		fmt.Println()
+		par.tracer.EarleyComplete(state.DottedRule, state.Origin)
 	}
 }
 
@@ -294,16 +351,9 @@ func (parser *parser) memoize(location int) {
 		if !parser.grammar.IsRightRecursive(normal.DottedRule.Production) {
 			continue
 		}
-		next, ok := parser.grammar.Rules.Next(normal.DottedRule)
-		if !ok {
-			continue
-		}
-		if !parser.isQuasiComplete(next) {
-			continue
-		}
 
-		// create the transition
-		trans, ok := parser.newTransition(normal, normal.Origin, location)
+		// create the transition, quasi complete items are skipped unless the symbols after the postdot symbol are nulling
+		trans, ok := parser.newTransition(normal, normal.Origin)
 		if !ok {
 			continue
 		}
@@ -311,68 +361,125 @@ func (parser *parser) memoize(location int) {
 		// add the transition
 		parser.chart.Enqueue(location, trans)
 
-		// log the transistion creation
-		fmt.Printf("%s : Transition", trans.String())
-		fmt.Println()
+		// trace the transistion creation
+		parser.tracer.Transition(trans.Symbol, trans.DottedRule, trans.Origin)
 	}
 }
 
-func (parser *parser) newTransition(predict *state.Normal, origin int, location int) (*state.Transition, bool) {
+func (parser *parser) newTransition(predict *state.Normal, origin int) (*state.Transition, bool) {
 	sym, ok := predict.DottedRule.PostDotSymbol().Deconstruct()
 	if !ok {
 		return nil, ok
 	}
-	trans, ok := parser.chart.Sets[origin].FindTransition(sym)
+	next, ok := parser.grammar.Rules.Next(predict.DottedRule)
+	if !ok {
+		return nil, ok
+	}
+	suffix, ok := parser.nullingSuffix(next)
+	if !ok {
+		return nil, ok
+	}
+	complete := next
+	if len(suffix) > 0 {
+		complete = suffix[len(suffix)-1]
+	}
+	nulls := next.Production.RightHandSide[next.Position:]
+
+	// the item completes its left hand side at the origin, check if a transition for it exists there
+	trans, ok := parser.chart.Sets[origin].FindTransition(predict.DottedRule.Production.LeftHandSide)
 	if ok {
 		// if so, copy it here
 		clone := &state.Transition{
 			DottedRule: trans.DottedRule,
 			Origin:     trans.Origin,
-			Symbol:     trans.Symbol,
+			Symbol:     sym,
+			Rule:       next,
+			Suffix:     suffix,
+			Nulls:      union(trans.Nulls, nulls),
 			Predict:    predict.Node,
-			Root:       trans.Root,
 		}
-		trans.SetNext(clone)
+		clone.SetParent(trans)
 		trans = clone
 
 	} else {
-		next, ok := parser.grammar.Rules.Next(predict.DottedRule)
-		if !ok {
-			return nil, ok
-		}
-		// otherwise create it
+		// otherwise create it, the cached item is complete because the nulling suffix derives nothing
 		trans = &state.Transition{
-			DottedRule: next,
+			DottedRule: complete,
 			Origin:     predict.Origin,
 			Symbol:     sym,
+			Rule:       next,
+			Suffix:     suffix,
+			Nulls:      union(nil, nulls),
 			Predict:    predict.Node,
-			Root:       location,
 		}
 	}
 	return trans, true
 }
 
-func (parser *parser) isQuasiComplete(rule *grammar.DottedRule) bool {
-	if rule.Complete() {
-		return true
-	}
-	// all postdot symbols are nullable
-	for i := rule.Position; i < len(rule.Production.RightHandSide); i++ {
-		sym := rule.Production.RightHandSide[i]
+// nullingSuffix returns the dotted rules after the rule up to the complete rule
+// it fails if one of the remaining symbols is not nulling
+func (parser *parser) nullingSuffix(rule *grammar.DottedRule) ([]*grammar.DottedRule, bool) {
+	var suffix []*grammar.DottedRule
+	for !rule.Complete() {
+		sym, ok := rule.PostDotSymbol().Deconstruct()
+		if !ok {
+			return nil, false
+		}
 		nt, ok := sym.(grammar.NonTerminal)
+		if !ok || !parser.grammar.IsNulling(nt) {
+			return nil, false
+		}
+		rule, ok = parser.grammar.Rules.Next(rule)
 		if !ok {
-			return false
+			return nil, false
 		}
-		if !parser.grammar.IsTransativeNullable(nt) {
-			return false
+		suffix = append(suffix, rule)
+	}
+	return suffix, true
+}
+
+// union returns the symbols of both slices without duplicates
+func union(symbols []grammar.Symbol, others []grammar.Symbol) []grammar.Symbol {
+	result := append([]grammar.Symbol(nil), symbols...)
+	for _, other := range others {
+		found := false
+		for _, sym := range result {
+			if sym == other {
+				found = true
+				break
+			}
 		}
-		// page 4 leo paper
-		// check if S can derive S
-		if rule.Production.LeftHandSide == parser.grammar.Start && parser.grammar.Start == nt {
-			return false
+		if !found {
+			result = append(result, other)
 		}
 	}
-	return true
+	return result
+}
+
+// nullNode returns the node of the nulling symbol at the location with a family for each of its productions
+// the families are built like the predictions of the symbol would build them, nulls stops cycles
+func (p *parser) nullNode(sym grammar.NonTerminal, location int, nulls map[grammar.Symbol]forest.Node) forest.Node {
+	if node, ok := nulls[sym]; ok {
+		return node
+	}
+	node := p.nodes.AddOrGetExistingSymbolNode(sym, location, location)
+	nulls[sym] = node
+	for _, production := range p.grammar.RulesFor(sym) {
+		if len(production.RightHandSide) == 0 {
+			node.AddUniqueDerivation(production, nil, nil)
+			continue
+		}
+		var w forest.Node
+		for i, s := range production.RightHandSide {
+			rule, ok := p.grammar.Rules.Get(production, i+1)
+			if !ok {
+				break
+			}
+			v := p.nullNode(s.(grammar.NonTerminal), location, nulls)
+			w = p.createParseNode(rule, location, w, v, location)
+		}
+	}
+	return node
 }
 
 func (par *parser) predict(evidence *state.Normal, location int) {
@@ -409,8 +516,7 @@ func (p *parser) predictProduction(location int, production *grammar.Production)
 	}
 	s := p.newState(rule.Production, rule.Position, location)
 	p.chart.Enqueue(location, s)
-	fmt.Printf("%s : Predict", s)
-	fmt.Println()
+	p.tracer.Predict(s.DottedRule, s.Origin)
 }
 
 func (p *parser) predictAycockHorspool(evidence *state.Normal, nullableSymbol grammar.Symbol, location int) {
@@ -430,8 +536,7 @@ func (p *parser) predictAycockHorspool(evidence *state.Normal, nullableSymbol gr
 	state.Node = node
 
 	p.chart.Enqueue(location, state)
-	fmt.Printf("%s : Predict AH", state)
-	fmt.Println()
+	p.tracer.PredictAycockHorspool(state.DottedRule, state.Origin)
 }
 
 func (p *parser) Location() int {
@@ -440,20 +545,40 @@ func (p *parser) Location() int {
 
 // Accepted implements Parser.
 func (p *parser) Accepted() bool {
-	_, ok := p.findAcceptedCompletion(p.location)
-	return ok
+	_, ok := p.root()
+	return ok && !p.rejected()
 }
 
 func (p *parser) GetForestRoot() (forest.Node, bool) {
+	root, ok := p.root()
+	if !ok || p.rejected() {
+		return nil, false
+	}
+	return root, true
+}
+
+// root returns the forest root of the parse accepted at the current location
+// the forest is disambiguated when the grammar has disambiguation metadata
+func (p *parser) root() (forest.Node, bool) {
 	s, ok := p.findAcceptedCompletion(p.location)
 	if !ok {
 		return nil, false
 	}
-	return s.Node, true
+	if !p.disambiguate {
+		return s.Node, true
+	}
+	if p.disambiguated != p.location {
+		p.disambiguated = p.location
+		p.filtered, ok = forest.Disambiguate(p.grammar, s.Node, p.lookahead)
+		if !ok {
+			p.filtered = nil
+		}
+	}
+	return p.filtered, p.filtered != nil
 }
 
 func (p *parser) findAcceptedCompletion(location int) (*state.Normal, bool) {
-	set := p.chart.Sets[p.location]
+	set := p.chart.Sets[location]
 	start := p.grammar.Start
 	reductions := set.FindReductions(start)
 	for c := 0; c < len(reductions); c++ {
@@ -484,6 +609,50 @@ func (p *parser) Expected() []grammar.LexerRule {
 	return expected
 }
 
+// ExpectedNonTerminals implements Parser.
+func (p *parser) ExpectedNonTerminals() []grammar.NonTerminal {
+	set := p.chart.Sets[p.location]
+
+	seen := map[grammar.NonTerminal]struct{}{}
+	var expected []grammar.NonTerminal
+	for _, s := range set.Predictions {
+		postDot, ok := s.DottedRule.PostDotSymbol().Deconstruct()
+		if !ok {
+			continue
+		}
+		nt, ok := postDot.(grammar.NonTerminal)
+		if !ok {
+			continue
+		}
+		if _, ok := seen[nt]; ok {
+			continue
+		}
+		seen[nt] = struct{}{}
+		expected = append(expected, nt)
+	}
+	return expected
+}
+
+// unexpected returns a ParseError for tokens that no state at the current location accepts
+func (p *parser) unexpected(tok ...token.Token) *ParseError {
+	err := NewParseError(p, 0, 0, 0)
+	if len(tok) == 0 {
+		return err
+	}
+	err.Token = tok[0]
+	err.Position = tok[0].Position()
+	if capture, ok := tok[0].(*token.Capture); ok {
+		err.Line = capture.Start.Line
+		err.Column = capture.Start.Column
+	}
+	return err
+}
+
+// Ignored implements Parser.
+func (p *parser) Ignored() []grammar.LexerRule {
+	return p.grammar.Ignore
+}
+
 func (p *parser) createParseNode(
 	rule *grammar.DottedRule,
 	origin int,
@@ -511,9 +680,10 @@ func (p *parser) createParseNode(
 	*/
 	var internal forest.Internal
 	var node forest.Node
+	var symbol *forest.Symbol
 
 	if rule.Complete() {
-		symbol := p.nodes.AddOrGetExistingSymbolNode(
+		symbol = p.nodes.AddOrGetExistingSymbolNode(
 			rule.Production.LeftHandSide,
 			origin,
 			location,
@@ -538,9 +708,16 @@ func (p *parser) createParseNode(
 		return v
 	}
 
-	if w == nil && v != nil {
-		internal.AddUniqueFamily(v, nil)
-	} else if w != nil && v != nil {
+	if v == nil {
+		return node
+	}
+	if w == nil {
+		w, v = v, nil
+	}
+	// symbol nodes keep the production of each family so it is known without intermediate nodes
+	if symbol != nil {
+		symbol.AddUniqueDerivation(rule.Production, w, v)
+	} else {
 		internal.AddUniqueFamily(w, v)
 	}
 	return node
@@ -1,7 +1,9 @@
 package parser_test
 
 import (
+	"bytes"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/patrickhuber/go-earley/forest"
@@ -85,6 +87,327 @@ func TestParser(t *testing.T) {
 	})
 }
 
+func TestTracer(t *testing.T) {
+	S := grammar.NewNonTerminal("S")
+	a := grammar.NewStringLexerRule("a")
+
+	// S -> 'a'
+	g := grammar.New(S, grammar.NewProduction(S, a))
+
+	buf := &bytes.Buffer{}
+	p := parser.New(g, parser.Trace(parser.NewWriterTracer(buf)))
+	RunParse(t, p, a)
+
+	expected := []string{
+		"--------- 0 ---------",
+		"S ->•a, 0 : Init",
+		"--------- 1 ---------",
+		"S -> a•, 0 : Scan",
+		"",
+	}
+	require.Equal(t, strings.Join(expected, "\n"), buf.String())
+}
+
+func TestTracerAycockHorspool(t *testing.T) {
+	S := grammar.NewNonTerminal("S")
+	A := grammar.NewNonTerminal("A")
+	a := grammar.NewStringLexerRule("a")
+
+	// S -> A 'a'
+	// A -> <null>
+	g := grammar.New(S,
+		grammar.NewProduction(S, A, a),
+		grammar.NewProduction(A),
+	)
+
+	buf := &bytes.Buffer{}
+	p := parser.New(g, parser.Trace(parser.NewWriterTracer(buf)))
+	RunParse(t, p, a)
+
+	expected := []string{
+		"--------- 0 ---------",
+		"S ->•A a, 0 : Init",
+		"A ->•, 0 : Predict",
+		"S -> A•a, 0 : Predict AH",
+		"--------- 1 ---------",
+		"S -> A a•, 0 : Scan",
+		"",
+	}
+	require.Equal(t, strings.Join(expected, "\n"), buf.String())
+}
+
+func TestParseError(t *testing.T) {
+	S := grammar.NewNonTerminal("S")
+	A := grammar.NewNonTerminal("A")
+	a := grammar.NewStringLexerRule("a")
+	b := grammar.NewStringLexerRule("b")
+	g := grammar.New(S,
+		grammar.NewProduction(S, a, A),
+		grammar.NewProduction(A, b),
+	)
+
+	p := parser.New(g)
+	ok, err := p.Pulse(TokenFromString("a", 0, a.TokenType()))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	tok := token.NewCapture(
+		TokenFromString("a", 1, a.TokenType()),
+		"a",
+		token.Span{Offset: 1, Length: 1},
+		token.Location{Line: 1, Column: 2},
+		token.Location{Line: 1, Column: 2})
+	ok, err = p.Pulse(tok)
+	require.False(t, ok)
+
+	var parseErr *parser.ParseError
+	require.ErrorAs(t, err, &parseErr)
+	require.Equal(t, 1, parseErr.Position)
+	require.Equal(t, tok, parseErr.Token)
+	require.Equal(t, []grammar.LexerRule{b}, parseErr.Expected)
+	require.Equal(t, []grammar.NonTerminal{A}, parseErr.NonTerminals)
+	require.Equal(t, `unexpected "a", expected 'b' at 1:2`, err.Error())
+}
+
+func TestRecover(t *testing.T) {
+	t.Run("inserts missing token", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		a := grammar.NewStringLexerRule("a")
+		plus := grammar.NewStringLexerRule("+")
+		g := grammar.New(S,
+			grammar.NewProduction(S, a, plus, a),
+		)
+		p := parser.New(g, parser.Recover())
+		for i, tok := range []string{"a", "a"} {
+			ok, err := p.Pulse(TokenFromString(tok, i, tok))
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+		require.True(t, p.Accepted())
+		require.Len(t, p.Diagnostics(), 1)
+		require.Equal(t, 1, p.Diagnostics()[0].Position)
+
+		root, ok := p.GetForestRoot()
+		require.True(t, ok)
+		leaves := Leaves(root)
+		require.Len(t, leaves, 3)
+		missing, ok := leaves[1].(*parser.Missing)
+		require.True(t, ok)
+		require.Equal(t, plus, missing.LexerRule)
+	})
+	t.Run("skips unexpected token", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		a := grammar.NewStringLexerRule("a")
+		b := grammar.NewStringLexerRule("b")
+		g := grammar.New(S,
+			grammar.NewProduction(S, a, b),
+		)
+		p := parser.New(g, parser.Recover())
+		for i, tok := range []string{"a", "c", "b"} {
+			ok, err := p.Pulse(TokenFromString(tok, i, tok))
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+		require.True(t, p.Accepted())
+		require.Len(t, p.Diagnostics(), 1)
+
+		root, ok := p.GetForestRoot()
+		require.True(t, ok)
+		require.Len(t, Leaves(root), 2)
+	})
+	t.Run("resynchronizes on recovery nonterminal", func(t *testing.T) {
+		// Program -> Statements
+		// Statements -> Statement Statements | <empty>
+		// Statement -> 'x' ';'
+		Program := grammar.NewNonTerminal("Program")
+		Statements := grammar.NewNonTerminal("Statements")
+		Statement := grammar.NewNonTerminal("Statement")
+		x := grammar.NewStringLexerRule("x")
+		semicolon := grammar.NewStringLexerRule(";")
+		g := grammar.New(Program,
+			grammar.NewProduction(Program, Statements),
+			grammar.NewProduction(Statements, Statement, Statements),
+			grammar.NewProduction(Statements),
+			grammar.NewProduction(Statement, x, semicolon),
+		)
+		p := parser.New(g, parser.Recover(Statement))
+		input := []string{"x", ";", "x", "?", "?", ";", "x", ";"}
+		for i, tok := range input {
+			ok, err := p.Pulse(TokenFromString(tok, i, tok))
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+		require.True(t, p.Accepted())
+		require.Len(t, p.Diagnostics(), 3)
+
+		root, ok := p.GetForestRoot()
+		require.True(t, ok)
+		leaves := Leaves(root)
+		require.Len(t, leaves, 5)
+		skipped, ok := leaves[2].(*parser.Skipped)
+		require.True(t, ok)
+		var types []string
+		for _, tok := range skipped.Tokens {
+			types = append(types, tok.TokenType())
+		}
+		require.Equal(t, []string{"x", "?", "?", ";"}, types)
+
+		data, err := forest.MarshalJSON(root)
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"production":"Statement -> <error>"`)
+	})
+	t.Run("inserts missing token at end of input", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		a := grammar.NewStringLexerRule("a")
+		plus := grammar.NewStringLexerRule("+")
+		g := grammar.New(S,
+			grammar.NewProduction(S, a, plus, a),
+		)
+		p := parser.New(g, parser.Recover())
+		for i, tok := range []string{"a", "+"} {
+			ok, err := p.Pulse(TokenFromString(tok, i, tok))
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+		require.False(t, p.Accepted())
+		require.True(t, p.Repair(&parser.ParseError{EndOfInput: true, Position: 2}))
+		require.True(t, p.Accepted())
+		require.Len(t, p.Diagnostics(), 1)
+
+		root, ok := p.GetForestRoot()
+		require.True(t, ok)
+		leaves := Leaves(root)
+		require.Len(t, leaves, 3)
+		missing, ok := leaves[2].(*parser.Missing)
+		require.True(t, ok)
+		require.Equal(t, a, missing.LexerRule)
+	})
+	t.Run("resynchronizes at end of input", func(t *testing.T) {
+		// Program -> Statements
+		// Statements -> Statement Statements | <empty>
+		// Statement -> 'x' '=' 'x' ';'
+		Program := grammar.NewNonTerminal("Program")
+		Statements := grammar.NewNonTerminal("Statements")
+		Statement := grammar.NewNonTerminal("Statement")
+		x := grammar.NewStringLexerRule("x")
+		equal := grammar.NewStringLexerRule("=")
+		semicolon := grammar.NewStringLexerRule(";")
+		g := grammar.New(Program,
+			grammar.NewProduction(Program, Statements),
+			grammar.NewProduction(Statements, Statement, Statements),
+			grammar.NewProduction(Statements),
+			grammar.NewProduction(Statement, x, equal, x, semicolon),
+		)
+		p := parser.New(g, parser.Recover(Statement))
+		input := []string{"x", "=", "x", ";", "x", "="}
+		for i, tok := range input {
+			ok, err := p.Pulse(TokenFromString(tok, i, tok))
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+		require.True(t, p.Repair(&parser.ParseError{EndOfInput: true, Position: len(input)}))
+		require.True(t, p.Accepted())
+		require.Len(t, p.Diagnostics(), 1)
+
+		root, ok := p.GetForestRoot()
+		require.True(t, ok)
+		leaves := Leaves(root)
+		require.Len(t, leaves, 5)
+		skipped, ok := leaves[4].(*parser.Skipped)
+		require.True(t, ok)
+		require.Len(t, skipped.Tokens, 2)
+	})
+	t.Run("skips unrecognized character", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		a := grammar.NewStringLexerRule("a")
+		b := grammar.NewStringLexerRule("b")
+		g := grammar.New(S,
+			grammar.NewProduction(S, a, b),
+		)
+		p := parser.New(g, parser.Recover())
+		ok, err := p.Pulse(TokenFromString("a", 0, "a"))
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.True(t, p.Repair(&parser.ParseError{Character: '?', Position: 1}))
+		ok, err = p.Pulse(TokenFromString("b", 2, "b"))
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.True(t, p.Accepted())
+		require.Len(t, p.Diagnostics(), 1)
+	})
+	t.Run("disabled by default", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		a := grammar.NewStringLexerRule("a")
+		g := grammar.New(S,
+			grammar.NewProduction(S, a),
+		)
+		p := parser.New(g)
+		ok, err := p.Pulse(TokenFromString("b", 0, "b"))
+		require.False(t, ok)
+		require.Error(t, err)
+		require.Empty(t, p.Diagnostics())
+		require.False(t, p.Repair(&parser.ParseError{EndOfInput: true}))
+	})
+}
+
+func TestAmbiguity(t *testing.T) {
+	E := grammar.NewNonTerminal("E")
+	plus := grammar.NewStringLexerRule("+")
+	a := grammar.NewStringLexerRule("a")
+
+	// E -> E '+' E | 'a'
+	g := grammar.New(E,
+		grammar.NewProduction(E, E, plus, E),
+		grammar.NewProduction(E, a),
+	)
+
+	t.Run("allows by default", func(t *testing.T) {
+		p := parser.New(g)
+		RunParse(t, p, a, plus, a, plus, a)
+		require.True(t, p.Accepted())
+		require.Empty(t, p.Ambiguities())
+	})
+	t.Run("warns", func(t *testing.T) {
+		p := parser.New(g, parser.Ambiguity(parser.WarnAmbiguity))
+		RunParse(t, p, a, plus, a, plus, a)
+		require.True(t, p.Accepted())
+		require.Len(t, p.Ambiguities(), 1)
+	})
+	t.Run("rejects", func(t *testing.T) {
+		p := parser.New(g, parser.Ambiguity(parser.RejectAmbiguity))
+		RunParse(t, p, a, plus, a)
+		require.True(t, p.Accepted())
+
+		for _, tok := range []*grammar.StringLexerRule{plus, a} {
+			ok, err := p.Pulse(token.NewString(tok, p.Location()))
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+		require.False(t, p.Accepted())
+		_, ok := p.GetForestRoot()
+		require.False(t, ok)
+		require.Len(t, p.Ambiguities(), 1)
+	})
+}
+
+func Leaves(node forest.Node) []token.Token {
+	switch n := node.(type) {
+	case *forest.Token:
+		return []token.Token{n.Token}
+	case forest.Internal:
+		alternatives := n.Alternatives()
+		if len(alternatives) == 0 {
+			return nil
+		}
+		var leaves []token.Token
+		for _, child := range alternatives[0].Children() {
+			leaves = append(leaves, Leaves(child)...)
+		}
+		return leaves
+	}
+	return nil
+}
+
 func TestAycockHorspool(t *testing.T) {
 	/*
 		S' -> S
@@ -211,6 +534,7 @@ func TestForest(t *testing.T) {
 		Edge(S_0_1, a_0_1)
 		Edge(T_1_2, a_1_2)
 		Edge(T_1_2, a_1_2, B_2_2)
+		Edge(B_2_2)
 
 		Equal(t, S_0_2, root)
 	})
@@ -320,6 +644,7 @@ func TestForest(t *testing.T) {
 		Edge(T_bbb_1_3, b_1_2, b_2_3)
 		Edge(A_0_1, a_0_1)
 		Edge(A_0_1, B_0_0, A_0_1)
+		Edge(B_0_0)
 		Equal(t, S_0_4, root)
 	})
 
@@ -576,6 +901,9 @@ func Edge(internal forest.Internal, nodes ...forest.Node) {
 		internal.AddUniqueFamily(nodes[0], nodes[1])
 	} else if len(nodes) == 1 {
 		internal.AddUniqueFamily(nodes[0], nil)
+	} else {
+		// the empty family of an empty production
+		internal.AddUniqueFamily(nil, nil)
 	}
 }
 
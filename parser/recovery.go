@@ -0,0 +1,310 @@
+package parser
+
+import (
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/internal/state"
+	"github.com/patrickhuber/go-earley/token"
+)
+
+// ErrorTokenType is the token type of Skipped and Unrecognized tokens
+const ErrorTokenType = "<error>"
+
+// Missing is a token inserted by error recovery in place of an expected token that is absent from the input
+type Missing struct {
+	LexerRule grammar.LexerRule
+	position  int
+}
+
+// Position implements token.Token.
+func (m *Missing) Position() int {
+	return m.position
+}
+
+// TokenType implements token.Token.
+func (m *Missing) TokenType() string {
+	return m.LexerRule.TokenType()
+}
+
+// Skipped is a token created by error recovery that holds the tokens consumed while resynchronizing on a recovery nonterminal
+// In the forest it is the only child of the error production of the recovery nonterminal.
+// It has no tokens if the input ends before the recovery nonterminal.
+type Skipped struct {
+	Tokens   []token.Token
+	position int
+}
+
+// Position implements token.Token.
+func (s *Skipped) Position() int {
+	if len(s.Tokens) == 0 {
+		return s.position
+	}
+	return s.Tokens[0].Position()
+}
+
+// TokenType implements token.Token.
+func (s *Skipped) TokenType() string {
+	return ErrorTokenType
+}
+
+// Unrecognized is a token created by error recovery for a character that no lexer rule matches
+type Unrecognized struct {
+	Character rune
+	position  int
+}
+
+// Position implements token.Token.
+func (u *Unrecognized) Position() int {
+	return u.position
+}
+
+// TokenType implements token.Token.
+func (u *Unrecognized) TokenType() string {
+	return ErrorTokenType
+}
+
+// errorSymbol is the right hand side of the error productions, it matches no character so it is never scanned
+type errorSymbol struct {
+	grammar.SymbolImpl
+}
+
+func (*errorSymbol) CanApply(ch rune) bool { return false }
+func (*errorSymbol) LexerRuleType() string { return ErrorTokenType }
+func (*errorSymbol) TokenType() string     { return ErrorTokenType }
+func (*errorSymbol) String() string        { return ErrorTokenType }
+
+var errorLexerRule grammar.LexerRule = &errorSymbol{}
+
+// IsErrorProduction returns true for the productions error recovery uses to derive a recovery nonterminal from a Skipped token
+func IsErrorProduction(production *grammar.Production) bool {
+	return len(production.RightHandSide) == 1 && production.RightHandSide[0] == errorLexerRule
+}
+
+// Recover enables error recovery, Pulse then reports unexpected tokens as Diagnostics instead of failing
+// Recovery first tries to insert a single Missing token that lets the unexpected token scan.
+// Otherwise the innermost expected recovery nonterminal is completed by its error production with a Skipped token
+// that covers the unexpected token and the tokens since the nonterminal was predicted, following unexpected tokens extend the same Skipped token.
+// If neither applies, the unexpected token is skipped and only reported as a diagnostic.
+// Repair applies the same recovery to the characters and the end of input the scanner reports.
+func Recover(nonTerminals ...grammar.NonTerminal) Option {
+	return func(p *parser) {
+		p.recover = true
+		p.recovery = nonTerminals
+		p.errors = map[grammar.NonTerminal]*grammar.DottedRule{}
+		for _, nt := range nonTerminals {
+			p.errors[nt] = grammar.NewDottedRule(grammar.NewProduction(nt, errorLexerRule), 1)
+		}
+	}
+}
+
+// resynchronization is the recovery nonterminal and span of the last Skipped token
+type resynchronization struct {
+	symbol   grammar.NonTerminal
+	origin   int
+	location int
+}
+
+// Diagnostics implements Parser.
+func (p *parser) Diagnostics() []*ParseError {
+	return p.diagnostics
+}
+
+// Repair implements Parser.
+// A character no lexer rule matches is recovered from like an unexpected token.
+// At the end of input a single Missing token is inserted if it lets the parser accept,
+// otherwise the innermost recovery nonterminal whose error production lets the parser accept is completed.
+// It returns false if recovery is disabled or does not apply.
+func (p *parser) Repair(err *ParseError) bool {
+	if !p.recover {
+		return false
+	}
+	if err.EndOfInput {
+		return p.repairEndOfInput(err)
+	}
+	tok := err.Token
+	if tok == nil {
+		tok = &Unrecognized{Character: err.Character, position: err.Position}
+	}
+	p.recoverFrom(err, tok)
+	return true
+}
+
+func (p *parser) recoverFrom(err *ParseError, tok ...token.Token) {
+	p.diagnostics = append(p.diagnostics, err)
+
+	// tokens after a Skipped token extend it until a token scans
+	if p.resync != nil && p.resync.location == p.location {
+		p.resynchronize(p.resync.symbol, p.resync.origin, tok...)
+		return
+	}
+	if p.insert(tok...) {
+		return
+	}
+	for j := p.location; j >= 0; j-- {
+		for _, nt := range p.recovery {
+			if len(p.chart.Sets[j].FindSourceStates(nt)) == 0 {
+				continue
+			}
+			p.resynchronize(nt, j, tok...)
+			return
+		}
+	}
+	// skip the tokens, the next token is scanned at the same location
+	p.look(p.location, tok...)
+}
+
+func (p *parser) repairEndOfInput(err *ParseError) bool {
+	// the scanner reports the end of input if a lexeme in progress is not accepted
+	if p.Accepted() {
+		p.diagnostics = append(p.diagnostics, err)
+		return true
+	}
+	seen := map[string]struct{}{}
+	for _, lexerRule := range p.Expected() {
+		if _, ok := seen[lexerRule.TokenType()]; ok {
+			continue
+		}
+		seen[lexerRule.TokenType()] = struct{}{}
+
+		missing := &Missing{
+			LexerRule: lexerRule,
+			position:  err.Position,
+		}
+		if !p.attempt(func(location int) bool {
+			p.scanPass(location, missing)
+			if len(p.chart.Sets) <= location+1 {
+				return false
+			}
+			p.reductionPass(location + 1)
+			_, ok := p.findAcceptedCompletion(location + 1)
+			return ok
+		}) {
+			continue
+		}
+		p.diagnostics = append(p.diagnostics, err)
+		p.scanPass(p.location, missing)
+		p.advance(missing)
+		return true
+	}
+	for j := p.location; j >= 0; j-- {
+		for _, nt := range p.recovery {
+			if len(p.chart.Sets[j].FindSourceStates(nt)) == 0 {
+				continue
+			}
+			skipped := p.skipped(j, err.Position)
+			if !p.attempt(func(location int) bool {
+				p.completeError(nt, j, skipped, location+1)
+				p.reductionPass(location + 1)
+				_, ok := p.findAcceptedCompletion(location + 1)
+				return ok
+			}) {
+				continue
+			}
+			p.diagnostics = append(p.diagnostics, err)
+			p.completeError(nt, j, skipped, p.location+1)
+			p.advance(skipped)
+			return true
+		}
+	}
+	return false
+}
+
+// insert scans a Missing token for the first expected lexer rule that allows the tokens to scan after it
+func (p *parser) insert(tok ...token.Token) bool {
+	seen := map[string]struct{}{}
+	for _, lexerRule := range p.Expected() {
+		if _, ok := seen[lexerRule.TokenType()]; ok {
+			continue
+		}
+		seen[lexerRule.TokenType()] = struct{}{}
+
+		missing := &Missing{
+			LexerRule: lexerRule,
+			position:  tok[0].Position(),
+		}
+		if !p.try(missing, tok...) {
+			continue
+		}
+		p.scanPass(p.location, missing)
+		p.advance(missing)
+		for _, t := range tok {
+			p.scanPass(p.location, t)
+		}
+		p.advance(tok...)
+		return true
+	}
+	return false
+}
+
+// try returns true if the tokens scan after the missing token
+func (p *parser) try(missing *Missing, tok ...token.Token) bool {
+	return p.attempt(func(location int) bool {
+		p.scanPass(location, missing)
+		if len(p.chart.Sets) <= location+1 {
+			return false
+		}
+		p.reductionPass(location + 1)
+		for _, t := range tok {
+			p.scanPass(location+1, t)
+		}
+		return len(p.chart.Sets) > location+2
+	})
+}
+
+// attempt returns the result of the step at the current location
+// the chart and forest nodes are restored before returning
+func (p *parser) attempt(step func(location int) bool) bool {
+	location := p.location
+	tracer := p.tracer
+	optimizeRightRecursion := p.optimizeRightRecursion
+	defer func() {
+		p.chart.Sets = p.chart.Sets[:location+1]
+		p.nodes.Clear()
+		p.tracer = tracer
+		p.optimizeRightRecursion = optimizeRightRecursion
+	}()
+
+	// leo memoization updates transitions in earlier sets so it is disabled for the attempt
+	p.tracer = NopTracer()
+	p.optimizeRightRecursion = false
+
+	return step(location)
+}
+
+// resynchronize completes the recovery nonterminal from origin to the next location with a Skipped token
+func (p *parser) resynchronize(nt grammar.NonTerminal, origin int, tok ...token.Token) {
+	location := p.location + 1
+
+	p.completeError(nt, origin, p.skipped(origin, tok[0].Position(), tok[0]), location)
+
+	p.advance(tok...)
+	p.resync = &resynchronization{
+		symbol:   nt,
+		origin:   origin,
+		location: location,
+	}
+}
+
+// skipped returns a Skipped token with the tokens pulsed since the origin followed by the tokens
+func (p *parser) skipped(origin int, position int, tok ...token.Token) *Skipped {
+	skipped := &Skipped{position: position}
+	for _, pulsed := range p.pulsed[origin:] {
+		if _, ok := pulsed.(*Missing); ok {
+			continue
+		}
+		skipped.Tokens = append(skipped.Tokens, pulsed)
+	}
+	skipped.Tokens = append(skipped.Tokens, tok...)
+	return skipped
+}
+
+// completeError adds the completed error production of the recovery nonterminal from origin to location with the Skipped token
+func (p *parser) completeError(nt grammar.NonTerminal, origin int, skipped *Skipped, location int) {
+	rule := p.errors[nt]
+
+	node := p.nodes.AddOrGetExistingSymbolNode(nt, origin, location)
+	node.AddUniqueDerivation(rule.Production, p.nodes.AddOrGetExistingTokenNode(skipped, location), nil)
+
+	completed := state.NewNormal(rule, origin)
+	completed.Node = node
+	p.chart.Enqueue(location, completed)
+}
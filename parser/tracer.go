@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/token"
+)
+
+// Tracer receives an event for each step the parser takes while building the chart
+type Tracer interface {
+	// Location is called when the parser moves to a new earley set
+	Location(location int)
+	// Init is called for each start state added to the first earley set
+	Init(rule *grammar.DottedRule, origin int)
+	// Predict is called for each predicted state
+	Predict(rule *grammar.DottedRule, origin int)
+	// PredictAycockHorspool is called for each state created by moving past a nullable nonterminal
+	PredictAycockHorspool(rule *grammar.DottedRule, origin int)
+	// Scan is called for each state created by scanning a token
+	Scan(rule *grammar.DottedRule, origin int, tok token.Token)
+	// EarleyComplete is called for each state created by an earley completion
+	EarleyComplete(rule *grammar.DottedRule, origin int)
+	// LeoComplete is called for each top most state created by a leo completion
+	LeoComplete(rule *grammar.DottedRule, origin int)
+	// Transition is called for each leo transition item memoized in the current set
+	Transition(symbol grammar.Symbol, rule *grammar.DottedRule, origin int)
+}
+
+// Trace sets the tracer that receives parse events
+// the default is a tracer that discards all events
+func Trace(tracer Tracer) Option {
+	return func(p *parser) {
+		if tracer == nil {
+			tracer = NopTracer()
+		}
+		p.tracer = tracer
+	}
+}
+
+type nopTracer struct{}
+
+// NopTracer returns a tracer that discards all events
+func NopTracer() Tracer {
+	return nopTracer{}
+}
+
+func (nopTracer) Location(int)                                        {}
+func (nopTracer) Init(*grammar.DottedRule, int)                       {}
+func (nopTracer) Predict(*grammar.DottedRule, int)                    {}
+func (nopTracer) PredictAycockHorspool(*grammar.DottedRule, int)      {}
+func (nopTracer) Scan(*grammar.DottedRule, int, token.Token)          {}
+func (nopTracer) EarleyComplete(*grammar.DottedRule, int)             {}
+func (nopTracer) LeoComplete(*grammar.DottedRule, int)                {}
+func (nopTracer) Transition(grammar.Symbol, *grammar.DottedRule, int) {}
+
+type writerTracer struct {
+	writer io.Writer
+}
+
+// NewWriterTracer returns a tracer that writes one line per event to the writer
+func NewWriterTracer(writer io.Writer) Tracer {
+	return &writerTracer{
+		writer: writer,
+	}
+}
+
+func (t *writerTracer) Location(location int) {
+	fmt.Fprintf(t.writer, "--------- %d ---------", location)
+	fmt.Fprintln(t.writer)
+}
+
+func (t *writerTracer) Init(rule *grammar.DottedRule, origin int) {
+	t.state(rule, origin, "Init")
+}
+
+func (t *writerTracer) Predict(rule *grammar.DottedRule, origin int) {
+	t.state(rule, origin, "Predict")
+}
+
+func (t *writerTracer) PredictAycockHorspool(rule *grammar.DottedRule, origin int) {
+	t.state(rule, origin, "Predict AH")
+}
+
+func (t *writerTracer) Scan(rule *grammar.DottedRule, origin int, tok token.Token) {
+	t.state(rule, origin, "Scan")
+}
+
+func (t *writerTracer) EarleyComplete(rule *grammar.DottedRule, origin int) {
+	t.state(rule, origin, "Earley Complete")
+}
+
+func (t *writerTracer) LeoComplete(rule *grammar.DottedRule, origin int) {
+	t.state(rule, origin, "Leo Complete")
+}
+
+func (t *writerTracer) Transition(symbol grammar.Symbol, rule *grammar.DottedRule, origin int) {
+	fmt.Fprintf(t.writer, "%s : %s, %d : Transition", symbol, rule, origin)
+	fmt.Fprintln(t.writer)
+}
+
+func (t *writerTracer) state(rule *grammar.DottedRule, origin int, event string) {
+	fmt.Fprintf(t.writer, "%s, %d : %s", rule, origin, event)
+	fmt.Fprintln(t.writer)
+}
@@ -1,24 +1,15 @@
 package pdl
 
-import "github.com/patrickhuber/go-earley/re"
+import (
+	"strings"
 
-type Definition interface {
-	definition()
-}
-
-type DefinitionBlock struct {
-	Block Block
-}
-
-func (DefinitionBlock) definition() {}
+	"github.com/patrickhuber/go-earley/re"
+)
 
-type DefinitionBlockDefinition struct {
-	Block      Block
-	Definition Definition
+type Definition struct {
+	Blocks []Block
 }
 
-func (DefinitionBlockDefinition) definition() {}
-
 type Block interface {
 	block()
 }
@@ -49,14 +40,20 @@ type Expression interface {
 }
 
 type ExpressionTerm struct {
-	Term Term
+	Term       Term
+	Attributes []Attribute
 }
 
+func (ExpressionTerm) expression() {}
+
 type ExpressionTermExpression struct {
 	Term       Term
+	Attributes []Attribute
 	Expression Expression
 }
 
+func (ExpressionTermExpression) expression() {}
+
 type Term interface {
 	term()
 }
@@ -65,11 +62,15 @@ type TermFactor struct {
 	Factor Factor
 }
 
+func (TermFactor) term() {}
+
 type TermFactorTerm struct {
 	Factor Factor
 	Term   Term
 }
 
+func (TermFactorTerm) term() {}
+
 type Factor interface {
 	factor()
 }
@@ -77,8 +78,33 @@ type Factor interface {
 type Literal interface {
 	literal()
 	factor()
+	lexerRuleFactor()
+	argument()
+	Value() string
+}
+
+// SingleQuoteString is a literal enclosed in single quotes 'value'
+type SingleQuoteString struct {
+	Text string
+}
+
+func (SingleQuoteString) literal()         {}
+func (SingleQuoteString) factor()          {}
+func (SingleQuoteString) lexerRuleFactor() {}
+func (SingleQuoteString) argument()        {}
+func (s SingleQuoteString) Value() string  { return s.Text }
+
+// DoubleQuoteString is a literal enclosed in double quotes "value"
+type DoubleQuoteString struct {
+	Text string
 }
 
+func (DoubleQuoteString) literal()         {}
+func (DoubleQuoteString) factor()          {}
+func (DoubleQuoteString) lexerRuleFactor() {}
+func (DoubleQuoteString) argument()        {}
+func (s DoubleQuoteString) Value() string  { return s.Text }
+
 type Repetition struct {
 	Expression Expression
 }
@@ -100,24 +126,70 @@ func (Grouping) factor() {}
 type QualifiedIdentifier interface {
 	qualifiedIdentifier()
 	factor()
+	lexerRuleFactor()
+	argument()
+	String() string
 }
 
-type QualifiedIdentifierIdentifier struct{}
+type QualifiedIdentifierIdentifier struct {
+	Identifier string
+}
 
 func (QualifiedIdentifierIdentifier) qualifiedIdentifier() {}
 
 func (QualifiedIdentifierIdentifier) factor() {}
 
-type QualifiedIdentifierIdentifierLetter struct{}
+func (QualifiedIdentifierIdentifier) lexerRuleFactor() {}
 
-func (QualifiedIdentifierIdentifierLetter) qualifiedIdentifier() {}
+func (QualifiedIdentifierIdentifier) argument() {}
 
-func (QualifiedIdentifierIdentifierLetter) factor() {}
+func (q QualifiedIdentifierIdentifier) String() string {
+	return q.Identifier
+}
 
-type SettingIdentifier struct {
+type QualifiedIdentifierIdentifierQualifiedIdentifier struct {
+	Identifier          string
+	QualifiedIdentifier QualifiedIdentifier
+}
+
+func (QualifiedIdentifierIdentifierQualifiedIdentifier) qualifiedIdentifier() {}
+
+func (QualifiedIdentifierIdentifierQualifiedIdentifier) factor() {}
+
+func (QualifiedIdentifierIdentifierQualifiedIdentifier) lexerRuleFactor() {}
+
+func (QualifiedIdentifierIdentifierQualifiedIdentifier) argument() {}
+
+func (q QualifiedIdentifierIdentifierQualifiedIdentifier) String() string {
+	var builder strings.Builder
+	builder.WriteString(q.Identifier)
+	builder.WriteRune('.')
+	builder.WriteString(q.QualifiedIdentifier.String())
+	return builder.String()
 }
 
-func (SettingIdentifier) factor() {}
+// Attribute is a disambiguation annotation at the end of an alternative like @left or @priority(2)
+type Attribute struct {
+	Name      string
+	Arguments []Argument
+}
+
+// Argument is a qualified identifier, literal or number argument of an attribute
+type Argument interface {
+	argument()
+}
+
+// Number is a decimal integer argument of an attribute
+type Number struct {
+	Value int
+}
+
+func (Number) argument() {}
+
+// SettingIdentifier is the name of a setting without the leading colon
+type SettingIdentifier struct {
+	Name string
+}
 
 type LexerRuleExpression interface {
 	lexerRuleExpression()
@@ -140,14 +212,49 @@ type LexerRuleTerm interface {
 	lexerRuleTerm()
 }
 
-type LexerRuleTermFactor struct{}
+type LexerRuleTermFactor struct {
+	LexerRuleFactor LexerRuleFactor
+}
 
 func (LexerRuleTermFactor) lexerRuleTerm() {}
 
-type LexerRuleTermFactorTerm struct{}
+type LexerRuleTermFactorTerm struct {
+	LexerRuleFactor LexerRuleFactor
+	LexerRuleTerm   LexerRuleTerm
+}
 
 func (LexerRuleTermFactorTerm) lexerRuleTerm() {}
 
+type LexerRuleFactor interface {
+	lexerRuleFactor()
+}
+
+// LexerRuleRepetition matches the lexer rule expression enclosed in braces zero or more times
+type LexerRuleRepetition struct {
+	LexerRuleExpression LexerRuleExpression
+}
+
+func (LexerRuleRepetition) lexerRuleFactor() {}
+
+// LexerRuleOptional matches the lexer rule expression enclosed in brackets zero or one time
+type LexerRuleOptional struct {
+	LexerRuleExpression LexerRuleExpression
+}
+
+func (LexerRuleOptional) lexerRuleFactor() {}
+
+// LexerRuleGrouping is a lexer rule expression enclosed in parentheses
+type LexerRuleGrouping struct {
+	LexerRuleExpression LexerRuleExpression
+}
+
+func (LexerRuleGrouping) lexerRuleFactor() {}
+
+// RegularExpression is a pattern enclosed in forward slashes /pattern/
 type RegularExpression struct {
-	Definition re.Definition
+	Pattern    string
+	Definition *re.Definition
 }
+
+func (RegularExpression) factor()          {}
+func (RegularExpression) lexerRuleFactor() {}
@@ -0,0 +1,535 @@
+package pdl
+
+import (
+	"fmt"
+
+	"github.com/patrickhuber/go-earley/automata/dfa"
+	"github.com/patrickhuber/go-earley/automata/nfa"
+	"github.com/patrickhuber/go-earley/automata/transform"
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/re"
+)
+
+const (
+	StartSetting     = "start"
+	IgnoreSetting    = "ignore"
+	NamespaceSetting = "namespace"
+)
+
+const (
+	PriorityAttribute      = "priority"
+	LeftAttribute          = "left"
+	RightAttribute         = "right"
+	NonAssocAttribute      = "nonassoc"
+	PreferAttribute        = "prefer"
+	AvoidAttribute         = "avoid"
+	RejectAttribute        = "reject"
+	NotFollowedByAttribute = "notfollowedby"
+)
+
+type compiler struct {
+	nonTerminals map[string]grammar.NonTerminal
+	lexerRules   map[string]grammar.LexerRule
+	// definitions holds the expressions of the declared lexer rules in declaration order
+	definitions map[string]LexerRuleExpression
+	declared    []string
+	// expanding holds the lexer rules whose references are being expanded, to detect recursion
+	expanding   map[string]struct{}
+	literals    map[string]grammar.LexerRule
+	expressions map[string]grammar.LexerRule
+	productions []*grammar.Production
+	start       grammar.NonTerminal
+	ignore      []grammar.LexerRule
+	generated   int
+}
+
+// alternative is the right hand side of a production and the attributes that annotate it
+type alternative struct {
+	symbols    []grammar.Symbol
+	attributes []Attribute
+}
+
+// Compile converts the definition into a grammar
+// Rules become productions, lexer rules and regular expressions become dfa lexer rules and the :start setting selects the start symbol.
+// When no :start setting exists, the first rule is the start symbol.
+func Compile(definition *Definition) (*grammar.Grammar, error) {
+	c := &compiler{
+		nonTerminals: map[string]grammar.NonTerminal{},
+		lexerRules:   map[string]grammar.LexerRule{},
+		definitions:  map[string]LexerRuleExpression{},
+		expanding:    map[string]struct{}{},
+		literals:     map[string]grammar.LexerRule{},
+		expressions:  map[string]grammar.LexerRule{},
+	}
+	return c.compile(definition)
+}
+
+func (c *compiler) compile(definition *Definition) (*grammar.Grammar, error) {
+	// declare all symbols first so rules can reference symbols defined later
+	for _, block := range definition.Blocks {
+		if err := c.declare(block); err != nil {
+			return nil, err
+		}
+	}
+	// lexer rules are built after all of them are declared so they can reference lexer rules defined later
+	for _, name := range c.declared {
+		lexerRule, err := c.lexerRule(name, c.definitions[name])
+		if err != nil {
+			return nil, err
+		}
+		c.lexerRules[name] = lexerRule
+	}
+
+	for _, block := range definition.Blocks {
+		var err error
+		switch b := block.(type) {
+		case Rule:
+			err = c.rule(b)
+		case Setting:
+			err = c.setting(b)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.start == nil {
+		return nil, fmt.Errorf("unable to determine start symbol, add a rule or a :%s setting", StartSetting)
+	}
+	g := grammar.New(c.start, c.productions...)
+	g.Ignore = c.ignore
+	return g, nil
+}
+
+func (c *compiler) declare(block Block) error {
+	switch b := block.(type) {
+	case Rule:
+		name := b.QualifiedIdentifier.String()
+		if _, ok := c.definitions[name]; ok {
+			return fmt.Errorf("%s is declared as both a rule and a lexer rule", name)
+		}
+		if _, ok := c.nonTerminals[name]; ok {
+			return nil
+		}
+		nt := grammar.NewNonTerminal(name)
+		c.nonTerminals[name] = nt
+		if c.start == nil {
+			c.start = nt
+		}
+	case LexerRule:
+		name := b.QualifiedIdentifier.String()
+		if _, ok := c.nonTerminals[name]; ok {
+			return fmt.Errorf("%s is declared as both a rule and a lexer rule", name)
+		}
+		if _, ok := c.definitions[name]; ok {
+			return fmt.Errorf("lexer rule %s is declared more than once", name)
+		}
+		c.definitions[name] = b.LexerRuleExpression
+		c.declared = append(c.declared, name)
+	}
+	return nil
+}
+
+func (c *compiler) setting(s Setting) error {
+	name := s.QualifiedIdentifier.String()
+	switch s.SettingIdentifier.Name {
+	case StartSetting:
+		nt, ok := c.nonTerminals[name]
+		if !ok {
+			return fmt.Errorf(":%s setting references undefined rule %s", StartSetting, name)
+		}
+		c.start = nt
+	case IgnoreSetting:
+		lexerRule, ok := c.lexerRules[name]
+		if !ok {
+			return fmt.Errorf(":%s setting references undefined lexer rule %s", IgnoreSetting, name)
+		}
+		c.ignore = append(c.ignore, lexerRule)
+	case NamespaceSetting:
+	default:
+		return fmt.Errorf("unsupported setting :%s", s.SettingIdentifier.Name)
+	}
+	return nil
+}
+
+func (c *compiler) rule(r Rule) error {
+	lhs := c.nonTerminals[r.QualifiedIdentifier.String()]
+	alternatives, err := c.expression(lhs, r.Expression)
+	if err != nil {
+		return err
+	}
+	for _, alternative := range alternatives {
+		if err := c.production(lhs, alternative.attributes, alternative.symbols...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// production adds the production with the disambiguation metadata of the attributes
+func (c *compiler) production(lhs grammar.NonTerminal, attributes []Attribute, rhs ...grammar.Symbol) error {
+	production := grammar.NewProduction(lhs, rhs...)
+	for _, attribute := range attributes {
+		if err := c.attribute(production, attribute); err != nil {
+			return err
+		}
+	}
+	c.productions = append(c.productions, production)
+	return nil
+}
+
+func (c *compiler) attribute(production *grammar.Production, attribute Attribute) error {
+	lhs := production.LeftHandSide
+	switch attribute.Name {
+	case PriorityAttribute:
+		if len(attribute.Arguments) != 1 {
+			return fmt.Errorf("rule %s: @%s requires a number", lhs, attribute.Name)
+		}
+		number, ok := attribute.Arguments[0].(Number)
+		if !ok {
+			return fmt.Errorf("rule %s: @%s requires a number", lhs, attribute.Name)
+		}
+		production.Priority = number.Value
+		return nil
+	case NotFollowedByAttribute:
+		if len(attribute.Arguments) == 0 {
+			return fmt.Errorf("rule %s: @%s requires lexer rules", lhs, attribute.Name)
+		}
+		for _, argument := range attribute.Arguments {
+			switch a := argument.(type) {
+			case Literal:
+				production.NotFollowedBy = append(production.NotFollowedBy, c.literal(a.Value()))
+			case QualifiedIdentifier:
+				lexerRule, ok := c.lexerRules[a.String()]
+				if !ok {
+					return fmt.Errorf("rule %s: @%s references undefined lexer rule %s", lhs, attribute.Name, a)
+				}
+				production.NotFollowedBy = append(production.NotFollowedBy, lexerRule)
+			default:
+				return fmt.Errorf("rule %s: @%s requires lexer rules", lhs, attribute.Name)
+			}
+		}
+		return nil
+	}
+	if len(attribute.Arguments) > 0 {
+		return fmt.Errorf("rule %s: @%s does not take arguments", lhs, attribute.Name)
+	}
+	switch attribute.Name {
+	case LeftAttribute:
+		production.Associativity = grammar.LeftAssociative
+	case RightAttribute:
+		production.Associativity = grammar.RightAssociative
+	case NonAssocAttribute:
+		production.Associativity = grammar.NonAssociative
+	case PreferAttribute:
+		production.Preference = grammar.Prefer
+	case AvoidAttribute:
+		production.Preference = grammar.Avoid
+	case RejectAttribute:
+		production.Reject = true
+	default:
+		return fmt.Errorf("rule %s: unsupported attribute @%s", lhs, attribute.Name)
+	}
+	return nil
+}
+
+func (c *compiler) expression(lhs grammar.NonTerminal, expression Expression) ([]alternative, error) {
+	var alternatives []alternative
+	for {
+		switch e := expression.(type) {
+		case ExpressionTerm:
+			symbols, err := c.term(lhs, e.Term)
+			if err != nil {
+				return nil, err
+			}
+			return append(alternatives, alternative{symbols, e.Attributes}), nil
+		case ExpressionTermExpression:
+			symbols, err := c.term(lhs, e.Term)
+			if err != nil {
+				return nil, err
+			}
+			alternatives = append(alternatives, alternative{symbols, e.Attributes})
+			expression = e.Expression
+		default:
+			return nil, fmt.Errorf("unrecognized expression %T", expression)
+		}
+	}
+}
+
+func (c *compiler) term(lhs grammar.NonTerminal, term Term) ([]grammar.Symbol, error) {
+	var symbols []grammar.Symbol
+	for {
+		var factor Factor
+		var next Term
+		switch t := term.(type) {
+		case TermFactor:
+			factor = t.Factor
+		case TermFactorTerm:
+			factor = t.Factor
+			next = t.Term
+		default:
+			return nil, fmt.Errorf("unrecognized term %T", term)
+		}
+		symbol, err := c.factor(lhs, factor)
+		if err != nil {
+			return nil, err
+		}
+		if symbol != nil {
+			symbols = append(symbols, symbol)
+		}
+		if next == nil {
+			return symbols, nil
+		}
+		term = next
+	}
+}
+
+// factor returns the symbol for the factor, nil is returned for empty literals
+func (c *compiler) factor(lhs grammar.NonTerminal, factor Factor) (grammar.Symbol, error) {
+	switch f := factor.(type) {
+	case QualifiedIdentifier:
+		name := f.String()
+		if nt, ok := c.nonTerminals[name]; ok {
+			return nt, nil
+		}
+		if lexerRule, ok := c.lexerRules[name]; ok {
+			return lexerRule, nil
+		}
+		return nil, fmt.Errorf("rule %s references undefined symbol %s", lhs, name)
+	case Literal:
+		if f.Value() == "" {
+			return nil, nil
+		}
+		return c.literal(f.Value()), nil
+	case RegularExpression:
+		name := fmt.Sprintf("/%s/", f.Pattern)
+		if lexerRule, ok := c.expressions[name]; ok {
+			return lexerRule, nil
+		}
+		lexerRule, err := regex(name, f.Definition)
+		if err != nil {
+			return nil, err
+		}
+		c.expressions[name] = lexerRule
+		return lexerRule, nil
+	case Repetition:
+		// R -> e R | <empty>
+		nt := c.generate(lhs, "repetition")
+		alternatives, err := c.expression(lhs, f.Expression)
+		if err != nil {
+			return nil, err
+		}
+		for _, alternative := range alternatives {
+			rhs := append(alternative.symbols, nt)
+			if err := c.production(nt, alternative.attributes, rhs...); err != nil {
+				return nil, err
+			}
+		}
+		c.productions = append(c.productions, grammar.NewProduction(nt))
+		return nt, nil
+	case Optional:
+		// O -> e | <empty>
+		nt := c.generate(lhs, "optional")
+		alternatives, err := c.expression(lhs, f.Expression)
+		if err != nil {
+			return nil, err
+		}
+		for _, alternative := range alternatives {
+			if err := c.production(nt, alternative.attributes, alternative.symbols...); err != nil {
+				return nil, err
+			}
+		}
+		c.productions = append(c.productions, grammar.NewProduction(nt))
+		return nt, nil
+	case Grouping:
+		// G -> e
+		nt := c.generate(lhs, "grouping")
+		alternatives, err := c.expression(lhs, f.Expression)
+		if err != nil {
+			return nil, err
+		}
+		for _, alternative := range alternatives {
+			if err := c.production(nt, alternative.attributes, alternative.symbols...); err != nil {
+				return nil, err
+			}
+		}
+		return nt, nil
+	}
+	return nil, fmt.Errorf("unrecognized factor %T", factor)
+}
+
+func (c *compiler) literal(value string) grammar.LexerRule {
+	if lexerRule, ok := c.literals[value]; ok {
+		return lexerRule
+	}
+	lexerRule := grammar.NewStringLexerRule(value)
+	c.literals[value] = lexerRule
+	return lexerRule
+}
+
+// generate creates a uniquely named nonterminal for a repetition, optional or grouping
+func (c *compiler) generate(lhs grammar.NonTerminal, kind string) grammar.NonTerminal {
+	for {
+		c.generated++
+		name := fmt.Sprintf("%s_%s_%d", lhs.Name(), kind, c.generated)
+		if _, ok := c.nonTerminals[name]; ok {
+			continue
+		}
+		if _, ok := c.definitions[name]; ok {
+			continue
+		}
+		nt := grammar.NewSyntheticNonTerminal(name)
+		c.nonTerminals[name] = nt
+		return nt
+	}
+}
+
+// lexerRule builds a dfa that matches the strings described by the expression
+func (c *compiler) lexerRule(name string, expression LexerRuleExpression) (grammar.LexerRule, error) {
+	c.expanding[name] = struct{}{}
+	defer delete(c.expanding, name)
+	e, err := c.lexerRuleExpression(name, expression)
+	if err != nil {
+		return nil, err
+	}
+	return regex(name, &re.Definition{Expression: e})
+}
+
+// lexerRuleExpression converts the lexer rule expression into a regular expression
+func (c *compiler) lexerRuleExpression(name string, expression LexerRuleExpression) (re.Expression, error) {
+	switch e := expression.(type) {
+	case LexerRuleExpressionTerm:
+		term, err := c.lexerRuleTerm(name, e.LexerRuleTerm)
+		if err != nil {
+			return nil, err
+		}
+		return re.ExpressionTerm{Term: term}, nil
+	case LexerRuleExpressionTermExpression:
+		term, err := c.lexerRuleTerm(name, e.LexerRuleTerm)
+		if err != nil {
+			return nil, err
+		}
+		next, err := c.lexerRuleExpression(name, e.LexerRuleExpression)
+		if err != nil {
+			return nil, err
+		}
+		return re.ExpressionTermExpression{Term: term, Expression: next}, nil
+	}
+	return nil, fmt.Errorf("unrecognized lexer rule expression %T", expression)
+}
+
+// lexerRuleTerm converts the lexer rule term into a regular expression term
+func (c *compiler) lexerRuleTerm(name string, term LexerRuleTerm) (re.Term, error) {
+	var factors []re.Factor
+	for term != nil {
+		var factor LexerRuleFactor
+		switch t := term.(type) {
+		case LexerRuleTermFactor:
+			factor = t.LexerRuleFactor
+			term = nil
+		case LexerRuleTermFactorTerm:
+			factor = t.LexerRuleFactor
+			term = t.LexerRuleTerm
+		default:
+			return nil, fmt.Errorf("unrecognized lexer rule term %T", term)
+		}
+		switch f := factor.(type) {
+		case Literal:
+			// each character of a literal matches itself
+			for _, ch := range f.Value() {
+				factors = append(factors, re.FactorAtom{
+					Atom: re.AtomCharacter{Character: re.NotMetaCharacter{Char: ch}},
+				})
+			}
+		case RegularExpression:
+			factors = append(factors, re.FactorAtom{
+				Atom: re.AtomExpression{Expression: f.Definition.Expression},
+			})
+		case QualifiedIdentifier:
+			e, err := c.reference(name, f.String())
+			if err != nil {
+				return nil, err
+			}
+			factors = append(factors, re.FactorAtom{
+				Atom: re.AtomExpression{Expression: e},
+			})
+		case LexerRuleRepetition:
+			e, err := c.lexerRuleExpression(name, f.LexerRuleExpression)
+			if err != nil {
+				return nil, err
+			}
+			factors = append(factors, re.FactorAtomIterator{
+				Atom:     re.AtomExpression{Expression: e},
+				Iterator: re.ZeroOrMany,
+			})
+		case LexerRuleOptional:
+			e, err := c.lexerRuleExpression(name, f.LexerRuleExpression)
+			if err != nil {
+				return nil, err
+			}
+			factors = append(factors, re.FactorAtomIterator{
+				Atom:     re.AtomExpression{Expression: e},
+				Iterator: re.ZeroOrOne,
+			})
+		case LexerRuleGrouping:
+			e, err := c.lexerRuleExpression(name, f.LexerRuleExpression)
+			if err != nil {
+				return nil, err
+			}
+			factors = append(factors, re.FactorAtom{
+				Atom: re.AtomExpression{Expression: e},
+			})
+		default:
+			return nil, fmt.Errorf("unrecognized lexer rule factor %T", factor)
+		}
+	}
+	if len(factors) == 0 {
+		return nil, fmt.Errorf("lexer rule %s matches the empty string", name)
+	}
+
+	var result re.Term = re.TermFactor{Factor: factors[len(factors)-1]}
+	for i := len(factors) - 2; i >= 0; i-- {
+		result = re.TermFactorTerm{Factor: factors[i], Term: result}
+	}
+	return result, nil
+}
+
+// reference expands the lexer rule referenced by another lexer rule into a regular expression
+func (c *compiler) reference(name string, referenced string) (re.Expression, error) {
+	definition, ok := c.definitions[referenced]
+	if !ok {
+		if _, ok := c.nonTerminals[referenced]; ok {
+			return nil, fmt.Errorf("lexer rule %s references rule %s, lexer rules can only reference lexer rules", name, referenced)
+		}
+		return nil, fmt.Errorf("lexer rule %s references undefined lexer rule %s", name, referenced)
+	}
+	if _, ok := c.expanding[referenced]; ok {
+		return nil, fmt.Errorf("lexer rule %s references itself", referenced)
+	}
+	c.expanding[referenced] = struct{}{}
+	defer delete(c.expanding, referenced)
+	return c.lexerRuleExpression(referenced, definition)
+}
+
+// Regex builds a dfa lexer rule from the pattern, the token type is the pattern between slashes
+// It can be passed to grammar.RegexCompiler.
+func Regex(pattern string) (grammar.LexerRule, error) {
+	definition, err := re.Parse(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return regex(fmt.Sprintf("/%s/", pattern), definition)
+}
+
+// regex builds a dfa lexer rule from the regular expression
+func regex(name string, definition *re.Definition) (grammar.LexerRule, error) {
+	n, err := nfa.FromRegex(definition)
+	if err != nil {
+		return nil, fmt.Errorf("lexer rule %s: %w", name, err)
+	}
+	d, err := transform.Nfa2Dfa(n, name)
+	if err != nil {
+		return nil, fmt.Errorf("lexer rule %s: %w", name, err)
+	}
+	return dfa.Minimize(d), nil
+}
@@ -0,0 +1,245 @@
+package pdl_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/patrickhuber/go-earley/automata/dfa"
+	"github.com/patrickhuber/go-earley/forest"
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/parser"
+	"github.com/patrickhuber/go-earley/pdl"
+	"github.com/patrickhuber/go-earley/scanner"
+	"github.com/patrickhuber/go-earley/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile(t *testing.T) {
+	t.Run("start", func(t *testing.T) {
+		g := Compile(t, `
+			A = B;
+			B = 'b';
+			:start = B;`)
+		require.Equal(t, "B", g.Start.Name())
+		require.Equal(t, 2, len(g.Productions))
+	})
+	t.Run("first rule is start", func(t *testing.T) {
+		g := Compile(t, `
+			A = B;
+			B = 'b';`)
+		require.Equal(t, "A", g.Start.Name())
+	})
+	t.Run("alternation", func(t *testing.T) {
+		g := Compile(t, `S = 'a' | 'b' S;`)
+		require.Equal(t, 2, len(g.Productions))
+		Accepts(t, g, "b", "b", "a")
+	})
+	t.Run("repetition", func(t *testing.T) {
+		g := Compile(t, `S = 'a' { 'b' };`)
+		for _, p := range g.Productions {
+			require.Equal(t, p.LeftHandSide.Name() != "S", grammar.IsSynthetic(p.LeftHandSide), p.String())
+		}
+		Accepts(t, g, "a")
+		Accepts(t, g, "a", "b", "b", "b")
+	})
+	t.Run("optional", func(t *testing.T) {
+		g := Compile(t, `S = 'a' [ 'b' ] 'c';`)
+		Accepts(t, g, "a", "c")
+		Accepts(t, g, "a", "b", "c")
+	})
+	t.Run("grouping", func(t *testing.T) {
+		g := Compile(t, `S = 'a' ( 'b' | 'c' ) 'd';`)
+		Accepts(t, g, "a", "b", "d")
+		Accepts(t, g, "a", "c", "d")
+	})
+	t.Run("lexer rule", func(t *testing.T) {
+		g := Compile(t, `
+			S = Bit;
+			Bit ~ '0' | '1' | '1' '0';`)
+		lexerRule, ok := g.Productions[0].RightHandSide[0].(*dfa.Dfa)
+		require.True(t, ok)
+		require.Equal(t, "Bit", lexerRule.TokenType())
+		for _, input := range []string{"0", "1", "10"} {
+			require.True(t, Scan(lexerRule, input), input)
+		}
+		Accepts(t, g, "Bit")
+	})
+	t.Run("regular expression lexer rule", func(t *testing.T) {
+		g := Compile(t, `
+			S = Number;
+			Number ~ /[0-9]+/ '.' /[0-9]+/ | /[0-9]+/;`)
+		lexerRule, ok := g.Productions[0].RightHandSide[0].(*dfa.Dfa)
+		require.True(t, ok)
+		require.Equal(t, "Number", lexerRule.TokenType())
+		require.True(t, Scan(lexerRule, "12.5"))
+		require.True(t, Scan(lexerRule, "7"))
+		require.False(t, Scan(lexerRule, "7."))
+	})
+	t.Run("lexer rule reference", func(t *testing.T) {
+		g := Compile(t, `
+			S = Identifier;
+			Identifier ~ ( Letter | '_' ) { Letter | Digit | '_' } [ '?' ];
+			Letter ~ /[a-z]/;
+			Digit ~ /[0-9]/;`)
+		lexerRule, ok := g.Productions[0].RightHandSide[0].(*dfa.Dfa)
+		require.True(t, ok)
+		for _, input := range []string{"a", "_", "a1_b", "ok?"} {
+			require.True(t, Scan(lexerRule, input), input)
+		}
+		for _, input := range []string{"1a", "a??", ""} {
+			require.False(t, Scan(lexerRule, input), input)
+		}
+	})
+	t.Run("invalid lexer rule reference", func(t *testing.T) {
+		for _, test := range []struct {
+			input string
+			err   string
+		}{
+			{`S = A; A ~ 'a' B;`, "lexer rule A references undefined lexer rule B"},
+			{`S = A; A ~ 'a' S;`, "lexer rule A references rule S, lexer rules can only reference lexer rules"},
+			{`S = A; A ~ 'a' [ B ]; B ~ 'b' A;`, "lexer rule A references itself"},
+		} {
+			_, err := CompileString(test.input)
+			require.EqualError(t, err, test.err, test.input)
+		}
+	})
+	t.Run("regular expression factor", func(t *testing.T) {
+		g := Compile(t, `S = /[a-z]+/ ;`)
+		lexerRule, ok := g.Productions[0].RightHandSide[0].(*dfa.Dfa)
+		require.True(t, ok)
+		require.Equal(t, "/[a-z]+/", lexerRule.TokenType())
+		require.True(t, Scan(lexerRule, "abc"))
+	})
+	t.Run("regex", func(t *testing.T) {
+		lexerRule, err := pdl.Regex("[0-9]+")
+		require.NoError(t, err)
+		require.Equal(t, "/[0-9]+/", lexerRule.TokenType())
+		d, ok := lexerRule.(*dfa.Dfa)
+		require.True(t, ok)
+		require.True(t, Scan(d, "42"))
+	})
+	t.Run("calculator", func(t *testing.T) {
+		g := Compile(t, `
+			Calculator = Expression;
+			Expression = Expression '+' Term | Term;
+			Term = Term '*' Factor | Factor;
+			Factor = Number ;
+			Number = Digits;
+			Digits ~ /[0-9]+/ ;
+			Whitespace ~ /[\s]+/ ;
+			:start = Calculator;
+			:ignore = Whitespace;`)
+		require.Equal(t, "Calculator", g.Start.Name())
+		Accepts(t, g, "Digits", "+", "Digits", "*", "Digits")
+		require.Len(t, g.Ignore, 1)
+		require.Equal(t, "Whitespace", g.Ignore[0].TokenType())
+
+		accepted, err := scanner.RunToEnd(scanner.New(parser.New(g), " 1 + 22\n* 333 "))
+		require.NoError(t, err)
+		require.True(t, accepted)
+	})
+	t.Run("attributes", func(t *testing.T) {
+		g := Compile(t, `
+			Expression 
+				= Expression '+' Expression @left @priority(1)
+				| Expression '*' Expression @left @priority(2)
+				| Expression '^' Expression @right @priority(3)
+				| Expression '=' Expression @nonassoc
+				| Identifier
+				| Number;
+			Identifier = Word | 'if' @reject;
+			Number = Digits @notfollowedby('.', Digits) @prefer | Digits @avoid;
+			Word ~ /[a-z]+/ ;
+			Digits ~ /[0-9]+/ ;`)
+		productions := g.Productions
+		require.Equal(t, 1, productions[0].Priority)
+		require.Equal(t, grammar.LeftAssociative, productions[0].Associativity)
+		require.Equal(t, 2, productions[1].Priority)
+		require.Equal(t, grammar.RightAssociative, productions[2].Associativity)
+		require.Equal(t, grammar.NonAssociative, productions[3].Associativity)
+		require.True(t, productions[7].Reject)
+		require.Len(t, productions[8].NotFollowedBy, 2)
+		require.Equal(t, ".", productions[8].NotFollowedBy[0].TokenType())
+		require.Equal(t, "Digits", productions[8].NotFollowedBy[1].TokenType())
+		require.Equal(t, grammar.Prefer, productions[8].Preference)
+		require.Equal(t, grammar.Avoid, productions[9].Preference)
+	})
+	t.Run("disambiguation", func(t *testing.T) {
+		g := Compile(t, `
+			Expression 
+				= Expression '+' Expression @left @priority(1)
+				| Expression '*' Expression @left @priority(2)
+				| Digits;
+			Digits ~ /[0-9]+/ ;
+			Whitespace ~ /[\s]+/ ;
+			:ignore = Whitespace;`)
+		p := parser.New(g)
+		accepted, err := scanner.RunToEnd(scanner.New(p, "1 + 2 * 3 + 4"))
+		require.NoError(t, err)
+		require.True(t, accepted)
+		root, ok := p.GetForestRoot()
+		require.True(t, ok)
+		require.Equal(t, int64(1), forest.CountTrees(root).Int64())
+	})
+	t.Run("invalid attributes", func(t *testing.T) {
+		for _, input := range []string{
+			`S = 'a' @unknown;`,
+			`S = 'a' @priority;`,
+			`S = 'a' @priority('a');`,
+			`S = 'a' @left(1);`,
+			`S = 'a' @notfollowedby(S);`,
+		} {
+			_, err := CompileString(input)
+			require.Error(t, err, input)
+		}
+	})
+	t.Run("undefined symbol", func(t *testing.T) {
+		_, err := CompileString(`S = A;`)
+		require.Error(t, err)
+	})
+	t.Run("undefined start", func(t *testing.T) {
+		_, err := CompileString(`S = 'a'; :start = A;`)
+		require.Error(t, err)
+	})
+	t.Run("rule and lexer rule", func(t *testing.T) {
+		_, err := CompileString(`S = 'a'; S ~ 'a';`)
+		require.Error(t, err)
+	})
+}
+
+func CompileString(input string) (*grammar.Grammar, error) {
+	definition, err := pdl.Parse(strings.NewReader(input))
+	if err != nil {
+		return nil, err
+	}
+	return pdl.Compile(definition)
+}
+
+func Compile(t *testing.T, input string) *grammar.Grammar {
+	g, err := CompileString(input)
+	require.NoError(t, err)
+	return g
+}
+
+// Accepts pulses one token per token type and requires the parser to accept
+func Accepts(t *testing.T, g *grammar.Grammar, tokenTypes ...string) {
+	p := parser.New(g)
+	for i, tokenType := range tokenTypes {
+		tok := token.NewString(grammar.NewStringLexerRule(tokenType), i)
+		ok, err := p.Pulse(tok)
+		require.NoError(t, err)
+		require.True(t, ok, "token %d %s", i, tokenType)
+	}
+	require.True(t, p.Accepted())
+}
+
+// Scan returns true if the lexer rule accepts the entire input
+func Scan(lexerRule *dfa.Dfa, input string) bool {
+	lexeme := dfa.NewLexeme(lexerRule, 0)
+	for _, ch := range input {
+		if !lexeme.Scan(ch) {
+			return false
+		}
+	}
+	return lexeme.Accepted()
+}
@@ -0,0 +1,666 @@
+package pdl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/patrickhuber/go-earley/automata/dfa"
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/token"
+)
+
+// Format writes the grammar as canonical pdl text that Parse and Compile read back into an equivalent grammar
+// String lexer rules are written as literals and lexer rules named /pattern/ as regular expressions.
+// Other lexer rules named with an identifier become lexer rule blocks whose regular expression is recovered from the dfa,
+// so the pattern may be written differently than in the pdl the grammar was compiled from.
+// Synthetic nonterminals used once, like the ones Compile generates, are written back as repetitions, optionals and groupings.
+// Ignored lexer rules without an identifier are named ignore, ignore_2 and so on.
+func Format(g *grammar.Grammar) ([]byte, error) {
+	f := &formatter{
+		nonTerminals: map[string]struct{}{},
+		names:        map[grammar.LexerRule]string{},
+		declared:     map[string]grammar.LexerRule{},
+		inline:       map[string]ebnf{},
+	}
+	if err := f.grammar(g); err != nil {
+		return nil, err
+	}
+	return layout(f.tokens, nil), nil
+}
+
+// FormatSource returns the pdl text in canonical form
+// Comments are kept in front of the token that follows them, or at the end of the line when they follow a token on the same line.
+// The text must parse, so formatting never changes the grammar it describes.
+func FormatSource(source []byte) ([]byte, error) {
+	root, captures, trailing, err := parse(string(source))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := transformDefinition(root); err != nil {
+		return nil, err
+	}
+	var tokens []formatToken
+	for i, capture := range captures {
+		tokens = append(tokens, formatToken{
+			text:     capture.Text,
+			comments: comments(capture.Leading, i == 0),
+		})
+	}
+	return layout(tokens, comments(trailing, len(captures) == 0)), nil
+}
+
+// formatter converts a grammar into pdl tokens
+type formatter struct {
+	nonTerminals map[string]struct{}
+	// names holds the lexer rules written as lexer rule blocks
+	names      map[grammar.LexerRule]string
+	declared   map[string]grammar.LexerRule
+	lexerRules []grammar.LexerRule
+	// inline holds the synthetic rules written where they are used
+	inline map[string]ebnf
+	tokens []formatToken
+}
+
+// ebnf is a synthetic rule written as a repetition, optional or grouping
+type ebnf struct {
+	open         string
+	close        string
+	alternatives []*grammar.Production
+}
+
+func (f *formatter) grammar(g *grammar.Grammar) error {
+	// productions are grouped by the name of the left hand side in the order the rules first appear
+	var rules []string
+	alternatives := map[string][]*grammar.Production{}
+	for _, p := range g.Productions {
+		name := p.LeftHandSide.Name()
+		if _, ok := alternatives[name]; !ok {
+			rules = append(rules, name)
+		}
+		alternatives[name] = append(alternatives[name], p)
+	}
+
+	if err := f.nonTerminal(g.Start); err != nil {
+		return err
+	}
+	for _, p := range g.Productions {
+		if err := f.nonTerminal(p.LeftHandSide); err != nil {
+			return err
+		}
+		for _, symbol := range p.RightHandSide {
+			if nt, ok := symbol.(grammar.NonTerminal); ok {
+				if err := f.nonTerminal(nt); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, p := range g.Productions {
+		for _, symbol := range p.RightHandSide {
+			if lexerRule, ok := symbol.(grammar.LexerRule); ok {
+				if err := f.declare(lexerRule); err != nil {
+					return err
+				}
+			}
+		}
+		for _, lexerRule := range p.NotFollowedBy {
+			if err := f.declare(lexerRule); err != nil {
+				return err
+			}
+		}
+	}
+	f.fold(g, rules, alternatives)
+	var ignore []string
+	for _, lexerRule := range g.Ignore {
+		name, err := f.ignore(lexerRule)
+		if err != nil {
+			return err
+		}
+		ignore = append(ignore, name)
+	}
+
+	f.emit(":"+StartSetting, "=", g.Start.Name(), ";")
+	for _, name := range ignore {
+		f.emit(":"+IgnoreSetting, "=", name, ";")
+	}
+	for _, name := range rules {
+		if _, ok := f.inline[name]; ok {
+			continue
+		}
+		f.emit(name, "=")
+		if err := f.expression(alternatives[name]); err != nil {
+			return err
+		}
+		f.emit(";")
+	}
+	for _, lexerRule := range f.lexerRules {
+		body, err := f.lexerRuleBody(lexerRule)
+		if err != nil {
+			return err
+		}
+		f.emit(f.names[lexerRule], "~", body, ";")
+	}
+	return nil
+}
+
+// fold finds the synthetic rules used once that can be written where they are used
+func (f *formatter) fold(g *grammar.Grammar, rules []string, alternatives map[string][]*grammar.Production) {
+	uses := map[string]int{}
+	for _, p := range g.Productions {
+		for _, symbol := range p.RightHandSide {
+			if nt, ok := symbol.(grammar.NonTerminal); ok && nt.Name() != p.LeftHandSide.Name() {
+				uses[nt.Name()]++
+			}
+		}
+	}
+	for _, name := range rules {
+		productions := alternatives[name]
+		if name == g.Start.Name() || uses[name] != 1 || !grammar.IsSynthetic(productions[0].LeftHandSide) {
+			continue
+		}
+		if e, ok := toEBNF(name, productions); ok {
+			f.inline[name] = e
+		}
+	}
+
+	// synthetic rules that only use each other are written as rules so they are not lost
+	reached := map[string]struct{}{}
+	var reach func(productions []*grammar.Production)
+	reach = func(productions []*grammar.Production) {
+		for _, p := range productions {
+			for _, symbol := range p.RightHandSide {
+				nt, ok := symbol.(grammar.NonTerminal)
+				if !ok {
+					continue
+				}
+				e, ok := f.inline[nt.Name()]
+				if _, seen := reached[nt.Name()]; !ok || seen {
+					continue
+				}
+				reached[nt.Name()] = struct{}{}
+				reach(e.alternatives)
+			}
+		}
+	}
+	for _, name := range rules {
+		if _, ok := f.inline[name]; !ok {
+			reach(alternatives[name])
+		}
+	}
+	for name := range f.inline {
+		if _, ok := reached[name]; !ok {
+			delete(f.inline, name)
+		}
+	}
+}
+
+// toEBNF matches the productions Compile generates for a repetition, optional or grouping
+//
+//	R -> e R | <empty>
+//	O -> e | <empty>
+//	G -> e
+func toEBNF(name string, productions []*grammar.Production) (ebnf, bool) {
+	var empty int
+	var recursive, other []*grammar.Production
+	for _, p := range productions {
+		if len(p.RightHandSide) == 0 {
+			if disambiguates(p) {
+				return ebnf{}, false
+			}
+			empty++
+			continue
+		}
+		count := 0
+		for _, symbol := range p.RightHandSide {
+			if refers(symbol, name) {
+				count++
+			}
+		}
+		last := p.RightHandSide[len(p.RightHandSide)-1]
+		switch {
+		case count == 0:
+			other = append(other, p)
+		case count == 1 && refers(last, name) && len(p.RightHandSide) > 1:
+			q := *p
+			q.RightHandSide = p.RightHandSide[:len(p.RightHandSide)-1]
+			recursive = append(recursive, &q)
+		default:
+			return ebnf{}, false
+		}
+	}
+	switch {
+	case empty == 1 && len(other) == 0 && len(recursive) > 0:
+		return ebnf{open: "{", close: "}", alternatives: recursive}, true
+	case empty == 1 && len(recursive) == 0 && len(other) > 0:
+		return ebnf{open: "[", close: "]", alternatives: other}, true
+	case empty == 0 && len(recursive) == 0 && len(other) > 0:
+		return ebnf{open: "(", close: ")", alternatives: other}, true
+	}
+	return ebnf{}, false
+}
+
+func refers(symbol grammar.Symbol, name string) bool {
+	nt, ok := symbol.(grammar.NonTerminal)
+	return ok && nt.Name() == name
+}
+
+func disambiguates(p *grammar.Production) bool {
+	return p.Priority != 0 ||
+		p.Associativity != grammar.NoAssociativity ||
+		p.Preference != grammar.NoPreference ||
+		p.Reject ||
+		len(p.NotFollowedBy) > 0
+}
+
+func (f *formatter) expression(productions []*grammar.Production) error {
+	for i, p := range productions {
+		if i > 0 {
+			f.emit("|")
+		}
+		if err := f.alternative(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *formatter) nonTerminal(nt grammar.NonTerminal) error {
+	if !isQualifiedIdentifier(nt.Name()) {
+		return fmt.Errorf("nonterminal %q is not a pdl identifier", nt.Name())
+	}
+	f.nonTerminals[nt.Name()] = struct{}{}
+	return nil
+}
+
+// declare adds a lexer rule block for lexer rules named with an identifier
+func (f *formatter) declare(lexerRule grammar.LexerRule) error {
+	if !named(lexerRule) {
+		return nil
+	}
+	return f.add(lexerRule.TokenType(), lexerRule)
+}
+
+func (f *formatter) add(name string, lexerRule grammar.LexerRule) error {
+	if _, ok := f.nonTerminals[name]; ok {
+		return fmt.Errorf("%s is the name of both a nonterminal and a lexer rule", name)
+	}
+	if existing, ok := f.declared[name]; ok {
+		if existing != lexerRule {
+			return fmt.Errorf("more than one lexer rule is named %s", name)
+		}
+		return nil
+	}
+	f.declared[name] = lexerRule
+	f.names[lexerRule] = name
+	f.lexerRules = append(f.lexerRules, lexerRule)
+	return nil
+}
+
+// ignore returns the name of the lexer rule block of an ignored lexer rule
+func (f *formatter) ignore(lexerRule grammar.LexerRule) (string, error) {
+	if name, ok := f.names[lexerRule]; ok {
+		return name, nil
+	}
+	if named(lexerRule) {
+		return lexerRule.TokenType(), f.declare(lexerRule)
+	}
+	name := IgnoreSetting
+	for i := 2; ; i++ {
+		_, nonTerminal := f.nonTerminals[name]
+		_, declared := f.declared[name]
+		if !nonTerminal && !declared {
+			break
+		}
+		name = fmt.Sprintf("%s_%d", IgnoreSetting, i)
+	}
+	return name, f.add(name, lexerRule)
+}
+
+func (f *formatter) alternative(p *grammar.Production) error {
+	if len(p.RightHandSide) == 0 {
+		f.emit(quote(""))
+	}
+	for _, symbol := range p.RightHandSide {
+		switch s := symbol.(type) {
+		case grammar.NonTerminal:
+			e, ok := f.inline[s.Name()]
+			if !ok {
+				f.emit(s.Name())
+				continue
+			}
+			f.emit(e.open)
+			if err := f.expression(e.alternatives); err != nil {
+				return err
+			}
+			f.emit(e.close)
+		case grammar.LexerRule:
+			text, err := f.lexerRule(s)
+			if err != nil {
+				return err
+			}
+			f.emit(text)
+		default:
+			return fmt.Errorf("rule %s: unsupported symbol %T", p.LeftHandSide, symbol)
+		}
+	}
+	return f.attributes(p)
+}
+
+func (f *formatter) attributes(p *grammar.Production) error {
+	if p.Priority < 0 {
+		return fmt.Errorf("rule %s: @%s(%d) is not a pdl number", p.LeftHandSide, PriorityAttribute, p.Priority)
+	}
+	if p.Priority > 0 {
+		f.emit("@"+PriorityAttribute, "(", strconv.Itoa(p.Priority), ")")
+	}
+	switch p.Associativity {
+	case grammar.LeftAssociative:
+		f.emit("@" + LeftAttribute)
+	case grammar.RightAssociative:
+		f.emit("@" + RightAttribute)
+	case grammar.NonAssociative:
+		f.emit("@" + NonAssocAttribute)
+	}
+	switch p.Preference {
+	case grammar.Prefer:
+		f.emit("@" + PreferAttribute)
+	case grammar.Avoid:
+		f.emit("@" + AvoidAttribute)
+	}
+	if p.Reject {
+		f.emit("@" + RejectAttribute)
+	}
+	if len(p.NotFollowedBy) == 0 {
+		return nil
+	}
+	f.emit("@"+NotFollowedByAttribute, "(")
+	for i, lexerRule := range p.NotFollowedBy {
+		if i > 0 {
+			f.emit(",")
+		}
+		switch {
+		case lexerRule.LexerRuleType() == grammar.StringLexerRuleType:
+			f.emit(quote(lexerRule.TokenType()))
+		case named(lexerRule):
+			f.emit(lexerRule.TokenType())
+		default:
+			return fmt.Errorf("rule %s: @%s argument %s is neither a literal nor a named lexer rule", p.LeftHandSide, NotFollowedByAttribute, lexerRule)
+		}
+	}
+	f.emit(")")
+	return nil
+}
+
+// lexerRule returns the text of a lexer rule used in a rule
+func (f *formatter) lexerRule(lexerRule grammar.LexerRule) (string, error) {
+	if s, ok := lexerRule.(*grammar.StringLexerRule); ok {
+		return quote(s.Value), nil
+	}
+	if name, ok := f.names[lexerRule]; ok {
+		return name, nil
+	}
+	if isRegularExpression(lexerRule) {
+		return lexerRule.TokenType(), nil
+	}
+	p, err := Pattern(lexerRule)
+	if err != nil {
+		return "", err
+	}
+	return "/" + p + "/", nil
+}
+
+// lexerRuleBody returns the text of the expression of a lexer rule block
+func (f *formatter) lexerRuleBody(lexerRule grammar.LexerRule) (string, error) {
+	if s, ok := lexerRule.(*grammar.StringLexerRule); ok {
+		return quote(s.Value), nil
+	}
+	if isRegularExpression(lexerRule) {
+		return lexerRule.TokenType(), nil
+	}
+	p, err := Pattern(lexerRule)
+	if err != nil {
+		return "", err
+	}
+	return "/" + p + "/", nil
+}
+
+func (f *formatter) emit(texts ...string) {
+	for _, text := range texts {
+		f.tokens = append(f.tokens, formatToken{text: text})
+	}
+}
+
+// named returns true if the lexer rule is written as a lexer rule block named by its token type
+func named(lexerRule grammar.LexerRule) bool {
+	return lexerRule.LexerRuleType() != grammar.StringLexerRuleType && isQualifiedIdentifier(lexerRule.TokenType())
+}
+
+// isRegularExpression returns true for the dfa of a regular expression, its token type is the pattern between slashes
+func isRegularExpression(lexerRule grammar.LexerRule) bool {
+	tokenType := lexerRule.TokenType()
+	return lexerRule.LexerRuleType() == dfa.LexerRuleType &&
+		len(tokenType) > 2 &&
+		strings.HasPrefix(tokenType, "/") &&
+		strings.HasSuffix(tokenType, "/")
+}
+
+func isQualifiedIdentifier(name string) bool {
+	for _, identifier := range strings.Split(name, ".") {
+		if identifier == "" {
+			return false
+		}
+		for i, ch := range identifier {
+			letter := unicode.IsLetter(ch) || ch == '_'
+			if !letter && (i == 0 || !unicode.IsNumber(ch)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// quote encloses the value in single quotes, or double quotes if only those avoid escaping
+func quote(value string) string {
+	q := "'"
+	if strings.Contains(value, "'") && !strings.Contains(value, `"`) {
+		q = `"`
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, q, `\`+q)
+	return q + value + q
+}
+
+// formatToken is the text of a pdl token and the comments before it
+type formatToken struct {
+	text     string
+	comments []comment
+}
+
+type comment struct {
+	text string
+	// newline is true if the comment starts on its own line
+	newline bool
+	// blank is true if an empty line follows the comment
+	blank bool
+}
+
+// comments returns the comments in the trivia between two tokens, start is true for the trivia before the first token
+func comments(trivia []token.Token, start bool) []comment {
+	var result []comment
+	newlines := 0
+	if start {
+		newlines = 1
+	}
+	for _, tok := range trivia {
+		capture, ok := tok.(*token.Capture)
+		if !ok {
+			continue
+		}
+		switch capture.TokenType() {
+		case LineCommentTokenType, BlockCommentTokenType:
+			if len(result) > 0 && newlines > 1 {
+				result[len(result)-1].blank = true
+			}
+			text := strings.TrimRightFunc(capture.Text, unicode.IsSpace)
+			result = append(result, comment{text: text, newline: newlines > 0})
+			newlines = 0
+		default:
+			newlines += strings.Count(capture.Text, "\n")
+		}
+	}
+	if len(result) > 0 && newlines > 1 {
+		result[len(result)-1].blank = true
+	}
+	return result
+}
+
+const indent = "    "
+
+// printer lays out pdl tokens
+// blocks are separated by an empty line, except for consecutive settings, and each alternative of a rule with more than one starts on its own line
+//
+//	:start = S;
+//	:ignore = whitespace;
+//
+//	S =
+//	    S '+' T @left
+//	    | T;
+type printer struct {
+	builder strings.Builder
+	// pending is true when a line comment ends the current line
+	pending bool
+	// setting is true if the last block was a setting
+	setting bool
+}
+
+func layout(tokens []formatToken, trailing []comment) []byte {
+	p := &printer{}
+	for len(tokens) > 0 {
+		end := 0
+		for end < len(tokens)-1 && tokens[end].text != ";" {
+			end++
+		}
+		p.block(tokens[:end+1])
+		tokens = tokens[end+1:]
+	}
+	for i, c := range trailing {
+		switch {
+		case p.builder.Len() == 0:
+		case !c.newline:
+			p.write(" ")
+		case i > 0 && trailing[i-1].blank:
+			p.write("\n\n")
+		default:
+			p.write("\n")
+		}
+		p.write(c.text)
+	}
+	if p.builder.Len() > 0 {
+		p.write("\n")
+	}
+	return []byte(p.builder.String())
+}
+
+func (p *printer) block(tokens []formatToken) {
+	first := tokens[0]
+	setting := strings.HasPrefix(first.text, ":")
+
+	// comments on the line of the previous block stay there, the others are written above the block
+	var leading []comment
+	for _, c := range first.comments {
+		if c.newline || p.builder.Len() == 0 {
+			leading = append(leading, c)
+			continue
+		}
+		p.write(" " + c.text)
+	}
+	if p.builder.Len() > 0 {
+		p.write("\n")
+		if !setting || !p.setting {
+			p.write("\n")
+		}
+	}
+	for _, c := range leading {
+		p.write(c.text + "\n")
+		if c.blank {
+			p.write("\n")
+		}
+	}
+	p.setting = setting
+	p.pending = false
+	p.write(first.text)
+
+	body := len(tokens)
+	multiple := false
+	depth := 0
+	for i, tok := range tokens {
+		switch tok.text {
+		case "=", "~":
+			if body == len(tokens) {
+				body = i + 1
+			}
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		case "|":
+			multiple = multiple || depth == 0
+		}
+	}
+
+	// stack holds an entry per open bracket, true for the parentheses around attribute arguments
+	var stack []bool
+	previous := first.text
+	for i := 1; i < len(tokens); i++ {
+		tok := tokens[i]
+		attribute := len(stack) > 0 && stack[len(stack)-1]
+		newline := multiple && (i == body || (tok.text == "|" && len(stack) == 0))
+		space := true
+		switch {
+		case tok.text == ";", tok.text == ",", tok.text == ".", previous == ".":
+			space = false
+		case tok.text == "(" && strings.HasPrefix(previous, "@"):
+			space = false
+		case attribute && (previous == "(" || tok.text == ")"):
+			space = false
+		}
+		switch tok.text {
+		case "(", "[", "{":
+			stack = append(stack, tok.text == "(" && strings.HasPrefix(previous, "@"))
+		case ")", "]", "}":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+		p.token(tok, newline, space)
+		previous = tok.text
+	}
+}
+
+func (p *printer) token(tok formatToken, newline, space bool) {
+	for _, c := range tok.comments {
+		if c.newline {
+			p.write("\n" + indent + c.text)
+			p.pending = true
+			continue
+		}
+		p.write(" " + c.text)
+		if strings.HasPrefix(c.text, "//") {
+			p.pending = true
+		}
+	}
+	switch {
+	case newline || p.pending:
+		p.write("\n" + indent)
+	case space:
+		p.write(" ")
+	}
+	p.pending = false
+	p.write(tok.text)
+}
+
+func (p *printer) write(text string) {
+	p.builder.WriteString(text)
+}
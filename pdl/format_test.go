@@ -0,0 +1,203 @@
+package pdl_test
+
+import (
+	"testing"
+
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/parser"
+	"github.com/patrickhuber/go-earley/pdl"
+	"github.com/patrickhuber/go-earley/scanner"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	format := func(t *testing.T, g *grammar.Grammar) string {
+		output, err := pdl.Format(g)
+		require.NoError(t, err)
+		return string(output)
+	}
+
+	t.Run("calculator", func(t *testing.T) {
+		g := Compile(t, `
+			Calculator = Expression;
+			Expression = Expression '+' Term @left | Term;
+			Term = Term '*' Factor @left | Factor;
+			Factor = Number | '(' Expression ')' @prefer;
+			Number ~ /[0-9]+/ '.' /[0-9]+/ | /[0-9]+/;
+			Whitespace ~ /[\s]+/;
+			:ignore = Whitespace;`)
+		require.Equal(t, `:start = Calculator;
+:ignore = Whitespace;
+
+Calculator = Expression;
+
+Expression =
+    Expression '+' Term @left
+    | Term;
+
+Term =
+    Term '*' Factor @left
+    | Factor;
+
+Factor =
+    Number
+    | '(' Expression ')' @prefer;
+
+Number ~ /[0-9]+(\.[0-9]+)?/;
+
+Whitespace ~ /\s+/;
+`, format(t, g))
+	})
+	t.Run("ebnf", func(t *testing.T) {
+		g := Compile(t, `S = 'a' { 'b' | 'c' } [ /[x-z]+/ ] ( 'd' | "it's" );`)
+		require.Equal(t, `:start = S;
+
+S = 'a' { 'b' | 'c' } [ /[x-z]+/ ] ( 'd' | "it's" );
+`, format(t, g))
+	})
+	t.Run("attributes", func(t *testing.T) {
+		g := Compile(t, `
+			S = A @priority(2) @nonassoc @avoid | A 'x' @notfollowedby('y', Y) @reject | '';
+			A = 'a' @right;
+			Y ~ 'y' 'y';`)
+		require.Equal(t, `:start = S;
+
+S =
+    A @priority(2) @nonassoc @avoid
+    | A 'x' @reject @notfollowedby('y', Y)
+    | '';
+
+A = 'a' @right;
+
+Y ~ /yy/;
+`, format(t, g))
+	})
+	t.Run("word shorthand", func(t *testing.T) {
+		g := Compile(t, `S = Id; Id ~ /[\w-]+/; Digit ~ /\d/; :ignore = Digit;`)
+		require.Contains(t, format(t, g), "Id ~ /[\\w\\-]+/;")
+		require.Contains(t, format(t, g), "Digit ~ /\\d/;")
+	})
+	t.Run("round trip", func(t *testing.T) {
+		for _, input := range []string{
+			`S = 'a' { 'b' } [ 'c' ] ( 'd' | 'e' ); :start = S;`,
+			`S = Id | Id '.' S; Id ~ /[a-zA-Z_][a-zA-Z0-9_]*/; Ws ~ /[ \t\n]+/; :ignore = Ws;`,
+			`S = /[^\/\]]/ | '\'' | "\\";`,
+		} {
+			first := format(t, Compile(t, input))
+			second := format(t, Compile(t, first))
+			require.Equal(t, first, second, input)
+		}
+	})
+	t.Run("pdl grammar", func(t *testing.T) {
+		output := format(t, pdl.Grammar())
+		require.Less(t, len(output), 4096)
+		require.Contains(t, output, `identifier ~ /[^\W\d]\w*/;`)
+
+		// the compiled grammar parses its own source and formats like the round trip
+		g := Compile(t, output)
+		second := format(t, g)
+		require.Equal(t, second, format(t, Compile(t, second)))
+		accepted, err := scanner.RunToEnd(scanner.New(parser.New(g), output))
+		require.NoError(t, err)
+		require.True(t, accepted)
+	})
+	t.Run("letters", func(t *testing.T) {
+		g := Compile(t, `S = Letter; Letter ~ /[^\W\d_]+/;`)
+		require.Contains(t, format(t, g), `Letter ~ /[^\W\d_]+/;`)
+	})
+	t.Run("builder", func(t *testing.T) {
+		b := grammar.NewBuilder(grammar.RegexCompiler(pdl.Regex))
+		b.Rule("List").Seq(grammar.Literal("["), grammar.Many(grammar.Ref("Item")), grammar.Literal("]"))
+		b.Rule("Item").Seq(grammar.Regex("[0-9]+")).Or(grammar.Ref("List"))
+		g, err := b.Build("List")
+		require.NoError(t, err)
+		output := format(t, g)
+		require.Equal(t, `:start = List;
+
+List = '[' { Item } ']';
+
+Item =
+    /[0-9]+/
+    | List;
+`, output)
+		Accepts(t, Compile(t, output), "[", "/[0-9]+/", "[", "]", "]")
+	})
+	t.Run("unnamed ignore", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S")
+		g := grammar.New(S, grammar.NewProduction(S, grammar.NewStringLexerRule("a")))
+		g.Ignore = []grammar.LexerRule{grammar.NewStringLexerRule(" ")}
+		require.Equal(t, `:start = S;
+:ignore = ignore;
+
+S = 'a';
+
+ignore ~ ' ';
+`, format(t, g))
+	})
+	t.Run("invalid identifier", func(t *testing.T) {
+		S := grammar.NewNonTerminal("S'")
+		_, err := pdl.Format(grammar.New(S, grammar.NewProduction(S)))
+		require.Error(t, err)
+	})
+}
+
+func TestFormatSource(t *testing.T) {
+	t.Run("layout", func(t *testing.T) {
+		output, err := pdl.FormatSource([]byte(`// calculator
+
+
+Calculator= Expression ;   // start
+Expression =Expression '+' Term @left|Term;
+/* terms */ Term = Term '*' Factor
+	// a factor
+	| Factor @notfollowedby( '.' , Digits );Factor = Digits | ( Calculator ) ;
+Digits~/[0-9]+/;:start=Calculator;:ignore
+= Whitespace ; Whitespace ~ /[\s]+/ ;
+// end`))
+		require.NoError(t, err)
+		require.Equal(t, `// calculator
+
+Calculator = Expression; // start
+
+Expression =
+    Expression '+' Term @left
+    | Term;
+
+/* terms */
+Term =
+    Term '*' Factor
+    // a factor
+    | Factor @notfollowedby('.', Digits);
+
+Factor =
+    Digits
+    | ( Calculator );
+
+Digits ~ /[0-9]+/;
+
+:start = Calculator;
+:ignore = Whitespace;
+
+Whitespace ~ /[\s]+/;
+// end
+`, string(output))
+
+		again, err := pdl.FormatSource(output)
+		require.NoError(t, err)
+		require.Equal(t, string(output), string(again))
+	})
+	t.Run("qualified identifier", func(t *testing.T) {
+		output, err := pdl.FormatSource([]byte(`S = a . b c;`))
+		require.NoError(t, err)
+		require.Equal(t, "S = a.b c;\n", string(output))
+	})
+	t.Run("line comment inside alternative", func(t *testing.T) {
+		output, err := pdl.FormatSource([]byte("S = 'a' // after a\n 'b';"))
+		require.NoError(t, err)
+		require.Equal(t, "S = 'a' // after a\n    'b';\n", string(output))
+	})
+	t.Run("invalid", func(t *testing.T) {
+		_, err := pdl.FormatSource([]byte(`S = 'a'`))
+		require.Error(t, err)
+	})
+}
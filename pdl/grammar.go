@@ -0,0 +1,413 @@
+package pdl
+
+import (
+	"github.com/patrickhuber/go-earley/automata/dfa"
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/terminal"
+)
+
+// token types of the pdl lexer rules
+const (
+	IdentifierTokenType          = "identifier"
+	SettingIdentifierTokenType   = "setting_identifier"
+	SingleQuoteStringTokenType   = "single_quote_string"
+	DoubleQuoteStringTokenType   = "double_quote_string"
+	RegularExpressionTokenType   = "regular_expression"
+	AttributeIdentifierTokenType = "attribute_identifier"
+	NumberTokenType              = "number"
+	WhitespaceTokenType          = "whitespace"
+	LineCommentTokenType         = "line_comment"
+	BlockCommentTokenType        = "block_comment"
+)
+
+// Grammar returns the grammar for pdl described in pdl.pdl
+// Whitespace and comments are ignored lexer rules.
+func Grammar() *grammar.Grammar {
+	definition := nonTerminal("definition")
+	block := nonTerminal("block")
+	rule := nonTerminal("rule")
+	setting := nonTerminal("setting")
+	lexerRule := nonTerminal("lexer_rule")
+	expression := nonTerminal("expression")
+	term := nonTerminal("term")
+	factor := nonTerminal("factor")
+	literal := nonTerminal("literal")
+	repetition := nonTerminal("repetition")
+	optional := nonTerminal("optional")
+	grouping := nonTerminal("grouping")
+	qualifiedIdentifier := nonTerminal("qualified_identifier")
+	lexerRuleExpression := nonTerminal("lexer_rule_expression")
+	lexerRuleTerm := nonTerminal("lexer_rule_term")
+	lexerRuleFactor := nonTerminal("lexer_rule_factor")
+	attributes := nonTerminal("attributes")
+	attribute := nonTerminal("attribute")
+	arguments := nonTerminal("arguments")
+	argument := nonTerminal("argument")
+	lexerRuleRepetition := nonTerminal("lexer_rule_repetition")
+	lexerRuleOptional := nonTerminal("lexer_rule_optional")
+	lexerRuleGrouping := nonTerminal("lexer_rule_grouping")
+
+	equal := str("=")
+	semicolon := str(";")
+	tilde := str("~")
+	pipe := str("|")
+	dot := str(".")
+	openBrace := str("{")
+	closeBrace := str("}")
+	openBracket := str("[")
+	closeBracket := str("]")
+	openParen := str("(")
+	closeParen := str(")")
+	comma := str(",")
+
+	identifier := identifierRule(IdentifierTokenType)
+	settingIdentifier := settingIdentifierRule()
+	singleQuoteString := quotedRule(SingleQuoteStringTokenType, '\'')
+	doubleQuoteString := quotedRule(DoubleQuoteStringTokenType, '"')
+	regularExpression := regularExpressionRule()
+	attributeIdentifier := attributeIdentifierRule()
+	number := numberRule()
+
+	productions := []*grammar.Production{
+		// definition
+		production(definition, block),
+		production(definition, block, definition),
+		// block
+		production(block, rule),
+		production(block, setting),
+		production(block, lexerRule),
+		// rule
+		production(rule, qualifiedIdentifier, equal, expression, semicolon),
+		// setting
+		production(setting, settingIdentifier, equal, qualifiedIdentifier, semicolon),
+		production(setting, settingIdentifier, qualifiedIdentifier, semicolon),
+		// lexer_rule
+		production(lexerRule, qualifiedIdentifier, tilde, lexerRuleExpression, semicolon),
+		// expression
+		production(expression, term),
+		production(expression, term, attributes),
+		production(expression, term, pipe, expression),
+		production(expression, term, attributes, pipe, expression),
+		// term
+		production(term, factor),
+		production(term, factor, term),
+		// factor
+		production(factor, qualifiedIdentifier),
+		production(factor, literal),
+		production(factor, regularExpression),
+		production(factor, repetition),
+		production(factor, optional),
+		production(factor, grouping),
+		// literal
+		production(literal, singleQuoteString),
+		production(literal, doubleQuoteString),
+		// repetition
+		production(repetition, openBrace, expression, closeBrace),
+		// optional
+		production(optional, openBracket, expression, closeBracket),
+		// grouping
+		production(grouping, openParen, expression, closeParen),
+		// qualified_identifier
+		production(qualifiedIdentifier, identifier),
+		production(qualifiedIdentifier, identifier, dot, qualifiedIdentifier),
+		// lexer_rule_expression
+		production(lexerRuleExpression, lexerRuleTerm),
+		production(lexerRuleExpression, lexerRuleTerm, pipe, lexerRuleExpression),
+		// lexer_rule_term
+		production(lexerRuleTerm, lexerRuleFactor),
+		production(lexerRuleTerm, lexerRuleFactor, lexerRuleTerm),
+		// lexer_rule_factor
+		production(lexerRuleFactor, literal),
+		production(lexerRuleFactor, regularExpression),
+		production(lexerRuleFactor, qualifiedIdentifier),
+		production(lexerRuleFactor, lexerRuleRepetition),
+		production(lexerRuleFactor, lexerRuleOptional),
+		production(lexerRuleFactor, lexerRuleGrouping),
+		// lexer_rule_repetition
+		production(lexerRuleRepetition, openBrace, lexerRuleExpression, closeBrace),
+		// lexer_rule_optional
+		production(lexerRuleOptional, openBracket, lexerRuleExpression, closeBracket),
+		// lexer_rule_grouping
+		production(lexerRuleGrouping, openParen, lexerRuleExpression, closeParen),
+		// attributes
+		production(attributes, attribute),
+		production(attributes, attribute, attributes),
+		// attribute
+		production(attribute, attributeIdentifier),
+		production(attribute, attributeIdentifier, openParen, arguments, closeParen),
+		// arguments
+		production(arguments, argument),
+		production(arguments, argument, comma, arguments),
+		// argument
+		production(argument, qualifiedIdentifier),
+		production(argument, literal),
+		production(argument, number),
+	}
+	g := grammar.New(definition, productions...)
+	g.Ignore = []grammar.LexerRule{
+		whitespaceRule(),
+		lineCommentRule(),
+		blockCommentRule(),
+	}
+	return g
+}
+
+func production(lhs grammar.NonTerminal, rhs ...grammar.Symbol) *grammar.Production {
+	return grammar.NewProduction(lhs, rhs...)
+}
+
+func nonTerminal(name string) grammar.NonTerminal {
+	return grammar.NewNonTerminal(name)
+}
+
+func str(value string) grammar.LexerRule {
+	return grammar.NewStringLexerRule(value)
+}
+
+// identifierRule matches /[a-zA-Z_][a-zA-Z0-9_]*/
+func identifierRule(name string) grammar.LexerRule {
+	start := &dfa.State{}
+	body := &dfa.State{Final: true}
+	start.Transitions = append(start.Transitions, dfa.Transition{
+		Terminal: identifierStart(),
+		Target:   body,
+	})
+	body.Transitions = append(body.Transitions, dfa.Transition{
+		Terminal: identifierPart(),
+		Target:   body,
+	})
+	return dfa.NewDfa(start, name)
+}
+
+// settingIdentifierRule matches /:[a-zA-Z_][a-zA-Z0-9_]*/
+func settingIdentifierRule() grammar.LexerRule {
+	start := &dfa.State{}
+	colon := &dfa.State{}
+	body := &dfa.State{Final: true}
+	start.Transitions = append(start.Transitions, dfa.Transition{
+		Terminal: terminal.NewCharacter(':'),
+		Target:   colon,
+	})
+	colon.Transitions = append(colon.Transitions, dfa.Transition{
+		Terminal: identifierStart(),
+		Target:   body,
+	})
+	body.Transitions = append(body.Transitions, dfa.Transition{
+		Terminal: identifierPart(),
+		Target:   body,
+	})
+	return dfa.NewDfa(start, SettingIdentifierTokenType)
+}
+
+// attributeIdentifierRule matches /@[a-zA-Z_][a-zA-Z0-9_]*/
+func attributeIdentifierRule() grammar.LexerRule {
+	start := &dfa.State{}
+	at := &dfa.State{}
+	body := &dfa.State{Final: true}
+	start.Transitions = append(start.Transitions, dfa.Transition{
+		Terminal: terminal.NewCharacter('@'),
+		Target:   at,
+	})
+	at.Transitions = append(at.Transitions, dfa.Transition{
+		Terminal: identifierStart(),
+		Target:   body,
+	})
+	body.Transitions = append(body.Transitions, dfa.Transition{
+		Terminal: identifierPart(),
+		Target:   body,
+	})
+	return dfa.NewDfa(start, AttributeIdentifierTokenType)
+}
+
+// numberRule matches /[0-9]+/
+func numberRule() grammar.LexerRule {
+	start := &dfa.State{}
+	body := &dfa.State{Final: true}
+	start.Transitions = append(start.Transitions, dfa.Transition{
+		Terminal: terminal.NewNumber(),
+		Target:   body,
+	})
+	body.Transitions = append(body.Transitions, dfa.Transition{
+		Terminal: terminal.NewNumber(),
+		Target:   body,
+	})
+	return dfa.NewDfa(start, NumberTokenType)
+}
+
+// quotedRule matches text enclosed in the quote character where a backslash escapes the next character
+func quotedRule(name string, quote rune) grammar.LexerRule {
+	start := &dfa.State{}
+	body := &dfa.State{}
+	escape := &dfa.State{}
+	end := &dfa.State{Final: true}
+	start.Transitions = append(start.Transitions, dfa.Transition{
+		Terminal: terminal.NewCharacter(quote),
+		Target:   body,
+	})
+	body.Transitions = append(body.Transitions,
+		dfa.Transition{
+			Terminal: terminal.NewCharacter(quote),
+			Target:   end,
+		},
+		dfa.Transition{
+			Terminal: terminal.NewCharacter('\\'),
+			Target:   escape,
+		},
+		dfa.Transition{
+			Terminal: terminal.NewNegate(
+				terminal.NewSet([]grammar.Terminal{
+					terminal.NewCharacter(quote),
+					terminal.NewCharacter('\\'),
+				})),
+			Target: body,
+		})
+	escape.Transitions = append(escape.Transitions, dfa.Transition{
+		Terminal: terminal.NewAny(),
+		Target:   body,
+	})
+	return dfa.NewDfa(start, name)
+}
+
+// regularExpressionRule matches a pattern enclosed in slashes
+// the pattern can not start with a slash or star, so // and /* always start a comment
+func regularExpressionRule() grammar.LexerRule {
+	start := &dfa.State{}
+	first := &dfa.State{}
+	body := &dfa.State{}
+	escape := &dfa.State{}
+	end := &dfa.State{Final: true}
+	start.Transitions = append(start.Transitions, dfa.Transition{
+		Terminal: terminal.NewCharacter('/'),
+		Target:   first,
+	})
+	first.Transitions = append(first.Transitions,
+		dfa.Transition{
+			Terminal: terminal.NewCharacter('\\'),
+			Target:   escape,
+		},
+		dfa.Transition{
+			Terminal: terminal.NewNegate(
+				terminal.NewSet([]grammar.Terminal{
+					terminal.NewCharacter('/'),
+					terminal.NewCharacter('*'),
+					terminal.NewCharacter('\\'),
+				})),
+			Target: body,
+		})
+	body.Transitions = append(body.Transitions,
+		dfa.Transition{
+			Terminal: terminal.NewCharacter('/'),
+			Target:   end,
+		},
+		dfa.Transition{
+			Terminal: terminal.NewCharacter('\\'),
+			Target:   escape,
+		},
+		dfa.Transition{
+			Terminal: terminal.NewNegate(
+				terminal.NewSet([]grammar.Terminal{
+					terminal.NewCharacter('/'),
+					terminal.NewCharacter('\\'),
+				})),
+			Target: body,
+		})
+	escape.Transitions = append(escape.Transitions, dfa.Transition{
+		Terminal: terminal.NewAny(),
+		Target:   body,
+	})
+	return dfa.NewDfa(start, RegularExpressionTokenType)
+}
+
+// whitespaceRule matches /\s+/
+func whitespaceRule() grammar.LexerRule {
+	start := &dfa.State{}
+	body := &dfa.State{Final: true}
+	start.Transitions = append(start.Transitions, dfa.Transition{
+		Terminal: terminal.NewWhitespace(),
+		Target:   body,
+	})
+	body.Transitions = append(body.Transitions, dfa.Transition{
+		Terminal: terminal.NewWhitespace(),
+		Target:   body,
+	})
+	return dfa.NewDfa(start, WhitespaceTokenType)
+}
+
+// lineCommentRule matches a comment from // to the end of the line
+func lineCommentRule() grammar.LexerRule {
+	start := &dfa.State{}
+	slash := &dfa.State{}
+	body := &dfa.State{Final: true}
+	start.Transitions = append(start.Transitions, dfa.Transition{
+		Terminal: terminal.NewCharacter('/'),
+		Target:   slash,
+	})
+	slash.Transitions = append(slash.Transitions, dfa.Transition{
+		Terminal: terminal.NewCharacter('/'),
+		Target:   body,
+	})
+	body.Transitions = append(body.Transitions, dfa.Transition{
+		Terminal: terminal.NewNegate(terminal.NewCharacter('\n')),
+		Target:   body,
+	})
+	return dfa.NewDfa(start, LineCommentTokenType)
+}
+
+// blockCommentRule matches a comment from /* to the next */
+func blockCommentRule() grammar.LexerRule {
+	start := &dfa.State{}
+	slash := &dfa.State{}
+	body := &dfa.State{}
+	star := &dfa.State{}
+	end := &dfa.State{Final: true}
+	start.Transitions = append(start.Transitions, dfa.Transition{
+		Terminal: terminal.NewCharacter('/'),
+		Target:   slash,
+	})
+	slash.Transitions = append(slash.Transitions, dfa.Transition{
+		Terminal: terminal.NewCharacter('*'),
+		Target:   body,
+	})
+	body.Transitions = append(body.Transitions,
+		dfa.Transition{
+			Terminal: terminal.NewCharacter('*'),
+			Target:   star,
+		},
+		dfa.Transition{
+			Terminal: terminal.NewNegate(terminal.NewCharacter('*')),
+			Target:   body,
+		})
+	star.Transitions = append(star.Transitions,
+		dfa.Transition{
+			Terminal: terminal.NewCharacter('/'),
+			Target:   end,
+		},
+		dfa.Transition{
+			Terminal: terminal.NewCharacter('*'),
+			Target:   star,
+		},
+		dfa.Transition{
+			Terminal: terminal.NewNegate(
+				terminal.NewSet([]grammar.Terminal{
+					terminal.NewCharacter('/'),
+					terminal.NewCharacter('*'),
+				})),
+			Target: body,
+		})
+	return dfa.NewDfa(start, BlockCommentTokenType)
+}
+
+func identifierStart() grammar.Terminal {
+	return terminal.NewSet([]grammar.Terminal{
+		terminal.NewLetter(),
+		terminal.NewCharacter('_'),
+	})
+}
+
+func identifierPart() grammar.Terminal {
+	return terminal.NewSet([]grammar.Terminal{
+		terminal.NewLetter(),
+		terminal.NewNumber(),
+		terminal.NewCharacter('_'),
+	})
+}
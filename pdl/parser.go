@@ -0,0 +1,432 @@
+package pdl
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/patrickhuber/go-earley/forest"
+	"github.com/patrickhuber/go-earley/parser"
+	"github.com/patrickhuber/go-earley/re"
+	"github.com/patrickhuber/go-earley/scanner"
+	"github.com/patrickhuber/go-earley/token"
+)
+
+// Parse reads pdl text from the reader and returns the definition
+func Parse(reader io.Reader) (*Definition, error) {
+	input, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	root, _, _, err := parse(string(input))
+	if err != nil {
+		return nil, err
+	}
+	return transformDefinition(root)
+}
+
+// parse returns the forest root, the tokens of the pdl text and the trivia after the last token
+// whitespace and comments are kept as the leading trivia of the token that follows them
+func parse(input string) (forest.Node, []*token.Capture, []token.Token, error) {
+	p := parser.New(Grammar())
+	s := scanner.New(p, input, scanner.KeepTrivia(true))
+	accepted, err := scanner.RunToEnd(s)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !accepted {
+		return nil, nil, nil, fmt.Errorf("failed to parse pdl")
+	}
+	root, ok := p.GetForestRoot()
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("failed to get forest root")
+	}
+	return root, captures(root), s.Trivia(), nil
+}
+
+// captures returns the tokens of the first derivation of the node in input order
+func captures(node forest.Node) []*token.Capture {
+	if tok, ok := node.(*forest.Token); ok {
+		capture, ok := tok.Token.(*token.Capture)
+		if !ok {
+			return nil
+		}
+		return []*token.Capture{capture}
+	}
+	var result []*token.Capture
+	for _, child := range children(node) {
+		result = append(result, captures(child)...)
+	}
+	return result
+}
+
+func transformDefinition(node forest.Node) (*Definition, error) {
+	definition := &Definition{}
+	for {
+		nodes := children(node)
+		block, err := transformBlock(nodes[0])
+		if err != nil {
+			return nil, err
+		}
+		definition.Blocks = append(definition.Blocks, block)
+		if len(nodes) == 1 {
+			return definition, nil
+		}
+		node = nodes[1]
+	}
+}
+
+func transformBlock(node forest.Node) (Block, error) {
+	child := children(node)[0]
+	nodes := children(child)
+	switch name(child) {
+	case "rule":
+		qualifiedIdentifier, err := transformQualifiedIdentifier(nodes[0])
+		if err != nil {
+			return nil, err
+		}
+		expression, err := transformExpression(nodes[2])
+		if err != nil {
+			return nil, err
+		}
+		return Rule{
+			QualifiedIdentifier: qualifiedIdentifier,
+			Expression:          expression,
+		}, nil
+	case "setting":
+		// the equal sign between the setting and its value is optional
+		qualifiedIdentifier, err := transformQualifiedIdentifier(nodes[len(nodes)-2])
+		if err != nil {
+			return nil, err
+		}
+		return Setting{
+			SettingIdentifier: SettingIdentifier{
+				Name: strings.TrimPrefix(text(nodes[0]), ":"),
+			},
+			QualifiedIdentifier: qualifiedIdentifier,
+		}, nil
+	case "lexer_rule":
+		qualifiedIdentifier, err := transformQualifiedIdentifier(nodes[0])
+		if err != nil {
+			return nil, err
+		}
+		expression, err := transformLexerRuleExpression(nodes[2])
+		if err != nil {
+			return nil, err
+		}
+		return LexerRule{
+			QualifiedIdentifier: qualifiedIdentifier,
+			LexerRuleExpression: expression,
+		}, nil
+	}
+	return nil, unexpected(child)
+}
+
+func transformExpression(node forest.Node) (Expression, error) {
+	nodes := children(node)
+	term, err := transformTerm(nodes[0])
+	if err != nil {
+		return nil, err
+	}
+	nodes = nodes[1:]
+	var attributes []Attribute
+	if len(nodes) > 0 && name(nodes[0]) == "attributes" {
+		attributes, err = transformAttributes(nodes[0])
+		if err != nil {
+			return nil, err
+		}
+		nodes = nodes[1:]
+	}
+	if len(nodes) == 0 {
+		return ExpressionTerm{Term: term, Attributes: attributes}, nil
+	}
+	expression, err := transformExpression(nodes[1])
+	if err != nil {
+		return nil, err
+	}
+	return ExpressionTermExpression{
+		Term:       term,
+		Attributes: attributes,
+		Expression: expression,
+	}, nil
+}
+
+func transformAttributes(node forest.Node) ([]Attribute, error) {
+	var attributes []Attribute
+	for {
+		nodes := children(node)
+		attribute, err := transformAttribute(nodes[0])
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, attribute)
+		if len(nodes) == 1 {
+			return attributes, nil
+		}
+		node = nodes[1]
+	}
+}
+
+func transformAttribute(node forest.Node) (Attribute, error) {
+	nodes := children(node)
+	attribute := Attribute{
+		Name: strings.TrimPrefix(text(nodes[0]), "@"),
+	}
+	if len(nodes) == 1 {
+		return attribute, nil
+	}
+	for node := nodes[2]; ; {
+		nodes := children(node)
+		argument, err := transformArgument(nodes[0])
+		if err != nil {
+			return Attribute{}, err
+		}
+		attribute.Arguments = append(attribute.Arguments, argument)
+		if len(nodes) == 1 {
+			return attribute, nil
+		}
+		node = nodes[2]
+	}
+}
+
+func transformArgument(node forest.Node) (Argument, error) {
+	child := children(node)[0]
+	switch name(child) {
+	case "qualified_identifier":
+		return transformQualifiedIdentifier(child)
+	case "literal":
+		return transformLiteral(child)
+	case NumberTokenType:
+		value, err := strconv.Atoi(text(child))
+		if err != nil {
+			return nil, err
+		}
+		return Number{Value: value}, nil
+	}
+	return nil, unexpected(child)
+}
+
+func transformTerm(node forest.Node) (Term, error) {
+	nodes := children(node)
+	factor, err := transformFactor(nodes[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 1 {
+		return TermFactor{Factor: factor}, nil
+	}
+	term, err := transformTerm(nodes[1])
+	if err != nil {
+		return nil, err
+	}
+	return TermFactorTerm{
+		Factor: factor,
+		Term:   term,
+	}, nil
+}
+
+func transformFactor(node forest.Node) (Factor, error) {
+	child := children(node)[0]
+	switch name(child) {
+	case "qualified_identifier":
+		return transformQualifiedIdentifier(child)
+	case "literal":
+		return transformLiteral(child)
+	case RegularExpressionTokenType:
+		return transformRegularExpression(child)
+	case "repetition":
+		expression, err := transformExpression(children(child)[1])
+		if err != nil {
+			return nil, err
+		}
+		return Repetition{Expression: expression}, nil
+	case "optional":
+		expression, err := transformExpression(children(child)[1])
+		if err != nil {
+			return nil, err
+		}
+		return Optional{Expression: expression}, nil
+	case "grouping":
+		expression, err := transformExpression(children(child)[1])
+		if err != nil {
+			return nil, err
+		}
+		return Grouping{Expression: expression}, nil
+	}
+	return nil, unexpected(child)
+}
+
+func transformLiteral(node forest.Node) (Literal, error) {
+	child := children(node)[0]
+	value := text(child)
+	value = value[1 : len(value)-1]
+	switch name(child) {
+	case SingleQuoteStringTokenType:
+		return SingleQuoteString{Text: unescape(value)}, nil
+	case DoubleQuoteStringTokenType:
+		return DoubleQuoteString{Text: unescape(value)}, nil
+	}
+	return nil, unexpected(child)
+}
+
+func transformRegularExpression(node forest.Node) (RegularExpression, error) {
+	value := text(node)
+	pattern := value[1 : len(value)-1]
+	definition, err := re.Parse(pattern)
+	if err != nil {
+		start := node.(*forest.Token).Start()
+		return RegularExpression{}, fmt.Errorf("%d:%d: %w", start.Line, start.Column, err)
+	}
+	return RegularExpression{
+		Pattern:    pattern,
+		Definition: definition,
+	}, nil
+}
+
+func transformQualifiedIdentifier(node forest.Node) (QualifiedIdentifier, error) {
+	nodes := children(node)
+	identifier := text(nodes[0])
+	if len(nodes) == 1 {
+		return QualifiedIdentifierIdentifier{Identifier: identifier}, nil
+	}
+	qualifiedIdentifier, err := transformQualifiedIdentifier(nodes[2])
+	if err != nil {
+		return nil, err
+	}
+	return QualifiedIdentifierIdentifierQualifiedIdentifier{
+		Identifier:          identifier,
+		QualifiedIdentifier: qualifiedIdentifier,
+	}, nil
+}
+
+func transformLexerRuleExpression(node forest.Node) (LexerRuleExpression, error) {
+	nodes := children(node)
+	term, err := transformLexerRuleTerm(nodes[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 1 {
+		return LexerRuleExpressionTerm{LexerRuleTerm: term}, nil
+	}
+	expression, err := transformLexerRuleExpression(nodes[2])
+	if err != nil {
+		return nil, err
+	}
+	return LexerRuleExpressionTermExpression{
+		LexerRuleTerm:       term,
+		LexerRuleExpression: expression,
+	}, nil
+}
+
+func transformLexerRuleTerm(node forest.Node) (LexerRuleTerm, error) {
+	nodes := children(node)
+	factor, err := transformLexerRuleFactor(nodes[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 1 {
+		return LexerRuleTermFactor{LexerRuleFactor: factor}, nil
+	}
+	term, err := transformLexerRuleTerm(nodes[1])
+	if err != nil {
+		return nil, err
+	}
+	return LexerRuleTermFactorTerm{
+		LexerRuleFactor: factor,
+		LexerRuleTerm:   term,
+	}, nil
+}
+
+func transformLexerRuleFactor(node forest.Node) (LexerRuleFactor, error) {
+	child := children(node)[0]
+	switch name(child) {
+	case "literal":
+		return transformLiteral(child)
+	case RegularExpressionTokenType:
+		return transformRegularExpression(child)
+	case "qualified_identifier":
+		return transformQualifiedIdentifier(child)
+	case "lexer_rule_repetition":
+		expression, err := transformLexerRuleExpression(children(child)[1])
+		if err != nil {
+			return nil, err
+		}
+		return LexerRuleRepetition{LexerRuleExpression: expression}, nil
+	case "lexer_rule_optional":
+		expression, err := transformLexerRuleExpression(children(child)[1])
+		if err != nil {
+			return nil, err
+		}
+		return LexerRuleOptional{LexerRuleExpression: expression}, nil
+	case "lexer_rule_grouping":
+		expression, err := transformLexerRuleExpression(children(child)[1])
+		if err != nil {
+			return nil, err
+		}
+		return LexerRuleGrouping{LexerRuleExpression: expression}, nil
+	}
+	return nil, unexpected(child)
+}
+
+// children returns the children of the first alternative of the node
+// intermediate nodes are flattened so the result matches the right hand side of the production
+func children(node forest.Node) []forest.Node {
+	internal, ok := node.(forest.Internal)
+	if !ok {
+		return nil
+	}
+	alternatives := internal.Alternatives()
+	if len(alternatives) == 0 {
+		return nil
+	}
+	var nodes []forest.Node
+	for _, child := range alternatives[0].Children() {
+		if intermediate, ok := child.(*forest.Intermediate); ok {
+			nodes = append(nodes, children(intermediate)...)
+			continue
+		}
+		nodes = append(nodes, child)
+	}
+	return nodes
+}
+
+// name returns the symbol name of a symbol node or the token type of a token node
+func name(node forest.Node) string {
+	switch n := node.(type) {
+	case *forest.Symbol:
+		return n.Symbol.String()
+	case *forest.Token:
+		return n.Token.TokenType()
+	}
+	return ""
+}
+
+func text(node forest.Node) string {
+	tok, ok := node.(*forest.Token)
+	if !ok {
+		return ""
+	}
+	return tok.Text()
+}
+
+func unescape(value string) string {
+	if !strings.ContainsRune(value, '\\') {
+		return value
+	}
+	var builder strings.Builder
+	escaped := false
+	for _, ch := range value {
+		if !escaped && ch == '\\' {
+			escaped = true
+			continue
+		}
+		escaped = false
+		builder.WriteRune(ch)
+	}
+	return builder.String()
+}
+
+func unexpected(node forest.Node) error {
+	return fmt.Errorf("unexpected node %v", node)
+}
@@ -0,0 +1,198 @@
+package pdl_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/patrickhuber/go-earley/pdl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("calculator", func(t *testing.T) {
+		input := `
+		Calculator 
+			= Expression;
+				
+		Expression 
+			= Expression '+' Term
+			| Term;
+				
+		Term 
+			= Term '*' Factor
+			| Factor;
+				
+		Factor 
+			= Number ;
+			
+		Number 
+			= Digits;
+				
+		Digits ~ /[0-9]+/ ;
+		Whitespace ~ /[\s]+/ ;
+			
+		:start = Calculator;
+		:ignore = Whitespace;`
+
+		definition, err := pdl.Parse(strings.NewReader(input))
+		require.NoError(t, err)
+		require.Equal(t, 9, len(definition.Blocks))
+
+		calculator, ok := definition.Blocks[0].(pdl.Rule)
+		require.True(t, ok)
+		require.Equal(t, "Calculator", calculator.QualifiedIdentifier.String())
+
+		digits, ok := definition.Blocks[5].(pdl.LexerRule)
+		require.True(t, ok)
+		require.Equal(t, "Digits", digits.QualifiedIdentifier.String())
+		expression, ok := digits.LexerRuleExpression.(pdl.LexerRuleExpressionTerm)
+		require.True(t, ok)
+		term, ok := expression.LexerRuleTerm.(pdl.LexerRuleTermFactor)
+		require.True(t, ok)
+		regex, ok := term.LexerRuleFactor.(pdl.RegularExpression)
+		require.True(t, ok)
+		require.Equal(t, "[0-9]+", regex.Pattern)
+		require.NotNil(t, regex.Definition)
+
+		start, ok := definition.Blocks[7].(pdl.Setting)
+		require.True(t, ok)
+		require.Equal(t, pdl.StartSetting, start.SettingIdentifier.Name)
+		require.Equal(t, "Calculator", start.QualifiedIdentifier.String())
+	})
+	t.Run("expression", func(t *testing.T) {
+		definition, err := pdl.Parse(strings.NewReader(`A = b.c "d" | { e } [ f ] ( g );`))
+		require.NoError(t, err)
+		require.Equal(t, &pdl.Definition{
+			Blocks: []pdl.Block{
+				pdl.Rule{
+					QualifiedIdentifier: pdl.QualifiedIdentifierIdentifier{Identifier: "A"},
+					Expression: pdl.ExpressionTermExpression{
+						Term: pdl.TermFactorTerm{
+							Factor: pdl.QualifiedIdentifierIdentifierQualifiedIdentifier{
+								Identifier:          "b",
+								QualifiedIdentifier: pdl.QualifiedIdentifierIdentifier{Identifier: "c"},
+							},
+							Term: pdl.TermFactor{
+								Factor: pdl.DoubleQuoteString{Text: "d"},
+							},
+						},
+						Expression: pdl.ExpressionTerm{
+							Term: pdl.TermFactorTerm{
+								Factor: pdl.Repetition{Expression: identifierExpression("e")},
+								Term: pdl.TermFactorTerm{
+									Factor: pdl.Optional{Expression: identifierExpression("f")},
+									Term: pdl.TermFactor{
+										Factor: pdl.Grouping{Expression: identifierExpression("g")},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}, definition)
+	})
+	t.Run("attributes", func(t *testing.T) {
+		definition, err := pdl.Parse(strings.NewReader(`A = B @left @priority(2) | C @notfollowedby('x', D.E);`))
+		require.NoError(t, err)
+		rule, ok := definition.Blocks[0].(pdl.Rule)
+		require.True(t, ok)
+		expression, ok := rule.Expression.(pdl.ExpressionTermExpression)
+		require.True(t, ok)
+		require.Equal(t, []pdl.Attribute{
+			{Name: "left"},
+			{Name: "priority", Arguments: []pdl.Argument{pdl.Number{Value: 2}}},
+		}, expression.Attributes)
+		last, ok := expression.Expression.(pdl.ExpressionTerm)
+		require.True(t, ok)
+		require.Equal(t, []pdl.Attribute{
+			{Name: "notfollowedby", Arguments: []pdl.Argument{
+				pdl.SingleQuoteString{Text: "x"},
+				pdl.QualifiedIdentifierIdentifierQualifiedIdentifier{
+					Identifier:          "D",
+					QualifiedIdentifier: pdl.QualifiedIdentifierIdentifier{Identifier: "E"},
+				},
+			}},
+		}, last.Attributes)
+	})
+	t.Run("comments", func(t *testing.T) {
+		input := `
+		// line comment
+		A = 'a'; /* block
+		comment */ B = 'b';`
+		definition, err := pdl.Parse(strings.NewReader(input))
+		require.NoError(t, err)
+		require.Equal(t, 2, len(definition.Blocks))
+	})
+	t.Run("comments next to regular expressions", func(t *testing.T) {
+		input := `
+		A = 'a' /* between factors */ | /b/ // after a factor
+		| 'c' //
+		;`
+		definition, err := pdl.Parse(strings.NewReader(input))
+		require.NoError(t, err)
+		require.Equal(t, 1, len(definition.Blocks))
+	})
+	t.Run("setting without equal sign", func(t *testing.T) {
+		definition, err := pdl.Parse(strings.NewReader(`:start A; A = 'a';`))
+		require.NoError(t, err)
+		require.Equal(t, pdl.Setting{
+			SettingIdentifier:   pdl.SettingIdentifier{Name: pdl.StartSetting},
+			QualifiedIdentifier: pdl.QualifiedIdentifierIdentifier{Identifier: "A"},
+		}, definition.Blocks[0])
+	})
+	t.Run("pdl.pdl", func(t *testing.T) {
+		file, err := os.Open("pdl.pdl")
+		require.NoError(t, err)
+		defer file.Close()
+
+		definition, err := pdl.Parse(file)
+		require.NoError(t, err)
+		namespace, ok := definition.Blocks[0].(pdl.Setting)
+		require.True(t, ok)
+		require.Equal(t, pdl.NamespaceSetting, namespace.SettingIdentifier.Name)
+		require.Equal(t, "pdl", namespace.QualifiedIdentifier.String())
+
+		// every rule of the pdl grammar is described
+		rules := map[string]struct{}{}
+		for _, block := range definition.Blocks {
+			switch b := block.(type) {
+			case pdl.Rule:
+				rules[b.QualifiedIdentifier.String()] = struct{}{}
+			case pdl.LexerRule:
+				rules[b.QualifiedIdentifier.String()] = struct{}{}
+			}
+		}
+		g := pdl.Grammar()
+		for _, production := range g.Productions {
+			require.Contains(t, rules, production.LeftHandSide.Name())
+		}
+		for _, lexerRule := range g.Ignore {
+			require.Contains(t, rules, lexerRule.TokenType())
+		}
+	})
+	t.Run("unexpected token", func(t *testing.T) {
+		_, err := pdl.Parse(strings.NewReader("A = 'a'\nB = 'b';"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "2:3")
+	})
+	t.Run("invalid regular expression", func(t *testing.T) {
+		_, err := pdl.Parse(strings.NewReader("A ~ /a)/;"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "1:5")
+	})
+	t.Run("unexpected end of input", func(t *testing.T) {
+		_, err := pdl.Parse(strings.NewReader("A = 'a'"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unexpected end of input")
+	})
+}
+
+func identifierExpression(name string) pdl.Expression {
+	return pdl.ExpressionTerm{
+		Term: pdl.TermFactor{
+			Factor: pdl.QualifiedIdentifierIdentifier{Identifier: name},
+		},
+	}
+}
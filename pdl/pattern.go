@@ -0,0 +1,491 @@
+package pdl
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/patrickhuber/go-earley/automata/dfa"
+	"github.com/patrickhuber/go-earley/grammar"
+	"github.com/patrickhuber/go-earley/terminal"
+)
+
+// Pattern returns a regular expression, without the enclosing slashes, that matches the same text as the lexer rule
+// It can be passed to Regex to rebuild the lexer rule.
+func Pattern(lexerRule grammar.LexerRule) (string, error) {
+	var e *expr
+	switch r := lexerRule.(type) {
+	case *dfa.Dfa:
+		var err error
+		e, err = eliminate(r)
+		if err != nil {
+			return "", fmt.Errorf("lexer rule %s: %w", r.TokenType(), err)
+		}
+	case *grammar.TerminalLexerRule:
+		intervals, err := terminal.Intervals(r.Terminal)
+		if err != nil {
+			return "", fmt.Errorf("lexer rule %s: %w", r.TokenType(), err)
+		}
+		e = atom(intervals)
+	default:
+		return "", fmt.Errorf("lexer rule %s of type %s can not be written as a regular expression", lexerRule.TokenType(), lexerRule.LexerRuleType())
+	}
+	if e == nil || e.op == emptyOp {
+		return "", fmt.Errorf("lexer rule %s matches the empty string", lexerRule.TokenType())
+	}
+	return e.String(), nil
+}
+
+// eliminate converts the dfa to a regular expression by removing its states one at a time
+// each removed state is replaced by edges that spell the paths through it.
+func eliminate(d *dfa.Dfa) (*expr, error) {
+	states := []*dfa.State{d.Start}
+	index := map[*dfa.State]int{d.Start: 0}
+	for i := 0; i < len(states); i++ {
+		for _, transition := range states[i].Transitions {
+			if _, ok := index[transition.Target]; ok {
+				continue
+			}
+			index[transition.Target] = len(states)
+			states = append(states, transition.Target)
+		}
+	}
+
+	// start and final are added so the start state has no incoming edges and there is a single final state
+	start := len(states)
+	final := start + 1
+	edges := make([][]*expr, final+1)
+	for i := range edges {
+		edges[i] = make([]*expr, final+1)
+	}
+	edges[start][0] = &expr{op: emptyOp}
+	for i, state := range states {
+		if state.Final {
+			edges[i][final] = &expr{op: emptyOp}
+		}
+		// transitions to the same target are merged into one character class
+		var targets []int
+		intervals := map[int][]terminal.Interval{}
+		for _, transition := range state.Transitions {
+			j := index[transition.Target]
+			ranges, err := terminal.Intervals(transition.Terminal)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := intervals[j]; !ok {
+				targets = append(targets, j)
+			}
+			intervals[j] = append(intervals[j], ranges...)
+		}
+		for _, j := range targets {
+			edges[i][j] = atom(terminal.Normalize(intervals[j]))
+		}
+	}
+
+	for k := range states {
+		loop := star(edges[k][k])
+		for i := range edges {
+			if i == k || edges[i][k] == nil {
+				continue
+			}
+			for j := range edges {
+				if j == k || edges[k][j] == nil {
+					continue
+				}
+				path := concat(edges[i][k], loop, edges[k][j])
+				if edges[i][j] == nil {
+					edges[i][j] = path
+				} else {
+					edges[i][j] = alternate(edges[i][j], path)
+				}
+			}
+		}
+		for i := range edges {
+			edges[i][k] = nil
+			edges[k][i] = nil
+		}
+	}
+
+	if edges[start][final] == nil {
+		return nil, fmt.Errorf("dfa has no final state")
+	}
+	return edges[start][final], nil
+}
+
+type op int
+
+const (
+	// emptyOp matches the empty string
+	emptyOp op = iota
+	atomOp
+	concatOp
+	alternateOp
+	starOp
+	plusOp
+	optionalOp
+)
+
+// expr is a regular expression built while eliminating the states of a dfa
+// the constructors simplify as they go, so x x* becomes x+ and a b | a c becomes a(b|c)
+type expr struct {
+	op       op
+	atom     string
+	children []*expr
+}
+
+func atom(intervals []terminal.Interval) *expr {
+	return &expr{op: atomOp, atom: class(intervals)}
+}
+
+func concat(items ...*expr) *expr {
+	var children []*expr
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		switch item.op {
+		case emptyOp:
+		case concatOp:
+			children = append(children, item.children...)
+		default:
+			children = append(children, item)
+		}
+	}
+	children = collapse(children)
+	switch len(children) {
+	case 0:
+		return &expr{op: emptyOp}
+	case 1:
+		return children[0]
+	}
+	return &expr{op: concatOp, children: children}
+}
+
+// collapse replaces x x* and x* x with x+
+func collapse(children []*expr) []*expr {
+	for i := 0; i < len(children); i++ {
+		if children[i].op != starOp {
+			continue
+		}
+		body := children[i].children[0]
+		items := []*expr{body}
+		if body.op == concatOp {
+			items = body.children
+		}
+		n := len(items)
+		switch {
+		case i >= n && same(children[i-n:i], items):
+			children = splice(children, i-n, i+1, plus(body))
+			i = -1
+		case i+n < len(children) && same(children[i+1:i+1+n], items):
+			children = splice(children, i, i+1+n, plus(body))
+			i = -1
+		}
+	}
+	return children
+}
+
+func splice(children []*expr, from, to int, e *expr) []*expr {
+	result := append([]*expr{}, children[:from]...)
+	result = append(result, e)
+	return append(result, children[to:]...)
+}
+
+func same(left, right []*expr) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for i := range left {
+		if left[i].String() != right[i].String() {
+			return false
+		}
+	}
+	return true
+}
+
+func alternate(items ...*expr) *expr {
+	var alternatives []*expr
+	nullable := false
+	seen := map[string]struct{}{}
+	var add func(e *expr)
+	add = func(e *expr) {
+		switch e.op {
+		case emptyOp:
+			nullable = true
+			return
+		case alternateOp:
+			for _, child := range e.children {
+				add(child)
+			}
+			return
+		case optionalOp:
+			nullable = true
+			add(e.children[0])
+			return
+		}
+		key := e.String()
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		alternatives = append(alternatives, e)
+	}
+	for _, item := range items {
+		add(item)
+	}
+
+	// alternatives that start with the same expression share it
+	var keys []string
+	groups := map[string][]*expr{}
+	for _, alternative := range alternatives {
+		key := head(alternative).String()
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], alternative)
+	}
+	var children []*expr
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) == 1 {
+			children = append(children, group[0])
+			continue
+		}
+		var tails []*expr
+		for _, alternative := range group {
+			tails = append(tails, tail(alternative))
+		}
+		children = append(children, concat(head(group[0]), alternate(tails...)))
+	}
+
+	var result *expr
+	switch len(children) {
+	case 0:
+		return &expr{op: emptyOp}
+	case 1:
+		result = children[0]
+	default:
+		result = &expr{op: alternateOp, children: children}
+	}
+	if nullable {
+		return optional(result)
+	}
+	return result
+}
+
+func head(e *expr) *expr {
+	if e.op == concatOp {
+		return e.children[0]
+	}
+	return e
+}
+
+func tail(e *expr) *expr {
+	if e.op == concatOp {
+		return concat(e.children[1:]...)
+	}
+	return &expr{op: emptyOp}
+}
+
+func star(e *expr) *expr {
+	if e == nil || e.op == emptyOp {
+		return &expr{op: emptyOp}
+	}
+	switch e.op {
+	case starOp:
+		return e
+	case plusOp, optionalOp:
+		e = e.children[0]
+	}
+	return &expr{op: starOp, children: []*expr{e}}
+}
+
+func plus(e *expr) *expr {
+	switch e.op {
+	case starOp, plusOp:
+		return e
+	case optionalOp:
+		return star(e.children[0])
+	}
+	return &expr{op: plusOp, children: []*expr{e}}
+}
+
+func optional(e *expr) *expr {
+	switch e.op {
+	case emptyOp, starOp, optionalOp:
+		return e
+	case plusOp:
+		return star(e.children[0])
+	}
+	return &expr{op: optionalOp, children: []*expr{e}}
+}
+
+func (e *expr) String() string {
+	var builder strings.Builder
+	switch e.op {
+	case atomOp:
+		builder.WriteString(e.atom)
+	case concatOp:
+		for _, child := range e.children {
+			builder.WriteString(group(child, child.op == alternateOp))
+		}
+	case alternateOp:
+		for i, child := range e.children {
+			if i > 0 {
+				builder.WriteRune('|')
+			}
+			builder.WriteString(child.String())
+		}
+	case starOp, plusOp, optionalOp:
+		child := e.children[0]
+		builder.WriteString(group(child, child.op != atomOp))
+		builder.WriteString(map[op]string{starOp: "*", plusOp: "+", optionalOp: "?"}[e.op])
+	}
+	return builder.String()
+}
+
+func group(e *expr, parenthesize bool) string {
+	if parenthesize {
+		return "(" + e.String() + ")"
+	}
+	return e.String()
+}
+
+// shorthand is a character class escape and the characters it matches
+type shorthand struct {
+	escape    string
+	intervals []terminal.Interval
+}
+
+type shorthandList []shorthand
+
+func (l shorthandList) contains(escape string) bool {
+	for _, s := range l {
+		if s.escape == escape {
+			return true
+		}
+	}
+	return false
+}
+
+// shorthands lists \w first so class can exclude \W from a negative set
+var shorthands = func() shorthandList {
+	var result shorthandList
+	for _, s := range []struct {
+		escape   string
+		terminal grammar.Terminal
+	}{
+		{`\w`, terminal.NewSet([]grammar.Terminal{terminal.NewLetter(), terminal.NewNumber(), terminal.NewCharacter('_')})},
+		{`\d`, terminal.NewNumber()},
+		{`\s`, terminal.NewWhitespace()},
+	} {
+		intervals, _ := terminal.Intervals(s.terminal)
+		result = append(result, shorthand{escape: s.escape, intervals: intervals})
+	}
+	return result
+}()
+
+// class returns the shortest of the character, the set and the negative sets that match the normalized intervals
+func class(intervals []terminal.Interval) string {
+	if len(intervals) == 1 {
+		interval := intervals[0]
+		if interval.Min == interval.Max {
+			return escapeCharacter(interval.Min, false)
+		}
+		if interval.Min == 0 && interval.Max == utf8.MaxRune {
+			return "."
+		}
+	}
+	positive := members(intervals)
+	negative := members(terminal.Complement(intervals))
+	// word characters without a few others, like the letters [^\W\d_], exclude \W in a negative set
+	if word := shorthands[0].intervals; subset(intervals, word) {
+		excluded := members(intersect(word, terminal.Complement(intervals)))
+		if len(excluded)+1 < len(positive) && len(excluded)+1 < len(negative) {
+			return `[^\W` + strings.Join(excluded, "") + "]"
+		}
+	}
+	if len(negative) < len(positive) {
+		return "[^" + strings.Join(negative, "") + "]"
+	}
+	if len(positive) == 1 && shorthands.contains(positive[0]) {
+		return positive[0]
+	}
+	return "[" + strings.Join(positive, "") + "]"
+}
+
+// members returns the shorthands and ranges of a character class that matches the intervals
+func members(intervals []terminal.Interval) []string {
+	var result []string
+	for _, s := range shorthands {
+		if !subset(s.intervals, intervals) {
+			continue
+		}
+		result = append(result, s.escape)
+		intervals = intersect(intervals, terminal.Complement(s.intervals))
+	}
+	for _, interval := range intervals {
+		switch {
+		case interval.Min == interval.Max:
+			result = append(result, escapeCharacter(interval.Min, true))
+		case interval.Min+1 == interval.Max:
+			result = append(result, escapeCharacter(interval.Min, true)+escapeCharacter(interval.Max, true))
+		default:
+			result = append(result, escapeCharacter(interval.Min, true)+"-"+escapeCharacter(interval.Max, true))
+		}
+	}
+	return result
+}
+
+func subset(inner, outer []terminal.Interval) bool {
+	intersection := intersect(inner, outer)
+	if len(intersection) != len(inner) {
+		return false
+	}
+	for i := range inner {
+		if intersection[i] != inner[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// intersect returns the characters in both normalized interval lists
+func intersect(left, right []terminal.Interval) []terminal.Interval {
+	var result []terminal.Interval
+	for i, j := 0, 0; i < len(left) && j < len(right); {
+		lo := max(left[i].Min, right[j].Min)
+		hi := min(left[i].Max, right[j].Max)
+		if lo <= hi {
+			result = append(result, terminal.Interval{Min: lo, Max: hi})
+		}
+		if left[i].Max < right[j].Max {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
+
+// escapeCharacter escapes the meta characters of a regular expression, the slash is escaped because it ends the pdl token
+func escapeCharacter(ch rune, inClass bool) string {
+	switch ch {
+	case '\n':
+		return `\n`
+	case '\r':
+		return `\r`
+	case '\t':
+		return `\t`
+	case '\f':
+		return `\f`
+	}
+	meta := `.^$()[]+*?\/|`
+	if inClass {
+		meta = `[]^-\/`
+	}
+	if strings.ContainsRune(meta, ch) {
+		return `\` + string(ch)
+	}
+	return string(ch)
+}
@@ -34,8 +34,8 @@ type TermFactor struct {
 func (TermFactor) term() {}
 
 type TermFactorTerm struct {
-	Fator Factor
-	Term  Term
+	Factor Factor
+	Term   Term
 }
 
 func (TermFactorTerm) term() {}
@@ -73,15 +73,21 @@ type AtomAny struct{}
 
 func (AtomAny) atom() {}
 
-type AtomCharacter struct{}
+type AtomCharacter struct {
+	Character Character
+}
 
 func (AtomCharacter) atom() {}
 
-type AtomExpression struct{}
+type AtomExpression struct {
+	Expression Expression
+}
 
 func (AtomExpression) atom() {}
 
-type AtomSet struct{}
+type AtomSet struct {
+	Set Set
+}
 
 func (AtomSet) atom() {}
 
@@ -89,7 +95,7 @@ type Set interface {
 	set()
 }
 type NegativeSet struct {
-	Characterclass CharacterClass
+	CharacterClass CharacterClass
 }
 
 func (NegativeSet) set() {}
@@ -104,6 +110,19 @@ type CharacterClass interface {
 	characterClass()
 }
 
+type CharacterClassCharacterRange struct {
+	CharacterRange CharacterRange
+}
+
+func (CharacterClassCharacterRange) characterClass() {}
+
+type CharacterClassCharacterRangeCharacterClass struct {
+	CharacterRange CharacterRange
+	CharacterClass CharacterClass
+}
+
+func (CharacterClassCharacterRangeCharacterClass) characterClass() {}
+
 type CharacterRange interface {
 	characterRange()
 }
@@ -112,11 +131,15 @@ type CharacterRangeCharacterClassCharacter struct {
 	Begin CharacterClassCharacter
 }
 
+func (CharacterRangeCharacterClassCharacter) characterRange() {}
+
 type CharacterRangeCharacterClassCharacterRange struct {
 	Begin CharacterClassCharacter
 	End   CharacterClassCharacter
 }
 
+func (CharacterRangeCharacterClassCharacterRange) characterRange() {}
+
 type Character interface {
 	character()
 }
@@ -125,8 +148,8 @@ type CharacterClassCharacter interface {
 	characterClassCharacter()
 }
 
-// NotMetaCharacter represents a non meta character .^$()[]+*?\/
-// /[^.^$()[\]+*?\\\/]/;
+// NotMetaCharacter represents a non meta character .^$()[]+*?\/|
+// /[^.^$()[\]+*?\\\/|]/;
 type NotMetaCharacter struct {
 	Char rune
 }
@@ -142,7 +165,7 @@ type EscapeSequence struct {
 func (EscapeSequence) character()               {}
 func (EscapeSequence) characterClassCharacter() {}
 
-// NotCloseBracketCharacter is a non close bracket ] character
+// NotCloseBracketCharacter is a character other than the close bracket ] or backslash
 type NotCloseBracketCharacter struct {
 	Char rune
 }
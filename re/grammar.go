@@ -1,7 +1,6 @@
 package re
 
 import (
-	"github.com/patrickhuber/go-earley/automata/dfa"
 	"github.com/patrickhuber/go-earley/grammar"
 	"github.com/patrickhuber/go-earley/terminal"
 )
@@ -14,11 +13,12 @@ func Grammar() *grammar.Grammar {
 	atom := nonTerminal("atom")
 	set := nonTerminal("set")
 	positiveSet := nonTerminal("positive_set")
-	negativeSet := nonTerminal("negative_Set")
+	negativeSet := nonTerminal("negative_set")
 	characterClass := nonTerminal("character_class")
 	characterRange := nonTerminal("character_range")
 	character := nonTerminal("character")
 	characterClassCharacter := nonTerminal("character_class_character")
+	escapeSequence := nonTerminal("escape_sequence")
 
 	upCaret := oneOf('^')
 	dollar := oneOf('$')
@@ -26,10 +26,12 @@ func Grammar() *grammar.Grammar {
 	iterator := oneOf('*', '+', '?')
 	openBracket := oneOf('[')
 	closeBracket := oneOf(']')
+	openParen := oneOf('(')
+	closeParen := oneOf(')')
 	dash := oneOf('-')
-	notMeta := not(oneOf('^', '.', '$', '(', ')', '[', ']', '+', '*', '?', '\\', '/'))
-	escape := sequence("escape_sequence", oneOf('\\'), anyCh())
-	notCloseBracket := not(closeBracket)
+	backslash := oneOf('\\')
+	notMeta := not(oneOf('^', '.', '$', '(', ')', '[', ']', '+', '*', '?', '\\', '/', '|'))
+	notCloseBracket := not(oneOf(']', '\\'))
 	dot := oneOf('.')
 
 	productions := []*grammar.Production{
@@ -49,7 +51,8 @@ func Grammar() *grammar.Grammar {
 		production(factor, atom, iterator),
 		// atom
 		production(atom, character),
-		production(atom, expression),
+		production(atom, openParen, expression, closeParen),
+		production(atom, set),
 		production(atom, dot),
 		// set
 		production(set, positiveSet),
@@ -66,10 +69,12 @@ func Grammar() *grammar.Grammar {
 		production(characterRange, characterClassCharacter, dash, characterClassCharacter),
 		// character
 		production(character, notMeta),
-		production(character, escape),
+		production(character, escapeSequence),
 		// character_class_character
 		production(characterClassCharacter, notCloseBracket),
-		production(characterClassCharacter, escape),
+		production(characterClassCharacter, escapeSequence),
+		// escape_sequence
+		production(escapeSequence, backslash, anyCh()),
 	}
 	return grammar.New(definition, productions...)
 }
@@ -101,22 +106,3 @@ func not(t grammar.Terminal) grammar.Terminal {
 func anyCh() grammar.Terminal {
 	return terminal.NewAny()
 }
-
-func sequence(name string, terminals ...grammar.Terminal) grammar.LexerRule {
-	start := &dfa.State{
-		Final: false,
-	}
-	current := start
-	for _, term := range terminals {
-		state := &dfa.State{
-			Final: false,
-		}
-		current.Transitions = append(current.Transitions, dfa.Transition{
-			Target:   state,
-			Terminal: term,
-		})
-		current = state
-	}
-	current.Final = true
-	return dfa.NewDfa(start, name)
-}
@@ -12,26 +12,262 @@ func Parse(input string) (*Definition, error) {
 	g := Grammar()
 	p := parser.New(g)
 	s := scanner.New(p, input)
-	for {
-		ok, err := s.Read()
+	// the parse error holds the line and column of the unexpected character or end of the pattern
+	accepted, err := scanner.RunToEnd(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", input, err)
+	}
+	if !accepted {
+		return nil, fmt.Errorf("invalid pattern %q", input)
+	}
+	root, ok := s.Parser().GetForestRoot()
+	if !ok {
+		return nil, fmt.Errorf("failed to get forest root")
+	}
+	t := &transformer{
+		runes: []rune(input),
+	}
+	return t.definition(root)
+}
+
+// transformer converts the forest into the ast
+// every token in the regular expression grammar is a single character so
+// the location of a token node is the index of the character after it
+type transformer struct {
+	runes []rune
+}
+
+func (t *transformer) definition(node forest.Node) (*Definition, error) {
+	definition := &Definition{}
+	for _, child := range t.children(node) {
+		switch name(child) {
+		case "^":
+			definition.Start = true
+		case "$":
+			definition.End = true
+		case "expression":
+			expression, err := t.expression(child)
+			if err != nil {
+				return nil, err
+			}
+			definition.Expression = expression
+		default:
+			return nil, unexpected(child)
+		}
+	}
+	return definition, nil
+}
+
+func (t *transformer) expression(node forest.Node) (Expression, error) {
+	nodes := t.children(node)
+	term, err := t.term(nodes[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 1 {
+		return ExpressionTerm{Term: term}, nil
+	}
+	expression, err := t.expression(nodes[2])
+	if err != nil {
+		return nil, err
+	}
+	return ExpressionTermExpression{
+		Term:       term,
+		Expression: expression,
+	}, nil
+}
+
+func (t *transformer) term(node forest.Node) (Term, error) {
+	nodes := t.children(node)
+	factor, err := t.factor(nodes[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 1 {
+		return TermFactor{Factor: factor}, nil
+	}
+	term, err := t.term(nodes[1])
+	if err != nil {
+		return nil, err
+	}
+	return TermFactorTerm{
+		Factor: factor,
+		Term:   term,
+	}, nil
+}
+
+func (t *transformer) factor(node forest.Node) (Factor, error) {
+	nodes := t.children(node)
+	atom, err := t.atom(nodes[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 1 {
+		return FactorAtom{Atom: atom}, nil
+	}
+	return FactorAtomIterator{
+		Atom:     atom,
+		Iterator: Iterator(t.char(nodes[1])),
+	}, nil
+}
+
+func (t *transformer) atom(node forest.Node) (Atom, error) {
+	nodes := t.children(node)
+	if len(nodes) == 3 {
+		expression, err := t.expression(nodes[1])
+		if err != nil {
+			return nil, err
+		}
+		return AtomExpression{Expression: expression}, nil
+	}
+	child := nodes[0]
+	switch name(child) {
+	case "character":
+		character, err := t.character(child)
+		if err != nil {
+			return nil, err
+		}
+		return AtomCharacter{Character: character}, nil
+	case "set":
+		set, err := t.set(child)
+		if err != nil {
+			return nil, err
+		}
+		return AtomSet{Set: set}, nil
+	case ".":
+		return AtomAny{}, nil
+	}
+	return nil, unexpected(child)
+}
+
+func (t *transformer) set(node forest.Node) (Set, error) {
+	child := t.children(node)[0]
+	nodes := t.children(child)
+	switch name(child) {
+	case "positive_set":
+		characterClass, err := t.characterClass(nodes[1])
 		if err != nil {
 			return nil, err
 		}
-		if !ok {
-			break
+		return PositiveSet{CharacterClass: characterClass}, nil
+	case "negative_set":
+		characterClass, err := t.characterClass(nodes[2])
+		if err != nil {
+			return nil, err
 		}
+		return NegativeSet{CharacterClass: characterClass}, nil
 	}
-	accpeted := s.Parser().Accepted()
-	if !accpeted {
-		return nil, fmt.Errorf("failed to parse")
+	return nil, unexpected(child)
+}
+
+func (t *transformer) characterClass(node forest.Node) (CharacterClass, error) {
+	nodes := t.children(node)
+	characterRange, err := t.characterRange(nodes[0])
+	if err != nil {
+		return nil, err
 	}
-	root, ok := s.Parser().GetForestRoot()
+	if len(nodes) == 1 {
+		return CharacterClassCharacterRange{CharacterRange: characterRange}, nil
+	}
+	characterClass, err := t.characterClass(nodes[1])
+	if err != nil {
+		return nil, err
+	}
+	return CharacterClassCharacterRangeCharacterClass{
+		CharacterRange: characterRange,
+		CharacterClass: characterClass,
+	}, nil
+}
+
+func (t *transformer) characterRange(node forest.Node) (CharacterRange, error) {
+	nodes := t.children(node)
+	begin, err := t.characterClassCharacter(nodes[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 1 {
+		return CharacterRangeCharacterClassCharacter{Begin: begin}, nil
+	}
+	end, err := t.characterClassCharacter(nodes[2])
+	if err != nil {
+		return nil, err
+	}
+	return CharacterRangeCharacterClassCharacterRange{
+		Begin: begin,
+		End:   end,
+	}, nil
+}
+
+func (t *transformer) character(node forest.Node) (Character, error) {
+	child := t.children(node)[0]
+	if name(child) == "escape_sequence" {
+		return t.escapeSequence(child), nil
+	}
+	return NotMetaCharacter{Char: t.char(child)}, nil
+}
+
+func (t *transformer) characterClassCharacter(node forest.Node) (CharacterClassCharacter, error) {
+	child := t.children(node)[0]
+	if name(child) == "escape_sequence" {
+		return t.escapeSequence(child), nil
+	}
+	return NotCloseBracketCharacter{Char: t.char(child)}, nil
+}
+
+func (t *transformer) escapeSequence(node forest.Node) EscapeSequence {
+	nodes := t.children(node)
+	return EscapeSequence{Char: t.char(nodes[1])}
+}
+
+// char returns the character matched by the token node
+func (t *transformer) char(node forest.Node) rune {
+	return t.runes[node.Location()-1]
+}
+
+// children returns the children of the chosen alternative of the node
+// intermediate nodes are flattened so the result matches the right hand side of the production
+func (t *transformer) children(node forest.Node) []forest.Node {
+	internal, ok := node.(forest.Internal)
 	if !ok {
-		return nil, fmt.Errorf("failed to get forest root")
+		return nil
+	}
+	var chosen []forest.Node
+	for _, alternative := range internal.Alternatives() {
+		var nodes []forest.Node
+		for _, child := range alternative.Children() {
+			if intermediate, ok := child.(*forest.Intermediate); ok {
+				nodes = append(nodes, t.children(intermediate)...)
+				continue
+			}
+			nodes = append(nodes, child)
+		}
+		if chosen == nil || prefer(nodes, chosen) {
+			chosen = nodes
+		}
+	}
+	return chosen
+}
+
+// prefer resolves the ambiguities in the grammar
+// a set starting with ^ is a negative set and a character class prefers the longest range
+func prefer(candidate, chosen []forest.Node) bool {
+	if name(candidate[0]) == "negative_set" {
+		return true
+	}
+	return candidate[0].Location() > chosen[0].Location()
+}
+
+// name returns the symbol name of a symbol node or the token type of a token node
+func name(node forest.Node) string {
+	switch n := node.(type) {
+	case *forest.Symbol:
+		return n.Symbol.String()
+	case *forest.Token:
+		return n.Token.TokenType()
 	}
-	return transform(root)
+	return ""
 }
 
-func transform(node forest.Node) (*Definition, error) {
-	return &Definition{}, nil
+func unexpected(node forest.Node) error {
+	return fmt.Errorf("unexpected node %v", node)
 }
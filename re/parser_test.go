@@ -2,6 +2,7 @@ package re_test
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/patrickhuber/go-earley/re"
@@ -27,6 +28,155 @@ func TestParser(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "anchors",
+			input: "^a$",
+			expected: &re.Definition{
+				Start: true,
+				Expression: re.ExpressionTerm{
+					Term: re.TermFactor{
+						Factor: re.FactorAtom{
+							Atom: re.AtomCharacter{Character: re.NotMetaCharacter{Char: 'a'}},
+						},
+					},
+				},
+				End: true,
+			},
+		},
+		{
+			name:  "alternation",
+			input: "a|b",
+			expected: &re.Definition{
+				Expression: re.ExpressionTermExpression{
+					Term: re.TermFactor{
+						Factor: re.FactorAtom{
+							Atom: re.AtomCharacter{Character: re.NotMetaCharacter{Char: 'a'}},
+						},
+					},
+					Expression: re.ExpressionTerm{
+						Term: re.TermFactor{
+							Factor: re.FactorAtom{
+								Atom: re.AtomCharacter{Character: re.NotMetaCharacter{Char: 'b'}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "concatenation and iterators",
+			input: "ab*c+d?",
+			expected: &re.Definition{
+				Expression: re.ExpressionTerm{
+					Term: re.TermFactorTerm{
+						Factor: re.FactorAtom{
+							Atom: re.AtomCharacter{Character: re.NotMetaCharacter{Char: 'a'}},
+						},
+						Term: re.TermFactorTerm{
+							Factor: re.FactorAtomIterator{
+								Atom:     re.AtomCharacter{Character: re.NotMetaCharacter{Char: 'b'}},
+								Iterator: re.ZeroOrMany,
+							},
+							Term: re.TermFactorTerm{
+								Factor: re.FactorAtomIterator{
+									Atom:     re.AtomCharacter{Character: re.NotMetaCharacter{Char: 'c'}},
+									Iterator: re.OneOrMany,
+								},
+								Term: re.TermFactor{
+									Factor: re.FactorAtomIterator{
+										Atom:     re.AtomCharacter{Character: re.NotMetaCharacter{Char: 'd'}},
+										Iterator: re.ZeroOrOne,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "grouping",
+			input: "(a)*",
+			expected: &re.Definition{
+				Expression: re.ExpressionTerm{
+					Term: re.TermFactor{
+						Factor: re.FactorAtomIterator{
+							Atom: re.AtomExpression{
+								Expression: re.ExpressionTerm{
+									Term: re.TermFactor{
+										Factor: re.FactorAtom{
+											Atom: re.AtomCharacter{Character: re.NotMetaCharacter{Char: 'a'}},
+										},
+									},
+								},
+							},
+							Iterator: re.ZeroOrMany,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "escape",
+			input: `\.`,
+			expected: &re.Definition{
+				Expression: re.ExpressionTerm{
+					Term: re.TermFactor{
+						Factor: re.FactorAtom{
+							Atom: re.AtomCharacter{Character: re.EscapeSequence{Char: '.'}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "range",
+			input: "[a-z_]",
+			expected: &re.Definition{
+				Expression: re.ExpressionTerm{
+					Term: re.TermFactor{
+						Factor: re.FactorAtom{
+							Atom: re.AtomSet{
+								Set: re.PositiveSet{
+									CharacterClass: re.CharacterClassCharacterRangeCharacterClass{
+										CharacterRange: re.CharacterRangeCharacterClassCharacterRange{
+											Begin: re.NotCloseBracketCharacter{Char: 'a'},
+											End:   re.NotCloseBracketCharacter{Char: 'z'},
+										},
+										CharacterClass: re.CharacterClassCharacterRange{
+											CharacterRange: re.CharacterRangeCharacterClassCharacter{
+												Begin: re.NotCloseBracketCharacter{Char: '_'},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "negative set",
+			input: `[^\]]`,
+			expected: &re.Definition{
+				Expression: re.ExpressionTerm{
+					Term: re.TermFactor{
+						Factor: re.FactorAtom{
+							Atom: re.AtomSet{
+								Set: re.NegativeSet{
+									CharacterClass: re.CharacterClassCharacterRange{
+										CharacterRange: re.CharacterRangeCharacterClassCharacter{
+											Begin: re.EscapeSequence{Char: ']'},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -40,3 +190,29 @@ func TestParser(t *testing.T) {
 		})
 	}
 }
+
+func TestParserError(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"unexpected character", "a)", `invalid pattern "a)": unexpected character ')', expected`},
+		{"unexpected character position", "a)", "at 1:2"},
+		{"unexpected character after multi-byte character", "é)", "at 1:2"},
+		{"unexpected end", "(a", `invalid pattern "(a": unexpected end of input`},
+		{"unexpected end position", "(a", "at 1:3"},
+		{"empty set", "[]", "at 1:2"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := re.Parse(test.input)
+			if err == nil {
+				t.Fatalf("expected error for %q", test.input)
+			}
+			if !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected error to contain %q, got %q", test.err, err.Error())
+			}
+		})
+	}
+}
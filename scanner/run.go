@@ -1,5 +1,10 @@
 package scanner
 
+import "github.com/patrickhuber/go-earley/parser"
+
+// RunToEnd reads the scanner until the end of the input
+// a parser.ParseError is returned if the input is invalid or ends before the parser accepts and the parser does not repair it
+// a parser.AmbiguityError is returned if the parser rejects the ambiguous parse of the input
 func RunToEnd(scanner Scanner) (bool, error) {
 	for !scanner.EndOfStream() {
 		ok, err := scanner.Read()
@@ -10,5 +15,25 @@ func RunToEnd(scanner Scanner) (bool, error) {
 			return false, nil
 		}
 	}
-	return scanner.Parser().Accepted(), nil
+	if !scanner.Parser().Accepted() {
+		if ambiguities := scanner.Parser().Ambiguities(); len(ambiguities) > 0 {
+			return false, &parser.AmbiguityError{Ambiguities: ambiguities}
+		}
+		err := endOfInput(scanner)
+		if !scanner.Parser().Repair(err) {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// endOfInput returns a ParseError for the location after the last character
+// scanners of other packages only report the offset of the last character, the error uses the offset after it assuming a single byte
+func endOfInput(s Scanner) *parser.ParseError {
+	if s, ok := s.(*scanner); ok {
+		return s.endOfInput()
+	}
+	err := parser.NewParseError(s.Parser(), s.Position()+1, s.Line(), s.Column()+1)
+	err.EndOfInput = true
+	return err
 }
@@ -2,8 +2,10 @@ package scanner
 
 import (
 	"fmt"
+	"io"
 	"strings"
 
+	"github.com/patrickhuber/go-earley/automata/dfa"
 	"github.com/patrickhuber/go-earley/grammar"
 	"github.com/patrickhuber/go-earley/parser"
 	"github.com/patrickhuber/go-earley/token"
@@ -16,34 +18,66 @@ type Scanner interface {
 	Column() int
 	EndOfStream() bool
 	Parser() parser.Parser
+	Trivia() []token.Token
 }
 
 type scanner struct {
 	position int
+	offset   int
 	line     int
 	column   int
 	parser   parser.Parser
 	lexemes  []token.Lexeme
-	input    string
-	reader   *strings.Reader
+	ignored  []token.Lexeme
+	trivia   []token.Token
+	keep     bool
+	text     strings.Builder
+	span     token.Span
+	start    token.Location
+	end      token.Location
+	reader   io.RuneReader
+	next     rune
+	size     int
+	err      error
 	registry map[string]token.Factory
 }
 
-// New creates a new scanner from the given parser and io reader
-func New(p parser.Parser, input string) Scanner {
+type Option func(*scanner)
+
+// KeepTrivia attaches ignored tokens to the token that follows them as token.Capture Leading trivia
+// the default is false
+func KeepTrivia(ok bool) Option {
+	return func(s *scanner) {
+		s.keep = ok
+	}
+}
+
+// New creates a new scanner from the given parser and input string
+func New(p parser.Parser, input string, options ...Option) Scanner {
+	return NewReader(p, strings.NewReader(input), options...)
+}
+
+// NewReader creates a new scanner that reads the input incrementally from the rune reader
+// Only the next rune and the lexemes in progress are held in memory.
+func NewReader(p parser.Parser, reader io.RuneReader, options ...Option) Scanner {
 	registry := map[string]token.Factory{
 		grammar.StringLexerRuleType:   token.NewStringFactory(),
 		grammar.TerminalLexerRuleType: token.NewTerminalFactory(),
+		dfa.LexerRuleType:             dfa.NewFactory(),
 	}
-	return &scanner{
+	s := &scanner{
 		parser:   p,
 		position: -1,
-		line:     0,
+		line:     1,
 		column:   0,
-		input:    input,
-		reader:   strings.NewReader(input),
+		reader:   reader,
 		registry: registry,
 	}
+	for _, option := range options {
+		option(s)
+	}
+	s.peek()
+	return s
 }
 
 // Column implements Scanner.
@@ -53,8 +87,8 @@ func (s *scanner) Column() int {
 
 // EndOfStream implements Scanner.
 func (s *scanner) EndOfStream() bool {
-	// if unread runes are zero, we are at the end
-	return s.reader.Len() == 0
+	// the lookahead rune is read ahead of time so the end is known before it is reached
+	return s.err == io.EOF
 }
 
 // Line implements Scanner.
@@ -72,6 +106,12 @@ func (s *scanner) Parser() parser.Parser {
 	return s.parser
 }
 
+// Trivia implements Scanner.
+// It returns the kept trivia that is not yet attached to a token, like trailing whitespace at the end of the input
+func (s *scanner) Trivia() []token.Token {
+	return s.trivia
+}
+
 // Read implements Scanner.
 func (s *scanner) Read() (bool, error) {
 	if s.EndOfStream() {
@@ -85,17 +125,20 @@ func (s *scanner) Read() (bool, error) {
 	s.update(ch)
 
 	if s.matchesExistingLexemes(ch) {
+		s.capture(ch)
 		if s.EndOfStream() {
-			if !s.tryParseExistingLexemes() {
-				return false, nil
-			}
-			return true, nil
+			return s.completeAtEndOfStream()
 		}
+		return true, nil
 	}
 
 	if s.anyExistingLexemes() {
-		if !s.tryParseExistingLexemes() {
-			return false, nil
+		ok, err := s.completeExistingLexemes()
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return s.skip(ch)
 		}
 	}
 
@@ -104,33 +147,106 @@ func (s *scanner) Read() (bool, error) {
 		return false, err
 	}
 
-	if matched {
-		if s.EndOfStream() {
-			if !s.tryParseExistingLexemes() {
-				return false, nil
-			}
-			return s.parser.Accepted(), nil
-		}
-		return true, nil
+	if !matched {
+		return s.skip(ch)
 	}
 
-	if !s.isEndOfLineCharacter(ch) {
-		return true, nil
+	// only the text of the lexemes in progress is kept
+	s.text.Reset()
+	s.span = token.Span{Offset: s.position}
+	s.start = token.Location{Line: s.line, Column: s.column}
+	s.capture(ch)
+
+	if s.EndOfStream() {
+		return s.completeAtEndOfStream()
 	}
+	return true, nil
+}
 
+func (s *scanner) completeAtEndOfStream() (bool, error) {
+	// lexemes that are not accepted expect more input
+	var pending []grammar.LexerRule
+	for _, lexeme := range append(s.lexemes, s.ignored...) {
+		if !lexeme.Accepted() {
+			pending = append(pending, lexeme.LexerRule())
+		}
+	}
+	ok, err := s.completeExistingLexemes()
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		err := s.endOfInput()
+		err.Expected = pending
+		if !s.parser.Repair(err) {
+			return false, err
+		}
+		s.discard()
+	}
+	return true, nil
+}
+
+// skip returns the error for the character that no lexer rule matches
+// if the parser repairs the error the character and the lexemes in progress are discarded
+func (s *scanner) skip(ch rune) (bool, error) {
+	err := s.unexpected(ch)
+	if !s.parser.Repair(err) {
+		return false, err
+	}
+	s.discard()
 	return true, nil
 }
 
+func (s *scanner) discard() {
+	s.freeLexemes(s.lexemes)
+	s.lexemes = s.lexemes[:0]
+	s.freeLexemes(s.ignored)
+	s.ignored = s.ignored[:0]
+}
+
+// endOfInput returns a ParseError for the location after the last character
+func (s *scanner) endOfInput() *parser.ParseError {
+	err := parser.NewParseError(s.parser, s.offset, s.line, s.column+1)
+	err.EndOfInput = true
+	return err
+}
+
+// unexpected returns a ParseError for the character that no lexer rule matches
+func (s *scanner) unexpected(ch rune) *parser.ParseError {
+	err := parser.NewParseError(s.parser, s.position, s.line, s.column)
+	err.Character = ch
+	return err
+}
+
 func (s *scanner) read() (rune, error) {
-	ch, n, err := s.reader.ReadRune()
-	if err != nil {
+	if s.err != nil {
 		var zero rune
-		return zero, err
+		return zero, s.err
 	}
-	s.position += n
+	ch, n := s.next, s.size
+	s.peek()
+	s.position = s.offset
+	s.offset += n
 	return ch, nil
 }
 
+// capture appends the character to the text of the lexemes in progress
+func (s *scanner) capture(ch rune) {
+	s.text.WriteRune(ch)
+	s.span.Length = s.offset - s.span.Offset
+	s.end = token.Location{Line: s.line, Column: s.column}
+}
+
+// captured returns the token with the text of the lexemes in progress
+func (s *scanner) captured(tok token.Token) *token.Capture {
+	return token.NewCapture(tok, s.text.String(), s.span, s.start, s.end)
+}
+
+// peek reads the lookahead rune
+func (s *scanner) peek() {
+	s.next, s.size, s.err = s.reader.ReadRune()
+}
+
 func (s *scanner) update(ch rune) {
 	if s.isEndOfLineCharacter(ch) {
 		s.column = 0
@@ -140,26 +256,81 @@ func (s *scanner) update(ch rune) {
 	}
 }
 
+// matchesExistingLexemes scans the character with each existing and ignored lexeme
+// if no lexeme matches, the existing lexemes are kept so the accepted ones can be parsed or ignored
 func (s *scanner) matchesExistingLexemes(ch rune) bool {
-	if len(s.lexemes) == 0 {
+	if !s.anyExistingLexemes() {
 		return false
 	}
-	var matched []token.Lexeme
-	for _, lexeme := range s.lexemes {
+	lexemes, unmatchedLexemes := scan(s.lexemes, ch)
+	ignored, unmatchedIgnored := scan(s.ignored, ch)
+	if len(lexemes) == 0 && len(ignored) == 0 {
+		return false
+	}
+	// the longest match wins, so lexemes that stop matching are discarded
+	for _, lexeme := range append(unmatchedLexemes, unmatchedIgnored...) {
+		s.freeLexeme(lexeme)
+	}
+	s.lexemes = lexemes
+	s.ignored = ignored
+	return true
+}
+
+func scan(lexemes []token.Lexeme, ch rune) (matched []token.Lexeme, unmatched []token.Lexeme) {
+	for _, lexeme := range lexemes {
 		if lexeme.Scan(ch) {
 			matched = append(matched, lexeme)
+		} else {
+			unmatched = append(unmatched, lexeme)
 		}
 	}
+	return
+}
+
+// completeExistingLexemes parses the accepted lexemes or, if none are accepted, skips the accepted ignored lexemes
+// lexemes take priority over ignored lexemes of the same length
+// if the parser rejects the lexemes and no ignored lexeme is accepted, the parser error is returned
+func (s *scanner) completeExistingLexemes() (bool, error) {
+	ok, parseErr := s.tryParseExistingLexemes()
+	if ok {
+		s.freeLexemes(s.ignored)
+		s.ignored = s.ignored[:0]
+		return true, nil
+	}
+	if s.tryIgnoreExistingLexemes() {
+		return true, nil
+	}
+	return false, parseErr
+}
 
-	s.lexemes = matched
-	return len(s.lexemes) > 0
+func (s *scanner) tryIgnoreExistingLexemes() bool {
+	var accepted token.Lexeme
+	for _, lexeme := range s.ignored {
+		if lexeme.Accepted() && accepted == nil {
+			accepted = lexeme
+			continue
+		}
+		s.freeLexeme(lexeme)
+	}
+	s.ignored = s.ignored[:0]
+	if accepted == nil {
+		return false
+	}
+	if s.keep {
+		s.trivia = append(s.trivia, s.captured(accepted))
+	} else {
+		s.freeLexeme(accepted)
+	}
+	s.freeLexemes(s.lexemes)
+	s.lexemes = s.lexemes[:0]
+	return true
 }
 
-func (s *scanner) tryParseExistingLexemes() bool {
+func (s *scanner) tryParseExistingLexemes() (bool, error) {
 	size := len(s.lexemes)
 	anyLexemes := size > 0
 	if !anyLexemes {
-		return false
+		return false, nil
 	}
 	i := 0
 	for i < size {
@@ -179,7 +350,7 @@ func (s *scanner) tryParseExistingLexemes() bool {
 	// if no matches, return
 	anyMatches := size > 0
 	if !anyMatches {
-		return false
+		return false, nil
 	}
 
 	// reclaim the lexemes
@@ -194,32 +365,45 @@ func (s *scanner) tryParseExistingLexemes() bool {
 	// TODO: check if it is better to just use lexemes directly
 	tokens := make([]token.Token, size)
 	for i := 0; i < size; i++ {
-		tokens[i] = s.lexemes[i]
+		capture := s.captured(s.lexemes[i])
+		capture.Leading = s.trivia
+		tokens[i] = capture
 	}
 
 	ok, err := s.parser.Pulse(tokens...)
 	if err != nil {
-		return false
+		return false, err
 	}
 	if !ok {
-		return false
+		return false, nil
 	}
 
-	clear(s.lexemes)
+	s.lexemes = s.lexemes[:0]
+	s.trivia = nil
 
-	return true
+	return true, nil
 }
 
 func (s *scanner) anyExistingLexemes() bool {
-	return len(s.lexemes) > 0
+	return len(s.lexemes) > 0 || len(s.ignored) > 0
 }
 
 func (s *scanner) matchesNewLexemes(ch rune) (bool, error) {
-	return s.matchLexerRules(ch, s.parser.Expected())
+	lexemes, err := s.matchLexerRules(ch, s.parser.Expected())
+	if err != nil {
+		return false, err
+	}
+	ignored, err := s.matchLexerRules(ch, s.parser.Ignored())
+	if err != nil {
+		return false, err
+	}
+	s.lexemes = append(s.lexemes, lexemes...)
+	s.ignored = append(s.ignored, ignored...)
+	return len(lexemes) > 0 || len(ignored) > 0, nil
 }
 
-func (s *scanner) matchLexerRules(ch rune, lexerRules []grammar.LexerRule) (bool, error) {
-	anyMatches := false
+func (s *scanner) matchLexerRules(ch rune, lexerRules []grammar.LexerRule) ([]token.Lexeme, error) {
+	var matched []token.Lexeme
 
 	for _, lexerRule := range lexerRules {
 		if !lexerRule.CanApply(ch) {
@@ -229,24 +413,31 @@ func (s *scanner) matchLexerRules(ch rune, lexerRules []grammar.LexerRule) (bool
 		// detect invalid lexer rule types
 		factory, ok := s.registry[lexerRule.LexerRuleType()]
 		if !ok {
-			return false, fmt.Errorf("unregistered lexer rule type %s", lexerRule.LexerRuleType())
+			return nil, fmt.Errorf("unregistered lexer rule type %s", lexerRule.LexerRuleType())
 		}
 
-		tok, err := factory.Create(lexerRule, s.input, s.position)
+		tok, err := create(factory, lexerRule, s.position)
 		if err != nil {
-			return false, err
+			return nil, err
 		}
 		if !tok.Scan(ch) {
 			err = s.freeLexeme(tok)
 			if err != nil {
-				return false, err
+				return nil, err
 			}
 			continue
 		}
-		s.lexemes = append(s.lexemes, tok)
-		anyMatches = true
+		matched = append(matched, tok)
 	}
-	return anyMatches, nil
+	return matched, nil
+}
+
+// create uses CreateAt when the factory supports it, the input string is not kept so other factories get an empty one
+func create(factory token.Factory, lexerRule grammar.LexerRule, offset int) (token.Lexeme, error) {
+	if f, ok := factory.(token.OffsetFactory); ok {
+		return f.CreateAt(lexerRule, offset)
+	}
+	return factory.Create(lexerRule, "", offset)
 }
 
 func (s *scanner) freeLexeme(lexeme token.Lexeme) error {
@@ -258,6 +449,12 @@ func (s *scanner) freeLexeme(lexeme token.Lexeme) error {
 	return factory.Free(lexeme)
 }
 
+func (s *scanner) freeLexemes(lexemes []token.Lexeme) {
+	for _, lexeme := range lexemes {
+		s.freeLexeme(lexeme)
+	}
+}
+
 func (s *scanner) isEndOfLineCharacter(ch rune) bool {
 	return ch == '\n'
 }
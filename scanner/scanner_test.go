@@ -1,11 +1,18 @@
 package scanner_test
 
 import (
+	"bufio"
+	"errors"
+	"strings"
 	"testing"
 
+	"github.com/patrickhuber/go-earley/automata/dfa"
+	"github.com/patrickhuber/go-earley/automata/nfa"
+	"github.com/patrickhuber/go-earley/automata/transform"
 	"github.com/patrickhuber/go-earley/forest"
 	"github.com/patrickhuber/go-earley/grammar"
 	"github.com/patrickhuber/go-earley/parser"
+	"github.com/patrickhuber/go-earley/re"
 	"github.com/patrickhuber/go-earley/scanner"
 	"github.com/patrickhuber/go-earley/terminal"
 
@@ -49,6 +56,303 @@ func TestScanner(t *testing.T) {
 			}
 		}
 	})
+	t.Run("reads from rune reader", func(t *testing.T) {
+		parser := NewFakeParser(
+			grammar.NewStringLexerRule("test"),
+			grammar.NewStringLexerRule("\n"),
+			grammar.NewStringLexerRule("file"),
+		)
+		s := scanner.NewReader(parser, bufio.NewReader(strings.NewReader("test\nfile")))
+		accepted, err := scanner.RunToEnd(s)
+		require.NoError(t, err)
+		require.True(t, accepted)
+		require.Equal(t, 8, s.Position())
+		require.Equal(t, 2, s.Line())
+		require.Equal(t, 4, s.Column())
+	})
+	t.Run("returns reader errors", func(t *testing.T) {
+		parser := NewFakeParser(
+			grammar.NewStringLexerRule("test"),
+		)
+		expected := errors.New("read failed")
+		s := scanner.NewReader(parser, &FailingReader{Reader: strings.NewReader("te"), err: expected})
+		_, err := scanner.RunToEnd(s)
+		require.ErrorIs(t, err, expected)
+	})
+	t.Run("scans dfa lexer rules", func(t *testing.T) {
+		start := &dfa.State{}
+		end := &dfa.State{Final: true}
+		start.Transitions = []dfa.Transition{{Terminal: terminal.NewRange('0', '9'), Target: end}}
+		end.Transitions = []dfa.Transition{{Terminal: terminal.NewRange('0', '9'), Target: end}}
+		number := dfa.NewDfa(start, "number")
+
+		s := grammar.NewNonTerminal("S")
+		g := grammar.New(s,
+			grammar.NewProduction(s, number, grammar.NewStringLexerRule("+"), number),
+		)
+		accepted, err := scanner.RunToEnd(NewScanner("12+345", parser.New(g)))
+		require.NoError(t, err)
+		require.True(t, accepted)
+	})
+	t.Run("skips ignored lexer rules", func(t *testing.T) {
+		g := IgnoreGrammar()
+		accepted, err := scanner.RunToEnd(NewScanner("  a / // comment\n a ", parser.New(g)))
+		require.NoError(t, err)
+		require.True(t, accepted)
+	})
+	t.Run("fails on partial ignored lexeme", func(t *testing.T) {
+		g := IgnoreGrammar()
+		accepted, err := scanner.RunToEnd(NewScanner("a/a/", parser.New(g)))
+		require.False(t, accepted)
+		var parseErr *parser.ParseError
+		require.ErrorAs(t, err, &parseErr)
+		require.True(t, parseErr.EndOfInput)
+		require.Equal(t, "unexpected end of input, expected comment at 1:5", err.Error())
+	})
+	t.Run("keeps trivia", func(t *testing.T) {
+		g := IgnoreGrammar()
+		p := &RecordingParser{Parser: parser.New(g)}
+		s := scanner.New(p, " a/ // comment\na ", scanner.KeepTrivia(true))
+		accepted, err := scanner.RunToEnd(s)
+		require.NoError(t, err)
+		require.True(t, accepted)
+		require.Len(t, p.tokens, 3)
+
+		first, ok := p.tokens[0].(*token.Capture)
+		require.True(t, ok)
+		require.Len(t, first.Leading, 1)
+		require.Equal(t, "whitespace", first.Leading[0].TokenType())
+		require.Equal(t, "a", first.TokenType())
+
+		second, ok := p.tokens[1].(*token.Capture)
+		require.True(t, ok)
+		require.Empty(t, second.Leading)
+
+		last, ok := p.tokens[2].(*token.Capture)
+		require.True(t, ok)
+		require.Len(t, last.Leading, 3)
+		comment, ok := last.Leading[1].(*token.Capture)
+		require.True(t, ok)
+		require.Equal(t, "comment", comment.TokenType())
+		require.Equal(t, "// comment", comment.Text)
+
+		require.Len(t, s.Trivia(), 1)
+	})
+	t.Run("returns parse errors", func(t *testing.T) {
+		e := grammar.NewNonTerminal("E")
+		a := grammar.NewStringLexerRule("a")
+		plus := grammar.NewStringLexerRule("+")
+		open := grammar.NewStringLexerRule("(")
+		close := grammar.NewStringLexerRule(")")
+		g := grammar.New(e,
+			grammar.NewProduction(e, e, plus, a),
+			grammar.NewProduction(e, a),
+			grammar.NewProduction(e, open, e, close),
+		)
+		tests := []struct {
+			name         string
+			input        string
+			message      string
+			position     int
+			nonTerminals []grammar.NonTerminal
+		}{
+			{"unexpected character", "(a+a]", "unexpected character ']', expected ')' or '+' at 1:5", 4, nil},
+			{"unexpected end of input", "((a+a)", "unexpected end of input, expected ')' or '+' at 1:7", 6, nil},
+			{"unexpected end of input after operator", "a+", "unexpected end of input, expected 'a' at 1:3", 2, nil},
+			{"expected nonterminal", "(]", "unexpected character ']', expected 'a' or '(' at 1:2", 1, []grammar.NonTerminal{e}},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				_, err := scanner.RunToEnd(NewScanner(test.input, parser.New(g)))
+				var parseErr *parser.ParseError
+				require.ErrorAs(t, err, &parseErr)
+				require.Equal(t, test.message, err.Error())
+				require.Equal(t, test.position, parseErr.Position)
+				require.Equal(t, test.nonTerminals, parseErr.NonTerminals)
+			})
+		}
+	})
+	t.Run("recovers when the parser recovers", func(t *testing.T) {
+		s := grammar.NewNonTerminal("S")
+		a := grammar.NewStringLexerRule("a")
+		plus := grammar.NewStringLexerRule("+")
+		g := grammar.New(s, grammar.NewProduction(s, a, plus, a))
+		tests := []struct {
+			name    string
+			input   string
+			message string
+		}{
+			{"unrecognized character", "a+?a", "unexpected character '?', expected 'a' at 1:3"},
+			{"truncated input", "a+", "unexpected end of input, expected 'a' at 1:3"},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				p := parser.New(g, parser.Recover())
+				accepted, err := scanner.RunToEnd(NewScanner(test.input, p))
+				require.NoError(t, err)
+				require.True(t, accepted)
+				require.Len(t, p.Diagnostics(), 1)
+				require.Equal(t, test.message, p.Diagnostics()[0].Error())
+			})
+		}
+	})
+	t.Run("reports positions after multi-byte characters", func(t *testing.T) {
+		s := grammar.NewNonTerminal("S")
+		e := grammar.NewStringLexerRule("é")
+		g := grammar.New(s, grammar.NewProduction(s, e, e))
+		tests := []struct {
+			name     string
+			input    string
+			message  string
+			position int
+		}{
+			{"unexpected character", "éx", "unexpected character 'x', expected 'é' at 1:2", 2},
+			{"unexpected end of input", "é", "unexpected end of input, expected 'é' at 1:2", 2},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				_, err := scanner.RunToEnd(NewScanner(test.input, parser.New(g)))
+				var parseErr *parser.ParseError
+				require.ErrorAs(t, err, &parseErr)
+				require.Equal(t, test.message, err.Error())
+				require.Equal(t, test.position, parseErr.Position)
+			})
+		}
+	})
+	t.Run("returns ambiguity errors", func(t *testing.T) {
+		e := grammar.NewNonTerminal("E")
+		a := grammar.NewStringLexerRule("a")
+		plus := grammar.NewStringLexerRule("+")
+		g := grammar.New(e,
+			grammar.NewProduction(e, e, plus, e),
+			grammar.NewProduction(e, a),
+		)
+		p := parser.New(g, parser.Ambiguity(parser.RejectAmbiguity))
+		_, err := scanner.RunToEnd(NewScanner("a+a+a", p))
+		var ambiguityErr *parser.AmbiguityError
+		require.ErrorAs(t, err, &ambiguityErr)
+		require.Len(t, ambiguityErr.Ambiguities, 1)
+		require.Equal(t, "ambiguous parse: E(0, 5): E -> E + E | E -> E + E", err.Error())
+	})
+	t.Run("captures text", func(t *testing.T) {
+		start := &dfa.State{}
+		end := &dfa.State{Final: true}
+		start.Transitions = []dfa.Transition{{Terminal: terminal.NewRange('0', '9'), Target: end}}
+		end.Transitions = []dfa.Transition{{Terminal: terminal.NewRange('0', '9'), Target: end}}
+		number := dfa.NewDfa(start, "number")
+
+		s := grammar.NewNonTerminal("S")
+		g := grammar.New(s,
+			grammar.NewProduction(s, number, grammar.NewStringLexerRule("é"), number),
+		)
+		g.Ignore = []grammar.LexerRule{Regex(`[\s]+`, "whitespace")}
+		p := &RecordingParser{Parser: parser.New(g)}
+		accepted, err := scanner.RunToEnd(NewScanner("12é\n 345", p))
+		require.NoError(t, err)
+		require.True(t, accepted)
+		require.Len(t, p.tokens, 3)
+
+		tests := []struct {
+			text  string
+			span  token.Span
+			start token.Location
+			end   token.Location
+		}{
+			{"12", token.Span{Offset: 0, Length: 2}, token.Location{Line: 1, Column: 1}, token.Location{Line: 1, Column: 2}},
+			{"é", token.Span{Offset: 2, Length: 2}, token.Location{Line: 1, Column: 3}, token.Location{Line: 1, Column: 3}},
+			{"345", token.Span{Offset: 6, Length: 3}, token.Location{Line: 2, Column: 2}, token.Location{Line: 2, Column: 4}},
+		}
+		for i, test := range tests {
+			capture, ok := p.tokens[i].(*token.Capture)
+			require.True(t, ok)
+			require.Equal(t, test.text, capture.Text)
+			require.Equal(t, test.span, capture.Span)
+			require.Equal(t, test.text, capture.Span.Slice("12é\n 345"))
+			require.Equal(t, test.start, capture.Start)
+			require.Equal(t, test.end, capture.End)
+		}
+
+		root, ok := p.GetForestRoot()
+		require.True(t, ok)
+		var leaves []*forest.Token
+		Leaves(root, &leaves)
+		require.Len(t, leaves, 3)
+		require.Equal(t, "345", leaves[2].Text())
+		require.Equal(t, token.Span{Offset: 6, Length: 3}, leaves[2].Span())
+		require.Equal(t, token.Location{Line: 2, Column: 2}, leaves[2].Start())
+		require.Equal(t, token.Location{Line: 2, Column: 4}, leaves[2].End())
+	})
+}
+
+// Leaves appends the tokens of the first tree in the forest
+func Leaves(node forest.Node, leaves *[]*forest.Token) {
+	switch n := node.(type) {
+	case *forest.Token:
+		*leaves = append(*leaves, n)
+	case forest.Internal:
+		alternatives := n.Alternatives()
+		if len(alternatives) == 0 {
+			return
+		}
+		for _, child := range alternatives[0].Children() {
+			Leaves(child, leaves)
+		}
+	}
+}
+
+// IgnoreGrammar returns S -> 'a' '/' 'a' with whitespace and line comments ignored
+func IgnoreGrammar() *grammar.Grammar {
+	s := grammar.NewNonTerminal("S")
+	a := grammar.NewStringLexerRule("a")
+	slash := grammar.NewStringLexerRule("/")
+	g := grammar.New(s,
+		grammar.NewProduction(s, a, slash, a),
+	)
+	g.Ignore = []grammar.LexerRule{
+		Regex(`[\s]+`, "whitespace"),
+		Regex(`\/\/[^\n]*`, "comment"),
+	}
+	return g
+}
+
+func Regex(pattern string, tokenType string) *dfa.Dfa {
+	definition, err := re.Parse(pattern)
+	if err != nil {
+		panic(err)
+	}
+	n, err := nfa.FromRegex(definition)
+	if err != nil {
+		panic(err)
+	}
+	d, err := transform.Nfa2Dfa(n, tokenType)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// FailingReader returns the error once the reader is exhausted
+type FailingReader struct {
+	*strings.Reader
+	err error
+}
+
+func (r *FailingReader) ReadRune() (rune, int, error) {
+	if r.Len() == 0 {
+		return 0, 0, r.err
+	}
+	return r.Reader.ReadRune()
+}
+
+// RecordingParser records the tokens pulsed into the parser
+type RecordingParser struct {
+	parser.Parser
+	tokens []token.Token
+}
+
+func (p *RecordingParser) Pulse(tok ...token.Token) (bool, error) {
+	p.tokens = append(p.tokens, tok...)
+	return p.Parser.Pulse(tok...)
 }
 
 func NewScanner(text string, parser parser.Parser) scanner.Scanner {
@@ -94,3 +398,28 @@ func (p *FakeParser) Expected() []grammar.LexerRule {
 func (*FakeParser) GetForestRoot() (forest.Node, bool) {
 	return nil, false
 }
+
+// ExpectedNonTerminals implements parser.Parser.
+func (*FakeParser) ExpectedNonTerminals() []grammar.NonTerminal {
+	return nil
+}
+
+// Diagnostics implements parser.Parser.
+func (*FakeParser) Diagnostics() []*parser.ParseError {
+	return nil
+}
+
+// Repair implements parser.Parser.
+func (*FakeParser) Repair(*parser.ParseError) bool {
+	return false
+}
+
+// Ambiguities implements parser.Parser.
+func (*FakeParser) Ambiguities() []forest.Ambiguity {
+	return nil
+}
+
+// Ignored implements parser.Parser.
+func (*FakeParser) Ignored() []grammar.LexerRule {
+	return nil
+}
@@ -0,0 +1,129 @@
+package terminal
+
+import (
+	"fmt"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/patrickhuber/go-earley/grammar"
+)
+
+// Interval is an inclusive range of characters
+type Interval struct {
+	Min rune
+	Max rune
+}
+
+// Intervals returns the sorted, non overlapping intervals of characters matched by the terminal
+// An error is returned for terminals that do not describe the characters they match.
+func Intervals(t grammar.Terminal) ([]Interval, error) {
+	switch t := t.(type) {
+	case *Any:
+		return []Interval{{Min: 0, Max: utf8.MaxRune}}, nil
+	case *Character:
+		return []Interval{{Min: t.Value, Max: t.Value}}, nil
+	case *Range:
+		return []Interval{{Min: t.Min, Max: t.Max}}, nil
+	case *RangeSet:
+		return t.Intervals, nil
+	case *Set:
+		var intervals []Interval
+		for _, term := range t.Terminals {
+			i, err := Intervals(term)
+			if err != nil {
+				return nil, err
+			}
+			intervals = append(intervals, i...)
+		}
+		return Normalize(intervals), nil
+	case *Negate:
+		intervals, err := Intervals(t.terminal)
+		if err != nil {
+			return nil, err
+		}
+		return Complement(intervals), nil
+	case *letter:
+		return fromTable(unicode.Letter), nil
+	case *number:
+		return fromTable(unicode.Number), nil
+	case *Whitespace:
+		return fromTable(unicode.White_Space), nil
+	}
+	return nil, fmt.Errorf("unable to compute intervals for terminal %T", t)
+}
+
+// Normalize sorts the intervals and merges overlapping and adjacent intervals
+func Normalize(intervals []Interval) []Interval {
+	if len(intervals) == 0 {
+		return nil
+	}
+	sorted := append([]Interval{}, intervals...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Min < sorted[j].Min
+	})
+	result := []Interval{sorted[0]}
+	for _, interval := range sorted[1:] {
+		last := &result[len(result)-1]
+		if interval.Min <= last.Max+1 {
+			if interval.Max > last.Max {
+				last.Max = interval.Max
+			}
+			continue
+		}
+		result = append(result, interval)
+	}
+	return result
+}
+
+// Complement returns the intervals of characters not matched by the normalized intervals
+func Complement(intervals []Interval) []Interval {
+	var result []Interval
+	var min rune = 0
+	for _, interval := range intervals {
+		if interval.Min > min {
+			result = append(result, Interval{Min: min, Max: interval.Min - 1})
+		}
+		min = interval.Max + 1
+	}
+	if min <= utf8.MaxRune {
+		result = append(result, Interval{Min: min, Max: utf8.MaxRune})
+	}
+	return result
+}
+
+// FromIntervals returns the simplest terminal that matches the normalized intervals
+func FromIntervals(intervals []Interval) grammar.Terminal {
+	if len(intervals) == 1 {
+		interval := intervals[0]
+		if interval.Min == interval.Max {
+			return NewCharacter(interval.Min)
+		}
+		if interval.Min == 0 && interval.Max == utf8.MaxRune {
+			return NewAny()
+		}
+		return NewRange(interval.Min, interval.Max)
+	}
+	return NewRangeSet(intervals)
+}
+
+func fromTable(table *unicode.RangeTable) []Interval {
+	var intervals []Interval
+	for _, r := range table.R16 {
+		intervals = appendStride(intervals, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	for _, r := range table.R32 {
+		intervals = appendStride(intervals, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	return Normalize(intervals)
+}
+
+func appendStride(intervals []Interval, lo, hi, stride rune) []Interval {
+	if stride == 1 {
+		return append(intervals, Interval{Min: lo, Max: hi})
+	}
+	for r := lo; r <= hi; r += stride {
+		intervals = append(intervals, Interval{Min: r, Max: r})
+	}
+	return intervals
+}
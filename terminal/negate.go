@@ -17,6 +17,11 @@ func NewNegate(terminal grammar.Terminal) *Negate {
 	}
 }
 
+// Terminal returns the terminal whose characters are excluded
+func (n *Negate) Terminal() grammar.Terminal {
+	return n.terminal
+}
+
 func (n *Negate) IsMatch(ch rune) bool {
 	return !n.terminal.IsMatch(ch)
 }
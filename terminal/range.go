@@ -0,0 +1,30 @@
+package terminal
+
+import (
+	"fmt"
+
+	"github.com/patrickhuber/go-earley/grammar"
+)
+
+// Range matches any character between Min and Max inclusive
+type Range struct {
+	grammar.SymbolImpl
+	Min rune
+	Max rune
+}
+
+func NewRange(min rune, max rune) *Range {
+	return &Range{
+		Min: min,
+		Max: max,
+	}
+}
+
+// IsMatch implements grammar.Terminal.
+func (r *Range) IsMatch(ch rune) bool {
+	return r.Min <= ch && ch <= r.Max
+}
+
+func (r *Range) String() string {
+	return fmt.Sprintf("%c-%c", r.Min, r.Max)
+}
@@ -0,0 +1,42 @@
+package terminal
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/patrickhuber/go-earley/grammar"
+)
+
+// RangeSet matches any character in a sorted list of non overlapping intervals
+type RangeSet struct {
+	grammar.SymbolImpl
+	Intervals []Interval
+}
+
+func NewRangeSet(intervals []Interval) *RangeSet {
+	return &RangeSet{
+		Intervals: intervals,
+	}
+}
+
+// IsMatch implements grammar.Terminal.
+func (r *RangeSet) IsMatch(ch rune) bool {
+	i := sort.Search(len(r.Intervals), func(i int) bool {
+		return r.Intervals[i].Max >= ch
+	})
+	return i < len(r.Intervals) && r.Intervals[i].Min <= ch
+}
+
+func (r *RangeSet) String() string {
+	var builder strings.Builder
+	builder.WriteRune('[')
+	for _, interval := range r.Intervals {
+		builder.WriteRune(interval.Min)
+		if interval.Max != interval.Min {
+			builder.WriteRune('-')
+			builder.WriteRune(interval.Max)
+		}
+	}
+	builder.WriteRune(']')
+	return builder.String()
+}
@@ -1,6 +1,7 @@
 package terminal_test
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/patrickhuber/go-earley/grammar"
@@ -32,6 +33,46 @@ func TestTerminal(t *testing.T) {
 			t.Fatalf("expected all letters to match")
 		}
 	})
+	t.Run("range", func(t *testing.T) {
+		term := terminal.NewRange('a', 'c')
+		if _, ok := matches(term, []rune{'a', 'b', 'c'}); !ok {
+			t.Fatalf("expected all characters in range to match")
+		}
+		if term.IsMatch('d') {
+			t.Fatalf("expected character outside range not to match")
+		}
+	})
+	t.Run("intervals", func(t *testing.T) {
+		set := terminal.NewSet([]grammar.Terminal{
+			terminal.NewRange('a', 'c'),
+			terminal.NewCharacter('b'),
+			terminal.NewCharacter('d'),
+			terminal.NewCharacter('x'),
+		})
+		intervals, err := terminal.Intervals(set)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []terminal.Interval{{Min: 'a', Max: 'd'}, {Min: 'x', Max: 'x'}}
+		if !reflect.DeepEqual(expected, intervals) {
+			t.Fatalf("expected %v, got %v", expected, intervals)
+		}
+		negated, err := terminal.Intervals(terminal.NewNegate(set))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rangeSet := terminal.NewRangeSet(negated)
+		for _, ch := range []rune{'a', 'd', 'x'} {
+			if rangeSet.IsMatch(ch) {
+				t.Fatalf("expected %q not to match", ch)
+			}
+		}
+		for _, ch := range []rune{'e', 'w', 'y', 0} {
+			if !rangeSet.IsMatch(ch) {
+				t.Fatalf("expected %q to match", ch)
+			}
+		}
+	})
 }
 
 func matches(term grammar.Terminal, runes []rune) (rune, bool) {
@@ -0,0 +1,28 @@
+package token
+
+// Location is the line and column of a character as reported by the scanner
+type Location struct {
+	Line   int
+	Column int
+}
+
+// Capture is a token produced by the scanner with the text it matched and where the text was found
+type Capture struct {
+	Token
+	Text  string
+	Span  Span
+	Start Location
+	End   Location
+	// Leading holds the ignored tokens, like whitespace and comments, that precede the token when trivia is kept
+	Leading []Token
+}
+
+func NewCapture(tok Token, text string, span Span, start Location, end Location) *Capture {
+	return &Capture{
+		Token: tok,
+		Text:  text,
+		Span:  span,
+		Start: start,
+		End:   end,
+	}
+}
@@ -6,6 +6,14 @@ import (
 
 type Factory interface {
 	Type() string
+	// Create creates a lexeme of the lexer rule at the offset, the lexemes of this module do not read str
 	Create(lexerRule grammar.LexerRule, str string, offset int) (Lexeme, error)
 	Free(lexeme Lexeme) error
 }
+
+// OffsetFactory creates lexemes without the input string
+// A scanner that reads from an io.RuneReader does not hold the input.
+type OffsetFactory interface {
+	Factory
+	CreateAt(lexerRule grammar.LexerRule, offset int) (Lexeme, error)
+}
@@ -1,10 +1,11 @@
 package token
 
+// Span is the byte offset and byte length of a token in the input
 type Span struct {
 	Offset int
 	Length int
 }
 
 func (s Span) Slice(str string) string {
-	return str[s.Offset:s.Length]
+	return str[s.Offset : s.Offset+s.Length]
 }
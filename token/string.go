@@ -8,6 +8,7 @@ import (
 
 type String struct {
 	position int
+	index    int
 	rule     *grammar.StringLexerRule
 }
 
@@ -20,7 +21,7 @@ func NewString(lexerRule *grammar.StringLexerRule, position int) *String {
 
 // Accepted implements Lexeme.
 func (s *String) Accepted() bool {
-	return s.position == len(s.rule.Value)
+	return s.index == len(s.rule.Value)
 }
 
 // Position implements Lexeme.
@@ -30,19 +31,20 @@ func (s *String) Position() int {
 
 // Reset implements Lexeme.
 func (s *String) Reset(offset int) {
-	s.position = -1
+	s.position = offset
+	s.index = 0
 }
 
 // Scan implements Lexeme.
 func (s *String) Scan(ch rune) bool {
-	if s.position >= len(s.rule.Value) {
+	if s.index >= len(s.rule.Value) {
 		return false
 	}
-	r, n := utf8.DecodeRuneInString(s.rule.Value[s.position:])
+	r, n := utf8.DecodeRuneInString(s.rule.Value[s.index:])
 	if ch != r {
 		return false
 	}
-	s.position += n
+	s.index += n
 	return true
 }
 
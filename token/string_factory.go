@@ -13,6 +13,11 @@ type stringFactory struct {
 
 // Create implements Factory.
 func (f *stringFactory) Create(lexerRule grammar.LexerRule, str string, position int) (Lexeme, error) {
+	return f.CreateAt(lexerRule, position)
+}
+
+// CreateAt implements OffsetFactory.
+func (f *stringFactory) CreateAt(lexerRule grammar.LexerRule, position int) (Lexeme, error) {
 	rule, ok := lexerRule.(*grammar.StringLexerRule)
 	if !ok || lexerRule.LexerRuleType() != grammar.StringLexerRuleType {
 		return nil, fmt.Errorf("string factory expected lexer rule of type %s but found %s", grammar.StringLexerRuleType, lexerRule.LexerRuleType())
@@ -21,6 +26,7 @@ func (f *stringFactory) Create(lexerRule grammar.LexerRule, str string, position
 		return NewString(rule, position), nil
 	}
 	reused := f.queue.Dequeue()
+	reused.rule = rule
 	reused.Reset(position)
 	return reused, nil
 }
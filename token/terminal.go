@@ -24,6 +24,7 @@ func (t *Terminal) Position() int {
 }
 
 func (t *Terminal) Reset(offset int) {
+	t.position = offset
 	t.accepted = false
 }
 
@@ -19,6 +19,11 @@ func NewTerminalFactory() *TerminalFactory {
 
 // Create implements Factory.
 func (f *TerminalFactory) Create(lexerRule grammar.LexerRule, str string, position int) (Lexeme, error) {
+	return f.CreateAt(lexerRule, position)
+}
+
+// CreateAt implements OffsetFactory.
+func (f *TerminalFactory) CreateAt(lexerRule grammar.LexerRule, position int) (Lexeme, error) {
 	rule, ok := lexerRule.(*grammar.TerminalLexerRule)
 	if !ok || lexerRule.LexerRuleType() != grammar.TerminalLexerRuleType {
 		return nil, fmt.Errorf("terminal factory expected lexer rule of type %s but found %s", grammar.TerminalLexerRuleType, lexerRule.LexerRuleType())
@@ -27,6 +32,7 @@ func (f *TerminalFactory) Create(lexerRule grammar.LexerRule, str string, positi
 		return NewTerminal(rule, position), nil
 	}
 	reused := f.queue.Dequeue()
+	reused.rule = rule
 	reused.Reset(position)
 	return reused, nil
 }